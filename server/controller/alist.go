@@ -1,8 +1,13 @@
 package controller
 
 import (
+	"strconv"
+
+	alistProfileRequest "github.com/MccRay-s/alist2strm/model/alistprofile/request"
 	"github.com/MccRay-s/alist2strm/model/common/response"
+	configResponse "github.com/MccRay-s/alist2strm/model/configs/response"
 	"github.com/MccRay-s/alist2strm/service"
+	"github.com/MccRay-s/alist2strm/utils"
 	"github.com/gin-gonic/gin"
 )
 
@@ -19,9 +24,142 @@ func (a *AListController) TestConnection(c *gin.Context) {
 		return
 	}
 
-	if err := alistService.TestConnection(); err != nil {
+	result, err := alistService.TestConnection(0)
+	if err != nil {
+		response.FailWithMessage("连接测试失败: "+err.Error(), c)
+		return
+	}
+	a.respondTestResult(result, c)
+}
+
+// GetMetrics 获取各 AList 主机的请求统计
+func (a *AListController) GetMetrics(c *gin.Context) {
+	response.SuccessWithData(service.GetAListService().Metrics(), c)
+}
+
+// CreateProfile 创建 AList 配置档案
+func (a *AListController) CreateProfile(c *gin.Context) {
+	var req alistProfileRequest.AListProfileCreateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error("创建 AList 配置档案参数绑定失败", "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	profile, err := service.AListProfile.Create(&req)
+	if err != nil {
+		utils.Error("创建 AList 配置档案失败", "name", req.Name, "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.Info("创建 AList 配置档案成功", "profile_id", profile.ID, "name", profile.Name, "request_id", c.GetString("request_id"))
+	response.SuccessWithData(profile, c)
+}
+
+// GetProfileInfo 获取 AList 配置档案信息
+func (a *AListController) GetProfileInfo(c *gin.Context) {
+	id, ok := a.parseProfileID(c)
+	if !ok {
+		return
+	}
+
+	profile, err := service.AListProfile.GetInfo(id)
+	if err != nil {
+		utils.Error("获取 AList 配置档案失败", "profile_id", id, "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	response.SuccessWithData(profile, c)
+}
+
+// GetProfileList 获取 AList 配置档案列表
+func (a *AListController) GetProfileList(c *gin.Context) {
+	profiles, err := service.AListProfile.List()
+	if err != nil {
+		utils.Error("获取 AList 配置档案列表失败", "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	response.SuccessWithData(profiles, c)
+}
+
+// UpdateProfile 更新 AList 配置档案
+func (a *AListController) UpdateProfile(c *gin.Context) {
+	id, ok := a.parseProfileID(c)
+	if !ok {
+		return
+	}
+
+	var req alistProfileRequest.AListProfileUpdateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error("更新 AList 配置档案参数绑定失败", "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+	req.ID = id
+
+	if err := service.AListProfile.Update(&req); err != nil {
+		utils.Error("更新 AList 配置档案失败", "profile_id", id, "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.Info("更新 AList 配置档案成功", "profile_id", id, "request_id", c.GetString("request_id"))
+	response.SuccessWithMessage("更新成功", c)
+}
+
+// DeleteProfile 删除 AList 配置档案
+func (a *AListController) DeleteProfile(c *gin.Context) {
+	id, ok := a.parseProfileID(c)
+	if !ok {
+		return
+	}
+
+	if err := service.AListProfile.Delete(id); err != nil {
+		utils.Error("删除 AList 配置档案失败", "profile_id", id, "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.Info("删除 AList 配置档案成功", "profile_id", id, "request_id", c.GetString("request_id"))
+	response.SuccessWithMessage("删除成功", c)
+}
+
+// TestProfileConnection 测试 AList 配置档案的连接
+func (a *AListController) TestProfileConnection(c *gin.Context) {
+	id, ok := a.parseProfileID(c)
+	if !ok {
+		return
+	}
+
+	result, err := service.AListProfile.TestConnection(id)
+	if err != nil {
 		response.FailWithMessage("连接测试失败: "+err.Error(), c)
 		return
 	}
-	response.SuccessWithMessage("连接测试成功", c)
+	a.respondTestResult(result, c)
+}
+
+// respondTestResult 返回连接测试结果，失败时同样携带认证状态便于排查
+func (a *AListController) respondTestResult(result *configResponse.AListConnectionTestResult, c *gin.Context) {
+	if !result.Connected {
+		response.FailWithDetailed(result, "连接测试失败: "+result.Error, c)
+		return
+	}
+	response.SuccessWithData(result, c)
+}
+
+// parseProfileID 解析路径中的配置档案ID，失败时直接返回错误响应
+func (a *AListController) parseProfileID(c *gin.Context) (uint, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.Error("配置档案ID参数错误", "id", idStr, "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage("配置档案ID参数错误", c)
+		return 0, false
+	}
+	return uint(id), true
 }
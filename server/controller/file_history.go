@@ -45,3 +45,59 @@ func (c *FileHistoryController) GetFileHistoryInfo(ctx *gin.Context) {
 
 	response.SuccessWithData(result, ctx)
 }
+
+// StartLinkCheck 在后台启动 STRM 链接检查
+func (c *FileHistoryController) StartLinkCheck(ctx *gin.Context) {
+	var req fileHistoryRequest.LinkCheckReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), ctx)
+		return
+	}
+
+	result, err := service.LinkCheck.Start(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	response.SuccessWithData(result, ctx)
+}
+
+// GetLinkCheckStatus 获取 STRM 链接检查进度
+func (c *FileHistoryController) GetLinkCheckStatus(ctx *gin.Context) {
+	response.SuccessWithData(service.LinkCheck.Status(), ctx)
+}
+
+// GetBrokenLinks 获取失效链接报告
+func (c *FileHistoryController) GetBrokenLinks(ctx *gin.Context) {
+	var req fileHistoryRequest.BrokenLinkListReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), ctx)
+		return
+	}
+
+	result, err := service.LinkCheck.GetBrokenLinks(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	response.SuccessWithData(result, ctx)
+}
+
+// FixBrokenLinks 重新生成或删除失效的 STRM 文件
+func (c *FileHistoryController) FixBrokenLinks(ctx *gin.Context) {
+	var req fileHistoryRequest.BrokenLinkFixReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), ctx)
+		return
+	}
+
+	result, err := service.LinkCheck.Fix(ctx.Request.Context(), &req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	response.SuccessWithData(result, ctx)
+}
@@ -228,33 +228,44 @@ func (tc *TaskController) ExecuteTask(c *gin.Context) {
 
 	// 获取是否异步执行参数（兼容两种方式）
 	var async bool
+	priority := service.QueuePriorityManual
 
 	// 先尝试从 JSON 请求体获取
 	var req taskRequest.TaskExecuteReq
 	if err := c.ShouldBindJSON(&req); err == nil {
 		async = !req.Sync // JSON 中是 sync 字段，需要取反
+		if req.Priority != nil {
+			priority = *req.Priority
+		}
 	} else {
 		// 如果 JSON 解析失败，尝试从查询参数获取
 		async = c.DefaultQuery("async", "false") == "true"
+		if p, err := strconv.Atoi(c.Query("priority")); err == nil {
+			priority = p
+		}
+		req.SubPath = c.Query("subPath")
 	}
 
 	var err2 error
 	if async {
 		// 异步执行
-		err2 = service.Task.ExecuteStrmGenerationAsync(uint(id))
+		err2 = service.Task.ExecuteStrmGenerationAsync(uint(id), priority, req.SubPath)
 		if err2 == nil {
 			utils.Info("异步执行任务已启动", "task_id", id, "request_id", c.GetString("request_id"))
 			response.SuccessWithMessage("任务已启动", c)
 		}
 	} else {
 		// 同步执行
-		execResult, err2 := service.Task.ExecuteStrmGeneration(uint(id))
+		execResult, err2 := service.Task.ExecuteStrmGeneration(uint(id), service.GenerateOptions{SubPath: req.SubPath, Source: service.QueueSourceManual})
 		if err2 != nil {
 			utils.Error("同步执行任务失败", "task_id", id, "error", err2.Error(), "request_id", c.GetString("request_id"))
 
 			// 即使发生错误，也返回执行结果（如果有）
 			if execResult != nil {
-				execResult.Status = "failed"
+				// 被取消的执行保留 cancelled 状态
+				if execResult.Status != "cancelled" {
+					execResult.Status = "failed"
+				}
 				execResult.ErrorMessage = err2.Error()
 				response.FailWithDetailed(execResult, err2.Error(), c)
 			} else {
@@ -278,3 +289,120 @@ func (tc *TaskController) ExecuteTask(c *gin.Context) {
 		return
 	}
 }
+
+// ResumeTask 从断点继续执行任务
+func (tc *TaskController) ResumeTask(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.Error("继续执行任务ID参数错误", "id", idStr, "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage("任务ID参数错误", c)
+		return
+	}
+
+	resp, err := service.Task.ResumeTask(uint(id))
+	if err != nil {
+		utils.Error("继续执行任务失败", "task_id", id, "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.Info("继续执行任务已启动", "task_id", id, "task_log_id", resp.ResumeFromLogID, "request_id", c.GetString("request_id"))
+	response.SuccessWithData(resp, c)
+}
+
+// ResignTask 重新签名任务已生成的 STRM 文件
+func (tc *TaskController) ResignTask(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.Error("重新签名任务ID参数错误", "id", idStr, "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage("任务ID参数错误", c)
+		return
+	}
+
+	resp, err := service.Task.ResignTask(c.Request.Context(), uint(id))
+	if err != nil {
+		utils.Error("重新签名失败", "task_id", id, "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.Info("重新签名完成", "task_id", id, "rewritten", resp.Rewritten, "failed", resp.Failed, "request_id", c.GetString("request_id"))
+	response.SuccessWithData(resp, c)
+}
+
+// CancelTask 取消任务
+func (tc *TaskController) CancelTask(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.Error("取消任务ID参数错误", "id", idStr, "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage("任务ID参数错误", c)
+		return
+	}
+
+	if err := service.Task.CancelTask(uint(id)); err != nil {
+		utils.Error("取消任务失败", "task_id", id, "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.Info("任务取消请求已提交", "task_id", id, "request_id", c.GetString("request_id"))
+	response.SuccessWithMessage("任务已取消", c)
+}
+
+// GetQueue 获取任务执行队列
+func (tc *TaskController) GetQueue(c *gin.Context) {
+	response.SuccessWithData(service.Task.GetQueue(), c)
+}
+
+// MoveQueueTaskUp 将任务在队列中上移一位
+func (tc *TaskController) MoveQueueTaskUp(c *gin.Context) {
+	tc.moveQueueTask(c, -1)
+}
+
+// MoveQueueTaskDown 将任务在队列中下移一位
+func (tc *TaskController) MoveQueueTaskDown(c *gin.Context) {
+	tc.moveQueueTask(c, 1)
+}
+
+// moveQueueTask 调整任务在队列中的位置
+func (tc *TaskController) moveQueueTask(c *gin.Context, offset int) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.Error("调整队列任务ID参数错误", "id", idStr, "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage("任务ID参数错误", c)
+		return
+	}
+
+	if err := service.Task.MoveQueueTask(uint(id), offset); err != nil {
+		utils.Error("调整队列顺序失败", "task_id", id, "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.Info("调整队列顺序成功", "task_id", id, "offset", offset, "request_id", c.GetString("request_id"))
+	response.SuccessWithData(service.Task.GetQueue(), c)
+}
+
+// RemoveQueueTask 从队列中移除任务
+func (tc *TaskController) RemoveQueueTask(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.Error("移除队列任务ID参数错误", "id", idStr, "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage("任务ID参数错误", c)
+		return
+	}
+
+	if err := service.Task.RemoveQueueTask(uint(id)); err != nil {
+		utils.Error("移除队列任务失败", "task_id", id, "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.Info("已从队列中移除任务", "task_id", id, "request_id", c.GetString("request_id"))
+	response.SuccessWithMessage("已从队列中移除", c)
+}
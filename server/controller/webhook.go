@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/MccRay-s/alist2strm/model/common/response"
+	webhookRequest "github.com/MccRay-s/alist2strm/model/webhook/request"
+	"github.com/MccRay-s/alist2strm/service"
+	"github.com/MccRay-s/alist2strm/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// 包级别的 Webhook 控制器实例
+var Webhook = &WebhookController{}
+
+type WebhookController struct{}
+
+// maxWebhookBodySize 入站请求体大小上限
+const maxWebhookBodySize = 1 << 20
+
+// Receive 接收入站 Webhook 事件（通过路径中的调用凭证鉴权，不使用用户 JWT）
+func (wc *WebhookController) Receive(c *gin.Context) {
+	token := c.Param("token")
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBodySize))
+	if err != nil {
+		utils.Error("读取 Webhook 请求体失败", "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage("读取请求体失败", c)
+		return
+	}
+
+	// 合并查询参数和表单参数，供 qBittorrent 等通过 curl 上报参数的发送方使用
+	values := c.Request.URL.Query()
+	if strings.HasPrefix(c.ContentType(), "application/x-www-form-urlencoded") {
+		if form, err := url.ParseQuery(string(body)); err == nil {
+			for key, items := range form {
+				values[key] = append(values[key], items...)
+			}
+		}
+	}
+
+	resp, err := service.Webhook.Receive(token, &service.WebhookPayload{Body: body, Values: values})
+	if err != nil {
+		utils.Error("处理 Webhook 事件失败", "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	response.SuccessWithData(resp, c)
+}
+
+// Create 创建 Webhook
+func (wc *WebhookController) Create(c *gin.Context) {
+	var req webhookRequest.WebhookCreateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error("创建 Webhook 参数绑定失败", "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+
+	hook, err := service.Webhook.Create(&req)
+	if err != nil {
+		utils.Error("创建 Webhook 失败", "name", req.Name, "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.Info("创建 Webhook 成功", "webhook_id", hook.ID, "name", hook.Name, "request_id", c.GetString("request_id"))
+	response.SuccessWithData(hook, c)
+}
+
+// GetWebhookInfo 获取 Webhook 信息
+func (wc *WebhookController) GetWebhookInfo(c *gin.Context) {
+	id, ok := wc.parseID(c)
+	if !ok {
+		return
+	}
+
+	hook, err := service.Webhook.GetInfo(id)
+	if err != nil {
+		utils.Error("获取 Webhook 信息失败", "webhook_id", id, "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	response.SuccessWithData(hook, c)
+}
+
+// GetWebhookList 获取 Webhook 列表
+func (wc *WebhookController) GetWebhookList(c *gin.Context) {
+	hooks, err := service.Webhook.List()
+	if err != nil {
+		utils.Error("获取 Webhook 列表失败", "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	response.SuccessWithData(hooks, c)
+}
+
+// UpdateWebhook 更新 Webhook
+func (wc *WebhookController) UpdateWebhook(c *gin.Context) {
+	id, ok := wc.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req webhookRequest.WebhookUpdateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error("更新 Webhook 参数绑定失败", "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage("参数错误: "+err.Error(), c)
+		return
+	}
+	req.ID = id
+
+	if err := service.Webhook.Update(&req); err != nil {
+		utils.Error("更新 Webhook 失败", "webhook_id", id, "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.Info("更新 Webhook 成功", "webhook_id", id, "request_id", c.GetString("request_id"))
+	response.SuccessWithMessage("更新成功", c)
+}
+
+// DeleteWebhook 删除 Webhook
+func (wc *WebhookController) DeleteWebhook(c *gin.Context) {
+	id, ok := wc.parseID(c)
+	if !ok {
+		return
+	}
+
+	if err := service.Webhook.Delete(id); err != nil {
+		utils.Error("删除 Webhook 失败", "webhook_id", id, "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.Info("删除 Webhook 成功", "webhook_id", id, "request_id", c.GetString("request_id"))
+	response.SuccessWithMessage("删除成功", c)
+}
+
+// RegenerateToken 重新生成 Webhook 调用凭证
+func (wc *WebhookController) RegenerateToken(c *gin.Context) {
+	id, ok := wc.parseID(c)
+	if !ok {
+		return
+	}
+
+	hook, err := service.Webhook.RegenerateToken(id)
+	if err != nil {
+		utils.Error("重新生成 Webhook 凭证失败", "webhook_id", id, "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.Info("重新生成 Webhook 凭证成功", "webhook_id", id, "request_id", c.GetString("request_id"))
+	response.SuccessWithData(hook, c)
+}
+
+// parseID 解析路径中的 Webhook ID，失败时直接返回错误响应
+func (wc *WebhookController) parseID(c *gin.Context) (uint, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.Error("Webhook ID参数错误", "id", idStr, "error", err.Error(), "request_id", c.GetString("request_id"))
+		response.FailWithMessage("Webhook ID参数错误", c)
+		return 0, false
+	}
+	return uint(id), true
+}
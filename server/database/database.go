@@ -7,12 +7,15 @@ import (
 	"path/filepath"
 
 	"github.com/MccRay-s/alist2strm/config"
+	"github.com/MccRay-s/alist2strm/model/alistprofile"
 	"github.com/MccRay-s/alist2strm/model/configs"
+	"github.com/MccRay-s/alist2strm/model/dirsnapshot"
 	"github.com/MccRay-s/alist2strm/model/filehistory"
 	"github.com/MccRay-s/alist2strm/model/notification"
 	"github.com/MccRay-s/alist2strm/model/task"
 	"github.com/MccRay-s/alist2strm/model/tasklog"
 	"github.com/MccRay-s/alist2strm/model/user"
+	"github.com/MccRay-s/alist2strm/model/webhook"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -49,6 +52,11 @@ func InitDatabase(cfg *config.AppConfig) error {
 		&tasklog.TaskLog{},
 		&filehistory.FileHistory{},
 		&notification.Queue{},
+		&dirsnapshot.DirSnapshot{},
+		&tasklog.TaskLogFailure{},
+		&tasklog.TaskLogCheckpoint{},
+		&webhook.Webhook{},
+		&alistprofile.AListProfile{},
 	); err != nil {
 		return fmt.Errorf("数据库表迁移失败: %v", err)
 	}
@@ -63,6 +63,11 @@ func main() {
 		utils.Info("通知服务初始化完成")
 	}
 
+	// 处理上次退出时中断的任务
+	if err := service.Task.RecoverInterruptedRuns(); err != nil {
+		utils.Error("处理中断的任务失败", "error", err.Error())
+	}
+
 	// 初始化任务调度器
 	taskScheduler := service.GetTaskScheduler()
 
@@ -0,0 +1,30 @@
+package alistprofile
+
+import (
+	"time"
+)
+
+// AListProfile AList 服务器配置档案
+// 任务可以选择使用的档案，未选择时使用全局 ALIST 配置
+type AListProfile struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+	Name             string    `json:"name" gorm:"type:VARCHAR(100);not null;uniqueIndex"`
+	Host             string    `json:"host" gorm:"type:VARCHAR(255);not null"`     // AList 服务器地址
+	Username         string    `json:"username" gorm:"type:VARCHAR(100)"`          // 用户名
+	Password         string    `json:"password" gorm:"type:VARCHAR(255)"`          // 密码
+	Token            string    `json:"token" gorm:"type:VARCHAR(500)"`             // API Token
+	Domain           string    `json:"domain" gorm:"type:VARCHAR(255)"`            // 访问域名（用于生成文件URL）
+	ReqRetryCount    int       `json:"reqRetryCount" gorm:"not null;default:0"`    // 重试次数，0 表示使用默认值
+	ReqInterval      int64     `json:"reqInterval" gorm:"not null;default:0"`      // 请求间隔时间(毫秒)
+	ReqBurst         int       `json:"reqBurst" gorm:"not null;default:0"`         // 空闲后允许连续发出的请求数，0 表示 1
+	ReqRetryInterval int64     `json:"reqRetryInterval" gorm:"not null;default:0"` // 重试基础间隔时间(毫秒)，0 表示使用默认值
+	ScanConcurrency  int       `json:"scanConcurrency" gorm:"not null;default:0"`  // 目录扫描并发数，0 表示使用默认值
+	HostConcurrency  int       `json:"hostConcurrency" gorm:"not null;default:0"`  // 同一 AList 主机上同时执行的任务数，0 表示使用默认值
+}
+
+// TableName 表名
+func (AListProfile) TableName() string {
+	return "alist_profiles"
+}
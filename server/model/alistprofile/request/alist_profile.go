@@ -0,0 +1,34 @@
+package request
+
+// AListProfileCreateReq AList 配置档案创建请求
+type AListProfileCreateReq struct {
+	Name             string `json:"name" binding:"required" validate:"required,min=1,max=100" example:"家庭 NAS"`
+	Host             string `json:"host" binding:"required" example:"http://192.168.1.10:5244"`
+	Username         string `json:"username" example:"admin"`
+	Password         string `json:"password" example:"password"`
+	Token            string `json:"token" example:"alist-xxxx"`
+	Domain           string `json:"domain" example:"https://alist.example.com"`
+	ReqRetryCount    int    `json:"reqRetryCount" binding:"min=0" example:"3"`
+	ReqInterval      int64  `json:"reqInterval" binding:"min=0" example:"100"`
+	ReqBurst         int    `json:"reqBurst" binding:"min=0" example:"5"`
+	ReqRetryInterval int64  `json:"reqRetryInterval" binding:"min=0" example:"1000"`
+	ScanConcurrency  int    `json:"scanConcurrency" binding:"min=0" example:"4"`
+	HostConcurrency  int    `json:"hostConcurrency" binding:"min=0" example:"1"`
+}
+
+// AListProfileUpdateReq AList 配置档案更新请求
+type AListProfileUpdateReq struct {
+	ID               uint    `json:"-"` // 通过路径参数传递
+	Name             string  `json:"name,omitempty" validate:"omitempty,min=1,max=100" example:"家庭 NAS"`
+	Host             string  `json:"host,omitempty" example:"http://192.168.1.10:5244"`
+	Username         *string `json:"username,omitempty" example:"admin"`
+	Password         *string `json:"password,omitempty" example:"password"`
+	Token            *string `json:"token,omitempty" example:"alist-xxxx"`
+	Domain           *string `json:"domain,omitempty" example:"https://alist.example.com"`
+	ReqRetryCount    *int    `json:"reqRetryCount,omitempty" binding:"omitempty,min=0" example:"3"`
+	ReqInterval      *int64  `json:"reqInterval,omitempty" binding:"omitempty,min=0" example:"100"`
+	ReqBurst         *int    `json:"reqBurst,omitempty" binding:"omitempty,min=0" example:"5"`
+	ReqRetryInterval *int64  `json:"reqRetryInterval,omitempty" binding:"omitempty,min=0" example:"1000"`
+	ScanConcurrency  *int    `json:"scanConcurrency,omitempty" binding:"omitempty,min=0" example:"4"`
+	HostConcurrency  *int    `json:"hostConcurrency,omitempty" binding:"omitempty,min=0" example:"1"`
+}
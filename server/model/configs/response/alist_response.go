@@ -0,0 +1,28 @@
+package response
+
+import "time"
+
+// AListConnectionTestResult AList 连接测试结果
+type AListConnectionTestResult struct {
+	Connected     bool       `json:"connected"`
+	Error         string     `json:"error,omitempty"`
+	Profile       string     `json:"profile"`               // 配置名称
+	AuthMode      string     `json:"authMode"`              // 认证方式：token（API Token）、password（用户名密码登录）、guest（未配置认证）
+	Authenticated bool       `json:"authenticated"`         // 当前是否持有可用的令牌
+	TokenSource   string     `json:"tokenSource,omitempty"` // 当前使用的令牌来源：static（配置的 Token）、login（登录获取）
+	LoggedInAt    *time.Time `json:"loggedInAt,omitempty"`  // 最近一次登录成功的时间
+	AuthError     string     `json:"authError,omitempty"`   // 最近一次认证失败的信息
+}
+
+// AListHostMetrics AList 主机请求统计，每次 HTTP 请求（包括重试）计数一次，进程重启后清零
+type AListHostMetrics struct {
+	Host         string     `json:"host"`
+	Requests     int64      `json:"requests"`              // 请求次数
+	Errors       int64      `json:"errors"`                // 失败次数（网络错误和 HTTP 4xx/5xx 响应）
+	Retries      int64      `json:"retries"`               // 重试次数
+	Throttled    int64      `json:"throttled"`             // 被限流次数
+	AvgLatencyMs int64      `json:"avgLatencyMs"`          // 平均耗时(毫秒)
+	MaxLatencyMs int64      `json:"maxLatencyMs"`          // 最长耗时(毫秒)
+	LastError    string     `json:"lastError,omitempty"`   // 最近一次失败信息
+	LastErrorAt  *time.Time `json:"lastErrorAt,omitempty"` // 最近一次失败时间
+}
@@ -0,0 +1,22 @@
+package dirsnapshot
+
+import (
+	"time"
+)
+
+// DirSnapshot 目录快照模型，记录上次成功扫描时目录的状态，用于增量扫描
+type DirSnapshot struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	TaskID     uint      `json:"taskId" gorm:"not null;uniqueIndex:idx_task_source_path"`
+	SourcePath string    `json:"sourcePath" gorm:"not null;type:varchar(500);uniqueIndex:idx_task_source_path"`
+	Modified   time.Time `json:"modified"`                             // 目录修改时间（来自 AList）
+	ChildCount int       `json:"childCount" gorm:"not null;default:0"` // 子项数量（文件与文件夹）
+	SizeSum    int64     `json:"sizeSum" gorm:"not null;default:0"`    // 直接子文件大小之和
+}
+
+// TableName 表名
+func (DirSnapshot) TableName() string {
+	return "dir_snapshots"
+}
@@ -4,6 +4,18 @@ import (
 	"time"
 )
 
+// STRM 链接检查状态
+const (
+	LinkStatusOK         = "ok"          // 链接可以访问
+	LinkStatusNotFound   = "not_found"   // HTTP 404/410
+	LinkStatusAuthFailed = "auth_failed" // HTTP 401/403，通常是签名失效或直链过期
+	LinkStatusTimeout    = "timeout"     // 请求超时
+	LinkStatusError      = "error"       // 其他 HTTP 错误或网络错误
+	LinkStatusMissing    = "missing"     // 本地 STRM 文件不存在
+	LinkStatusInvalid    = "invalid"     // STRM 内容为空或链接格式错误
+	LinkStatusSkipped    = "skipped"     // STRM 内容不是 http(s) 链接（如本地路径），不检查，不属于失效链接
+)
+
 // FileHistory 文件历史模型
 type FileHistory struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
@@ -22,6 +34,21 @@ type FileHistory struct {
 	IsStrm         bool       `json:"isStrm" gorm:"not null;index;default:false"`
 	ModifiedAt     *time.Time `json:"modifiedAt" gorm:"index"`
 	Hash           *string    `json:"hash" gorm:"type:varchar(20);uniqueIndex"` // 使用指针类型支持NULL值
+	Sign           string     `json:"sign" gorm:"type:varchar(255)"`            // STRM 中写入的签名，用于重新签名时判断是否过期
+	SignedAt       *time.Time `json:"signedAt"`                                 // 最近一次写入 STRM 内容的时间
+	LinkStatus     string     `json:"linkStatus" gorm:"type:varchar(20);index"` // 最近一次链接检查的结果，为空表示未检查
+	LinkError      string     `json:"linkError" gorm:"type:varchar(500)"`       // 链接检查失败的详细信息
+	LinkCheckedAt  *time.Time `json:"linkCheckedAt"`                            // 最近一次链接检查的时间
+
+	StackSources []string `json:"stackSources" gorm:"type:TEXT;serializer:json"` // 多分段合并为一个 STRM 时，除第一段外其他分段的源路径
+	StackSigns   []string `json:"stackSigns" gorm:"type:TEXT;serializer:json"`   // 与 StackSources 一一对应的分段签名
+
+	// 媒体文件改名：原文件名见 FileName/SourcePath，改名后的路径见 TargetFilePath
+	Renamed    bool   `json:"renamed" gorm:"type:TINYINT(1);not null;default:0"` // 是否按解析出的媒体信息改名
+	MediaTitle string `json:"mediaTitle" gorm:"type:varchar(255)"`               // 解析出的标题（剧集为剧名）
+	MediaYear  int    `json:"mediaYear" gorm:"not null;default:0"`               // 解析出的年份，0 表示未识别
+	Season     int    `json:"season" gorm:"not null;default:0"`                  // 剧集的季号
+	Episode    int    `json:"episode" gorm:"not null;default:0"`                 // 剧集的集号
 }
 
 // TableName 表名
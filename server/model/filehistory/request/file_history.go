@@ -13,3 +13,25 @@ type FileHistoryListReq struct {
 type FileHistoryByMainFileReq struct {
 	MainFileID uint `json:"mainFileId" form:"mainFileId" binding:"required"`
 }
+
+// LinkCheckReq 启动 STRM 链接检查请求
+type LinkCheckReq struct {
+	TaskID      *uint `json:"taskId"`                                         // 只检查指定任务，为空检查所有任务
+	SampleSize  int   `json:"sampleSize" binding:"min=0" example:"200"`       // 随机抽样检查的数量，0 表示检查全部
+	Concurrency int   `json:"concurrency" binding:"min=0,max=32" example:"4"` // 同时检查的链接数，0 表示使用默认值
+	OnlyBroken  bool  `json:"onlyBroken" example:"false"`                     // 只复查上次检查失败的链接
+}
+
+// BrokenLinkListReq 失效链接分页查询请求
+type BrokenLinkListReq struct {
+	Page     int    `json:"page" form:"page" binding:"required,min=1"`
+	PageSize int    `json:"pageSize" form:"pageSize" binding:"required,min=1,max=100"`
+	TaskID   *uint  `json:"taskId" form:"taskId"`
+	Status   string `json:"status" form:"status" binding:"omitempty,oneof=not_found auth_failed timeout error missing invalid"` // 为空表示所有失效状态
+}
+
+// BrokenLinkFixReq 处理失效链接请求
+type BrokenLinkFixReq struct {
+	IDs    []uint `json:"ids" binding:"required,min=1"`
+	Action string `json:"action" binding:"required,oneof=regenerate delete" example:"regenerate"` // regenerate: 按任务当前配置重新生成；delete: 删除 STRM 文件和文件历史
+}
@@ -1,6 +1,10 @@
 package response
 
-import "github.com/MccRay-s/alist2strm/model/filehistory"
+import (
+	"time"
+
+	"github.com/MccRay-s/alist2strm/model/filehistory"
+)
 
 // FileHistoryInfoResp 文件历史详情响应
 type FileHistoryInfoResp struct {
@@ -20,3 +24,25 @@ type FileHistoryRelatedResp struct {
 	MainFile     *filehistory.FileHistory   `json:"mainFile"`
 	RelatedFiles []*filehistory.FileHistory `json:"relatedFiles"`
 }
+
+// LinkCheckStatusResp STRM 链接检查进度
+type LinkCheckStatusResp struct {
+	Running    bool           `json:"running"`
+	TaskID     *uint          `json:"taskId"`     // 检查范围，为空表示所有任务
+	Total      int            `json:"total"`      // 本次需要检查的链接数
+	Checked    int            `json:"checked"`    // 已检查的链接数
+	OK         int            `json:"ok"`         // 可以访问的链接数
+	Broken     int            `json:"broken"`     // 失效的链接数
+	ByStatus   map[string]int `json:"byStatus"`   // 各检查结果的数量
+	StartedAt  *time.Time     `json:"startedAt"`  // 开始时间
+	FinishedAt *time.Time     `json:"finishedAt"` // 结束时间，检查中为空
+	Error      string         `json:"error,omitempty"`
+}
+
+// BrokenLinkFixResp 处理失效链接结果
+type BrokenLinkFixResp struct {
+	Regenerated int      `json:"regenerated"` // 重新生成的文件数
+	Deleted     int      `json:"deleted"`     // 删除的文件数
+	Failed      int      `json:"failed"`      // 处理失败的文件数
+	Errors      []string `json:"errors"`      // 失败原因
+}
@@ -62,13 +62,29 @@ type TaskNotificationData struct {
 	FailedCount        int    `json:"failedCount"`        // 处理失败的文件数，与 TaskLog 保持一致
 
 	// 以下字段是为了通知显示更详细信息而保留的额外字段
-	MetadataSkipped int    `json:"metadataSkipped"` // 跳过的元数据文件数
-	SubtitleSkipped int    `json:"subtitleSkipped"` // 跳过的字幕文件数
-	OtherSkipped    int    `json:"otherSkipped"`    // 跳过的其他文件数
-	ErrorMessage    string `json:"errorMessage,omitempty"`
-	EventTime       string `json:"eventTime"`  // 事件发生时间，格式为 2006-01-02 15:04:05
-	SourcePath      string `json:"sourcePath"` // 任务源路径
-	TargetPath      string `json:"targetPath"` // 任务目标路径
+	MetadataSkipped int      `json:"metadataSkipped"` // 跳过的元数据文件数
+	SubtitleSkipped int      `json:"subtitleSkipped"` // 跳过的字幕文件数
+	OtherSkipped    int      `json:"otherSkipped"`    // 跳过的其他文件数
+	OrphanFile      int      `json:"orphanFile"`      // 镜像模式发现的孤立文件数
+	DeletedFile     int      `json:"deletedFile"`     // 镜像模式删除的文件数
+	MirrorDryRun    bool     `json:"mirrorDryRun"`    // 镜像模式是否为演练
+	UnchangedFile   int      `json:"unchangedFile"`   // 增量扫描跳过的未变化文件数
+	SkippedDir      int      `json:"skippedDir"`      // 增量扫描跳过的未变化目录数
+	FailedDirCount  int      `json:"failedDirCount"`  // 容错模式下扫描失败的目录数
+	FailedDirs      []string `json:"failedDirs"`      // 扫描失败的目录路径（最多展示前若干条）
+	ExcludedDir     int      `json:"excludedDir"`     // 被排除规则排除的目录数
+	ExcludedFile    int      `json:"excludedFile"`    // 被排除规则排除的文件数
+	NotIncludedFile int      `json:"notIncludedFile"` // 不满足包含规则的媒体文件数
+	ErrorMessage    string   `json:"errorMessage,omitempty"`
+	EventTime       string   `json:"eventTime"`  // 事件发生时间，格式为 2006-01-02 15:04:05
+	SourcePath      string   `json:"sourcePath"` // 任务源路径
+	TargetPath      string   `json:"targetPath"` // 任务目标路径
+	SubPath         string   `json:"subPath"`    // 本次执行的扫描范围，为空表示完整执行
+
+	SubtitleUnmatched  int      `json:"subtitleUnmatched"`  // 未匹配到媒体文件的字幕数
+	UnmatchedSubtitles []string `json:"unmatchedSubtitles"` // 未匹配的字幕文件路径（最多展示前若干条）
+	DiscFragmentFile   int      `json:"discFragmentFile"`   // 原盘目录中跳过的分段文件数
+	NfoGenerated       int      `json:"nfoGenerated"`       // 本地生成的 NFO 文件数
 }
 
 // GetTaskName 获取任务名称
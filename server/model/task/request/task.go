@@ -1,6 +1,9 @@
 package request
 
-import "github.com/MccRay-s/alist2strm/model/common/request"
+import (
+	"github.com/MccRay-s/alist2strm/model/common/request"
+	"github.com/MccRay-s/alist2strm/model/task"
+)
 
 // TaskCreateReq 任务创建请求
 type TaskCreateReq struct {
@@ -16,23 +19,64 @@ type TaskCreateReq struct {
 	DownloadSubtitle   bool   `json:"downloadSubtitle" example:"是否下载字幕"`
 	MetadataExtensions string `json:"metadataExtensions" example:"刮削数据文件扩展名"`
 	SubtitleExtensions string `json:"subtitleExtensions" example:"字幕文件扩展名"`
+	MirrorMode         bool   `json:"mirrorMode" example:"是否启用镜像模式"`
+	MirrorDryRun       bool   `json:"mirrorDryRun" example:"镜像模式是否仅演练"`
+	SkipUnchanged      bool   `json:"skipUnchanged" example:"是否启用增量扫描"`
+	TolerantMode       bool   `json:"tolerantMode" example:"是否启用容错模式"`
+	StrmTemplate       string `json:"strmTemplate" example:"{{.Domain}}/p{{.EncodedPath}}?sign={{.Sign}}"`
+	IncludeRules       string `json:"includeRules" example:"re:S\\d{2}E\\d{2}"`
+	ExcludeRules       string `json:"excludeRules" example:"Extras/\n*sample*\n*.partial"`
+	AListProfileID     uint   `json:"alistProfileId" example:"0"`
+	RefreshMode        string `json:"refreshMode" binding:"omitempty,oneof=off always recent" example:"off"`
+	RefreshRecentHours int    `json:"refreshRecentHours" binding:"min=0" example:"24"`
+	RefreshManualOnly  bool   `json:"refreshManualOnly" example:"false"`
+	URLMode            string `json:"urlMode" binding:"omitempty,oneof=list get raw" example:"list"`
+	ResignCron         string `json:"resignCron" example:"0 4 * * *"`
+	LinkCheckCron      string `json:"linkCheckCron" example:"0 5 * * 0"`
+	StackMode          string `json:"stackMode" binding:"omitempty,oneof=off rename playlist" example:"off"`
+	RenameMedia        bool   `json:"renameMedia" example:"false"`
+	GenerateNFO        bool   `json:"generateNfo" example:"false"`
+
+	StrmOverride    task.StrmOverride     `json:"strmOverride"`    // 覆盖全局 STRM 配置，未设置的字段沿用全局配置
+	FolderPasswords []task.FolderPassword `json:"folderPasswords"` // 受密码保护目录的密码，路径为相对源路径的目录
 }
 
 // TaskUpdateReq 任务更新请求
 type TaskUpdateReq struct {
-	ID                 uint   `json:"-"` // 通过路径参数传递，不参与JSON绑定和验证
-	Name               string `json:"name,omitempty" validate:"omitempty,min=1,max=100" example:"任务名称"`
-	MediaType          string `json:"mediaType,omitempty" validate:"omitempty,oneof=movie tv" example:"movie"`
-	SourcePath         string `json:"sourcePath,omitempty" example:"源路径"`
-	TargetPath         string `json:"targetPath,omitempty" example:"目标路径"`
-	FileSuffix         string `json:"fileSuffix,omitempty" example:"文件后缀"`
-	Overwrite          *bool  `json:"overwrite,omitempty" example:"是否覆盖"`
-	Enabled            *bool  `json:"enabled,omitempty" example:"是否启用"`
-	Cron               string `json:"cron,omitempty" example:"定时任务表达式"`
-	DownloadMetadata   *bool  `json:"downloadMetadata,omitempty" example:"是否下载刮削数据"`
-	DownloadSubtitle   *bool  `json:"downloadSubtitle,omitempty" example:"是否下载字幕"`
-	MetadataExtensions string `json:"metadataExtensions,omitempty" example:"刮削数据文件扩展名"`
-	SubtitleExtensions string `json:"subtitleExtensions,omitempty" example:"字幕文件扩展名"`
+	ID                 uint    `json:"-"` // 通过路径参数传递，不参与JSON绑定和验证
+	Name               string  `json:"name,omitempty" validate:"omitempty,min=1,max=100" example:"任务名称"`
+	MediaType          string  `json:"mediaType,omitempty" validate:"omitempty,oneof=movie tv" example:"movie"`
+	SourcePath         string  `json:"sourcePath,omitempty" example:"源路径"`
+	TargetPath         string  `json:"targetPath,omitempty" example:"目标路径"`
+	FileSuffix         string  `json:"fileSuffix,omitempty" example:"文件后缀"`
+	Overwrite          *bool   `json:"overwrite,omitempty" example:"是否覆盖"`
+	Enabled            *bool   `json:"enabled,omitempty" example:"是否启用"`
+	Cron               string  `json:"cron,omitempty" example:"定时任务表达式"`
+	DownloadMetadata   *bool   `json:"downloadMetadata,omitempty" example:"是否下载刮削数据"`
+	DownloadSubtitle   *bool   `json:"downloadSubtitle,omitempty" example:"是否下载字幕"`
+	MetadataExtensions string  `json:"metadataExtensions,omitempty" example:"刮削数据文件扩展名"`
+	SubtitleExtensions string  `json:"subtitleExtensions,omitempty" example:"字幕文件扩展名"`
+	MirrorMode         *bool   `json:"mirrorMode,omitempty" example:"是否启用镜像模式"`
+	MirrorDryRun       *bool   `json:"mirrorDryRun,omitempty" example:"镜像模式是否仅演练"`
+	SkipUnchanged      *bool   `json:"skipUnchanged,omitempty" example:"是否启用增量扫描"`
+	TolerantMode       *bool   `json:"tolerantMode,omitempty" example:"是否启用容错模式"`
+	StrmTemplate       *string `json:"strmTemplate,omitempty" example:"{{.Domain}}/p{{.EncodedPath}}?sign={{.Sign}}"` // 传空字符串恢复默认直链
+	IncludeRules       *string `json:"includeRules,omitempty" example:"re:S\\d{2}E\\d{2}"`                            // 传空字符串清除规则
+	ExcludeRules       *string `json:"excludeRules,omitempty" example:"Extras/\n*sample*\n*.partial"`                 // 传空字符串清除规则
+	AListProfileID     *uint   `json:"alistProfileId,omitempty" example:"0"`                                          // 传 0 使用全局 ALIST 配置
+	RefreshMode        string  `json:"refreshMode,omitempty" binding:"omitempty,oneof=off always recent" example:"off"`
+	RefreshRecentHours *int    `json:"refreshRecentHours,omitempty" binding:"omitempty,min=0" example:"24"`
+	RefreshManualOnly  *bool   `json:"refreshManualOnly,omitempty" example:"false"`
+	URLMode            string  `json:"urlMode,omitempty" binding:"omitempty,oneof=list get raw" example:"list"`
+	ResignCron         *string `json:"resignCron,omitempty" example:"0 4 * * *"` // 传空字符串关闭定时重新签名
+	StackMode          string  `json:"stackMode,omitempty" binding:"omitempty,oneof=off rename playlist" example:"off"`
+	RenameMedia        *bool   `json:"renameMedia,omitempty" example:"false"`
+	GenerateNFO        *bool   `json:"generateNfo,omitempty" example:"false"`
+
+	StrmOverride    *task.StrmOverride     `json:"strmOverride,omitempty"`    // 整体替换 STRM 配置覆盖，未设置的字段恢复为沿用全局配置
+	FolderPasswords *[]task.FolderPassword `json:"folderPasswords,omitempty"` // 整体替换目录密码，传空数组清除；密码为空表示沿用该目录已有的密码
+
+	LinkCheckCron *string `json:"linkCheckCron,omitempty" example:"0 5 * * 0"` // 传空字符串关闭定时链接检查
 }
 
 // TaskInfoReq 任务信息查询请求
@@ -59,7 +103,9 @@ type TaskAllReq struct {
 
 // TaskExecuteReq 任务执行请求
 type TaskExecuteReq struct {
-	Sync bool `json:"sync" example:"是否同步执行"` // true: 同步执行，false: 异步执行
+	Sync     bool   `json:"sync" example:"是否同步执行"` // true: 同步执行，false: 异步执行
+	Priority *int   `json:"priority,omitempty"`    // 异步执行时的队列优先级，数值越大越先执行，为空时使用手动执行默认优先级
+	SubPath  string `json:"subPath,omitempty"`     // 只扫描任务源路径下的子路径（相对路径，或位于源路径下的 AList 完整路径），为空表示完整执行
 }
 
 // TaskStatusReq 任务状态查询请求
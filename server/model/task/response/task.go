@@ -1,6 +1,10 @@
 package response
 
-import "time"
+import (
+	"time"
+
+	"github.com/MccRay-s/alist2strm/model/task"
+)
 
 // TaskInfo 任务信息响应
 type TaskInfo struct {
@@ -21,6 +25,41 @@ type TaskInfo struct {
 	DownloadSubtitle   bool       `json:"downloadSubtitle"`
 	MetadataExtensions string     `json:"metadataExtensions"`
 	SubtitleExtensions string     `json:"subtitleExtensions"`
+	MirrorMode         bool       `json:"mirrorMode"`
+	MirrorDryRun       bool       `json:"mirrorDryRun"`
+	SkipUnchanged      bool       `json:"skipUnchanged"`
+	TolerantMode       bool       `json:"tolerantMode"`
+	StrmTemplate       string     `json:"strmTemplate"`
+	IncludeRules       string     `json:"includeRules"`
+	ExcludeRules       string     `json:"excludeRules"`
+	AListProfileID     uint       `json:"alistProfileId"`
+	RefreshMode        string     `json:"refreshMode"`
+	RefreshRecentHours int        `json:"refreshRecentHours"`
+	RefreshManualOnly  bool       `json:"refreshManualOnly"`
+	URLMode            string     `json:"urlMode"`
+	ResignCron         string     `json:"resignCron"`
+	LinkCheckCron      string     `json:"linkCheckCron"`
+	StackMode          string     `json:"stackMode"`
+	RenameMedia        bool       `json:"renameMedia"`
+	GenerateNFO        bool       `json:"generateNfo"`
+
+	StrmOverride    task.StrmOverride    `json:"strmOverride"`            // 任务级 STRM 配置覆盖
+	FolderPasswords []FolderPasswordInfo `json:"folderPasswords"`         // 受密码保护的目录，不返回密码
+	EffectiveStrm   *TaskStrmConfig      `json:"effectiveStrm,omitempty"` // 应用覆盖后实际生效的 STRM 配置，仅任务详情返回
+}
+
+// FolderPasswordInfo 受密码保护的目录，密码不在响应中返回
+type FolderPasswordInfo struct {
+	Path        string `json:"path"`        // 相对任务源路径的目录
+	HasPassword bool   `json:"hasPassword"` // 是否已设置密码
+}
+
+// TaskStrmConfig 任务实际生效的 STRM 配置
+type TaskStrmConfig struct {
+	DefaultSuffix string `json:"defaultSuffix"` // 媒体文件后缀
+	ReplaceSuffix bool   `json:"replaceSuffix"` // 是否替换后缀
+	URLEncode     bool   `json:"urlEncode"`     // 是否URL编码
+	MinFileSize   int64  `json:"minFileSize"`   // 最小文件大小(MB)
 }
 
 // TaskListResp 任务列表响应
@@ -31,6 +70,45 @@ type TaskListResp struct {
 	PageSize int        `json:"pageSize"`
 }
 
+// TaskResumeResp 任务断点续传响应
+type TaskResumeResp struct {
+	TaskID          uint   `json:"taskId"`
+	TaskName        string `json:"taskName"`
+	ResumeFromLogID uint   `json:"resumeFromLogId"` // 继续执行的任务日志ID
+	CheckpointCount int64  `json:"checkpointCount"` // 已完成的断点数量
+}
+
+// TaskResignResp 重新签名结果
+type TaskResignResp struct {
+	TaskID    uint   `json:"taskId"`
+	TaskName  string `json:"taskName"`
+	Checked   int    `json:"checked"`   // 签名缺失或即将过期、需要检查的 STRM 文件数
+	NotDue    int    `json:"notDue"`    // 签名仍在有效期内、未检查的 STRM 文件数
+	Rewritten int    `json:"rewritten"` // 内容已过期并重新写入的文件数
+	Unchanged int    `json:"unchanged"` // 内容仍然有效的文件数
+	Missing   int    `json:"missing"`   // 本地 STRM 文件已不存在的记录数
+	Failed    int    `json:"failed"`    // 获取文件信息或写入失败的文件数
+	Duration  string `json:"duration"`  // 执行耗时
+}
+
+// TaskQueueItem 任务队列项
+type TaskQueueItem struct {
+	Position   int        `json:"position"` // 队列中的位置，从 1 开始；执行中的任务为 0
+	TaskID     uint       `json:"taskId"`
+	TaskName   string     `json:"taskName"`
+	Priority   int        `json:"priority"`            // 优先级，数值越大越先执行
+	Source     string     `json:"source"`              // 入队来源：cron、manual、webhook
+	SubPath    string     `json:"subPath"`             // 扫描范围，为空表示完整执行
+	EnqueuedAt time.Time  `json:"enqueuedAt"`          // 入队时间
+	StartedAt  *time.Time `json:"startedAt,omitempty"` // 开始执行时间，仅执行中的任务有值
+}
+
+// TaskQueueResp 任务队列响应
+type TaskQueueResp struct {
+	Running []TaskQueueItem `json:"running"` // 正在执行的任务
+	Queued  []TaskQueueItem `json:"queued"`  // 等待执行的任务，按执行顺序排列
+}
+
 // TaskExecuteResp 任务执行结果响应
 type TaskExecuteResp struct {
 	TaskID         uint   `json:"taskId"`         // 任务ID
@@ -47,10 +125,13 @@ type TaskExecuteResp struct {
 	OverwriteCount int    `json:"overwriteCount"` // 覆盖数量
 	SubtitleCount  int    `json:"subtitleCount"`  // 字幕文件数量
 	MetadataCount  int    `json:"metadataCount"`  // 元数据文件数量
+	OrphanCount    int    `json:"orphanCount"`    // 镜像模式发现的孤立文件数量
+	DeletedCount   int    `json:"deletedCount"`   // 镜像模式删除的文件数量
 	ErrorFiles     int    `json:"errorFiles"`     // 错误文件数量
 	ProcessedBytes int64  `json:"processedBytes"` // 处理的字节数
 	Message        string `json:"message"`        // 执行消息
 	ErrorMessage   string `json:"errorMessage"`   // 错误信息
+	SubPath        string `json:"subPath"`        // 扫描范围，为空表示完整执行
 }
 
 // TaskStatusResp 任务状态响应
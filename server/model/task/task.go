@@ -14,6 +14,49 @@ type TaskStats struct {
 	FailedCount     int64 // 失败执行次数
 }
 
+// StrmOverride 任务级 STRM 配置覆盖，字段为 nil 时沿用全局 STRM 配置
+type StrmOverride struct {
+	DefaultSuffix *string `json:"defaultSuffix" gorm:"column:strm_default_suffix;type:VARCHAR(255)"` // 媒体文件后缀，逗号分隔
+	ReplaceSuffix *bool   `json:"replaceSuffix" gorm:"column:strm_replace_suffix;type:TINYINT(1)"`   // 是否替换后缀
+	URLEncode     *bool   `json:"urlEncode" gorm:"column:strm_url_encode;type:TINYINT(1)"`           // 是否URL编码
+	MinFileSize   *int64  `json:"minFileSize" gorm:"column:strm_min_file_size"`                      // 最小文件大小(MB)，0表示不过滤
+}
+
+// 目录列表刷新模式
+const (
+	RefreshModeOff    = "off"    // 使用 AList 缓存，不刷新
+	RefreshModeAlways = "always" // 每个目录都刷新
+	RefreshModeRecent = "recent" // 只刷新最近修改过的目录
+
+	DefaultRefreshRecentHours = 24 // recent 模式默认的时间范围（小时）
+)
+
+// STRM 链接来源
+const (
+	URLModeList = "list" // 使用目录列表返回的签名在本地构建 /d 链接，不额外请求 AList
+	URLModeGet  = "get"  // 逐个调用 /api/fs/get 获取签名后构建 /d 链接
+	URLModeRaw  = "raw"  // 逐个调用 /api/fs/get，直接写入云盘直链 raw_url（通常有有效期，需配合定时重新签名）
+)
+
+// 媒体类型
+const (
+	MediaTypeMovie = "movie" // 电影
+	MediaTypeTV    = "tv"    // 剧集
+)
+
+// 多分段文件（Movie.cd1/Movie.part2 等）的处理方式
+const (
+	StackModeOff      = "off"      // 不识别多分段，每个文件单独生成 STRM
+	StackModeRename   = "rename"   // 每个分段单独生成 STRM，统一命名为 Emby 可识别的 "<标题> - cd1" 格式
+	StackModePlaylist = "playlist" // 所有分段合并为一个以标题命名的 STRM，按分段顺序每行一个链接
+)
+
+// FolderPassword 受密码保护的 AList 目录
+type FolderPassword struct {
+	Path     string `json:"path"`     // 相对任务源路径的目录，为空表示整个源路径；子目录沿用最近的上级目录密码
+	Password string `json:"password"` // AList 元信息中设置的目录密码
+}
+
 // Task 任务模型
 type Task struct {
 	ID                 uint       `json:"id" gorm:"primaryKey"`
@@ -33,6 +76,26 @@ type Task struct {
 	DownloadSubtitle   bool       `json:"downloadSubtitle" gorm:"type:TINYINT(1);not null;default:0"`      // 是否下载字幕
 	MetadataExtensions string     `json:"metadataExtensions" gorm:"type:VARCHAR(255);default:nfo,jpg,png"` // 刮削数据文件扩展名
 	SubtitleExtensions string     `json:"subtitleExtensions" gorm:"type:VARCHAR(255);default:srt,ass,ssa"` // 字幕文件扩展名
+	MirrorMode         bool       `json:"mirrorMode" gorm:"type:TINYINT(1);not null;default:0"`            // 镜像模式：清理源端已不存在的 STRM 及关联文件
+	MirrorDryRun       bool       `json:"mirrorDryRun" gorm:"type:TINYINT(1);not null;default:0"`          // 镜像模式演练：只统计孤立文件，不执行删除
+	SkipUnchanged      bool       `json:"skipUnchanged" gorm:"type:TINYINT(1);not null;default:0"`         // 增量扫描：跳过列表未变化的目录和大小、修改时间未变化的文件的处理
+	TolerantMode       bool       `json:"tolerantMode" gorm:"type:TINYINT(1);not null;default:0"`          // 容错模式：子目录扫描失败时记录并继续，任务以部分完成结束
+	StrmTemplate       string     `json:"strmTemplate" gorm:"type:TEXT"`                                   // STRM 内容模板（text/template），为空时使用默认 /d/ 直链
+	IncludeRules       string     `json:"includeRules" gorm:"type:TEXT"`                                   // 媒体文件包含规则，每行一条 glob 或 re: 正则，为空表示不限制
+	ExcludeRules       string     `json:"excludeRules" gorm:"type:TEXT"`                                   // 目录和文件排除规则，每行一条 glob 或 re: 正则，以 / 结尾只匹配目录
+	AListProfileID     uint       `json:"alistProfileId" gorm:"not null;default:0;index"`                  // 使用的 AList 配置档案，0 表示使用全局 ALIST 配置
+	RefreshMode        string     `json:"refreshMode" gorm:"type:VARCHAR(20);not null;default:off"`        // 目录列表刷新模式：off/always/recent，刷新时 AList 跳过缓存重新获取
+	RefreshRecentHours int        `json:"refreshRecentHours" gorm:"not null;default:24"`                   // recent 模式下刷新多少小时内修改过的目录
+	RefreshManualOnly  bool       `json:"refreshManualOnly" gorm:"type:TINYINT(1);not null;default:0"`     // 只在手动执行时刷新，定时和 Webhook 触发的执行使用缓存
+	URLMode            string     `json:"urlMode" gorm:"type:VARCHAR(20);not null;default:list"`           // STRM 链接来源：list/get/raw
+	ResignCron         string     `json:"resignCron" gorm:"type:VARCHAR(255)"`                             // 定时重新签名的 Cron 表达式，为空表示不执行
+	LinkCheckCron      string     `json:"linkCheckCron" gorm:"type:VARCHAR(255)"`                          // 定时检查 STRM 链接的 Cron 表达式，为空表示不执行
+	StackMode          string     `json:"stackMode" gorm:"type:VARCHAR(20);not null;default:off"`          // 多分段文件处理方式：off/rename/playlist
+	RenameMedia        bool       `json:"renameMedia" gorm:"type:TINYINT(1);not null;default:0"`           // 按媒体类型解析文件名，以规范的 "标题 (年份)" 和 "Season 01/标题 - S01E03" 结构命名 STRM
+	GenerateNFO        bool       `json:"generateNfo" gorm:"type:TINYINT(1);not null;default:0"`           // 源端没有 NFO 时按解析出的媒体信息在本地生成 movie/tvshow/episodedetails NFO
+
+	StrmOverride    StrmOverride     `json:"strmOverride" gorm:"embedded"`                     // 覆盖全局 STRM 配置
+	FolderPasswords []FolderPassword `json:"folderPasswords" gorm:"type:TEXT;serializer:json"` // 受密码保护目录的密码
 }
 
 // TableName 表名
@@ -5,7 +5,7 @@ import "github.com/MccRay-s/alist2strm/model/common/request"
 // TaskLogCreateReq 任务日志创建请求
 type TaskLogCreateReq struct {
 	TaskID        uint   `json:"taskId" binding:"required" validate:"required" example:"任务ID"`
-	Status        string `json:"status" binding:"required" validate:"required,oneof=running completed failed cancelled" example:"running"`
+	Status        string `json:"status" binding:"required" validate:"required,oneof=running completed failed cancelled partial interrupted" example:"running"`
 	Message       string `json:"message" validate:"max=1000" example:"任务执行消息"`
 	TotalFile     int    `json:"totalFile" validate:"min=0" example:"总文件数"`
 	GeneratedFile int    `json:"generatedFile" validate:"min=0" example:"生成文件数"`
@@ -19,7 +19,7 @@ type TaskLogCreateReq struct {
 // TaskLogUpdateReq 任务日志更新请求
 type TaskLogUpdateReq struct {
 	ID            uint   `json:"-"` // 通过路径参数传递，不参与JSON绑定和验证
-	Status        string `json:"status,omitempty" validate:"omitempty,oneof=running completed failed cancelled" example:"completed"`
+	Status        string `json:"status,omitempty" validate:"omitempty,oneof=running completed failed cancelled partial interrupted" example:"completed"`
 	Message       string `json:"message,omitempty" validate:"omitempty,max=1000" example:"任务执行完成"`
 	Duration      *int64 `json:"duration,omitempty" validate:"omitempty,min=0" example:"执行耗时（秒）"`
 	TotalFile     *int   `json:"totalFile,omitempty" validate:"omitempty,min=0" example:"总文件数"`
@@ -9,6 +9,7 @@ import (
 // TaskLogInfoResp 任务日志信息响应
 type TaskLogInfoResp struct {
 	tasklog.TaskLog
+	Failures []tasklog.TaskLogFailure `json:"failures"` // 扫描失败的目录
 }
 
 // TaskLogListResp 任务日志列表响应
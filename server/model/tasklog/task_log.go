@@ -6,10 +6,12 @@ import (
 
 // TaskLog 状态常量
 const (
-	TaskLogStatusRunning   = "running"
-	TaskLogStatusCompleted = "completed"
-	TaskLogStatusFailed    = "failed"
-	TaskLogStatusCancelled = "cancelled"
+	TaskLogStatusRunning     = "running"
+	TaskLogStatusCompleted   = "completed"
+	TaskLogStatusFailed      = "failed"
+	TaskLogStatusCancelled   = "cancelled"
+	TaskLogStatusPartial     = "partial"     // 容错模式下部分目录扫描失败，其余目录已正常处理
+	TaskLogStatusInterrupted = "interrupted" // 服务重启等原因导致执行中断，可从断点继续执行
 )
 
 // TaskLog 任务日志模型
@@ -27,11 +29,22 @@ type TaskLog struct {
 	GeneratedFile      int        `json:"generatedFile" gorm:"not null;default:0"`
 	SkipFile           int        `json:"skipFile" gorm:"not null;default:0"`
 	OverwriteFile      int        `json:"overwriteFile" gorm:"not null;default:0"`
-	MetadataCount      int        `json:"metadataCount" gorm:"not null;default:0"`      // 处理的元数据文件总数
-	SubtitleCount      int        `json:"subtitleCount" gorm:"not null;default:0"`      // 处理的字幕文件总数
-	MetadataDownloaded int        `json:"metadataDownloaded" gorm:"not null;default:0"` // 下载的元数据文件数
-	SubtitleDownloaded int        `json:"subtitleDownloaded" gorm:"not null;default:0"` // 下载的字幕文件数
-	FailedCount        int        `json:"failedCount" gorm:"not null;default:0"`        // 处理失败的文件数
+	MetadataCount      int        `json:"metadataCount" gorm:"not null;default:0"`                // 处理的元数据文件总数
+	SubtitleCount      int        `json:"subtitleCount" gorm:"not null;default:0"`                // 处理的字幕文件总数
+	MetadataDownloaded int        `json:"metadataDownloaded" gorm:"not null;default:0"`           // 下载的元数据文件数
+	SubtitleDownloaded int        `json:"subtitleDownloaded" gorm:"not null;default:0"`           // 下载的字幕文件数
+	FailedCount        int        `json:"failedCount" gorm:"not null;default:0"`                  // 处理失败的文件数
+	OrphanFile         int        `json:"orphanFile" gorm:"not null;default:0"`                   // 镜像模式发现的孤立文件数
+	DeletedFile        int        `json:"deletedFile" gorm:"not null;default:0"`                  // 镜像模式删除的文件数
+	FailedDirCount     int        `json:"failedDirCount" gorm:"not null;default:0"`               // 容错模式下扫描失败的目录数
+	SubPath            string     `json:"subPath" gorm:"type:varchar(500);default:''"`            // 本次执行的扫描范围（相对任务源路径），为空表示完整执行
+	PartialScope       bool       `json:"partialScope" gorm:"type:TINYINT(1);not null;default:0"` // 是否只扫描了任务源路径下的部分目录
+	ExcludedDir        int        `json:"excludedDir" gorm:"not null;default:0"`                  // 被排除规则排除的目录数
+	ExcludedFile       int        `json:"excludedFile" gorm:"not null;default:0"`                 // 被排除规则排除的文件数（已计入 SkipFile）
+	NotIncludedFile    int        `json:"notIncludedFile" gorm:"not null;default:0"`              // 不满足包含规则的媒体文件数（已计入 SkipFile）
+	SubtitleUnmatched  int        `json:"subtitleUnmatched" gorm:"not null;default:0"`            // 未匹配到媒体文件而未下载的字幕数
+	DiscFragmentFile   int        `json:"discFragmentFile" gorm:"not null;default:0"`             // 原盘目录中跳过的分段文件数（已计入 SkipFile）
+	NfoGenerated       int        `json:"nfoGenerated" gorm:"not null;default:0"`                 // 本地生成的 NFO 文件数（已计入 MetadataCount）
 }
 
 // TableName 表名
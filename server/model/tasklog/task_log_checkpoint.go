@@ -0,0 +1,21 @@
+package tasklog
+
+import (
+	"time"
+)
+
+// TaskLogCheckpoint 任务执行断点，记录单次执行中已完成的目录，用于中断后继续执行
+type TaskLogCheckpoint struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	TaskLogID  uint      `json:"taskLogId" gorm:"not null;uniqueIndex:idx_log_source_path"`
+	TaskID     uint      `json:"taskId" gorm:"not null;index"`
+	SourcePath string    `json:"sourcePath" gorm:"not null;type:varchar(500);uniqueIndex:idx_log_source_path"`
+	Subtree    bool      `json:"subtree" gorm:"not null;default:false"` // false: 仅目录内文件已处理完成；true: 包含所有子目录在内的整个子树已完成
+}
+
+// TableName 表名
+func (TaskLogCheckpoint) TableName() string {
+	return "task_log_checkpoints"
+}
@@ -0,0 +1,21 @@
+package tasklog
+
+import (
+	"time"
+)
+
+// TaskLogFailure 任务执行过程中扫描失败的目录记录，与单次执行的 TaskLog 关联
+type TaskLogFailure struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt  time.Time `json:"createdAt"`
+	TaskLogID  uint      `json:"taskLogId" gorm:"not null;index"`
+	TaskID     uint      `json:"taskId" gorm:"not null;index"`
+	Path       string    `json:"path" gorm:"not null;type:varchar(500)"` // 失败的源目录路径
+	Error      string    `json:"error" gorm:"type:text"`                 // 最后一次失败的错误信息
+	RetryCount int       `json:"retryCount" gorm:"not null;default:0"`   // 已重试次数
+}
+
+// TableName 表名
+func (TaskLogFailure) TableName() string {
+	return "task_log_failures"
+}
@@ -0,0 +1,28 @@
+package request
+
+// WebhookCreateReq Webhook 创建请求
+type WebhookCreateReq struct {
+	Name            string `json:"name" binding:"required" validate:"required,min=1,max=100" example:"Sonarr"`
+	Adapter         string `json:"adapter" binding:"required,oneof=generic sonarr radarr qbittorrent" example:"sonarr"`
+	PathField       string `json:"pathField" example:"data.path"`
+	PathFrom        string `json:"pathFrom" example:"/downloads"`
+	PathTo          string `json:"pathTo" example:"/115/downloads"`
+	TaskID          uint   `json:"taskId" example:"0"`
+	Priority        *int   `json:"priority,omitempty" example:"10"`
+	DebounceSeconds *int   `json:"debounceSeconds,omitempty" binding:"omitempty,min=0,max=3600" example:"30"`
+	Enabled         bool   `json:"enabled" example:"true"`
+}
+
+// WebhookUpdateReq Webhook 更新请求
+type WebhookUpdateReq struct {
+	ID              uint    `json:"-"` // 通过路径参数传递
+	Name            string  `json:"name,omitempty" validate:"omitempty,min=1,max=100" example:"Sonarr"`
+	Adapter         string  `json:"adapter,omitempty" binding:"omitempty,oneof=generic sonarr radarr qbittorrent" example:"sonarr"`
+	PathField       *string `json:"pathField,omitempty" example:"data.path"`
+	PathFrom        *string `json:"pathFrom,omitempty" example:"/downloads"`
+	PathTo          *string `json:"pathTo,omitempty" example:"/115/downloads"`
+	TaskID          *uint   `json:"taskId,omitempty" example:"0"`
+	Priority        *int    `json:"priority,omitempty" example:"10"`
+	DebounceSeconds *int    `json:"debounceSeconds,omitempty" binding:"omitempty,min=0,max=3600" example:"30"`
+	Enabled         *bool   `json:"enabled,omitempty" example:"true"`
+}
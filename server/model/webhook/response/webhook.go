@@ -0,0 +1,17 @@
+package response
+
+// WebhookTrigger 单个路径的处理结果
+type WebhookTrigger struct {
+	Path     string `json:"path"`     // 映射后的 AList 路径
+	TaskID   uint   `json:"taskId"`   // 匹配到的任务
+	TaskName string `json:"taskName"` // 任务名称
+	SubPath  string `json:"subPath"`  // 扫描范围，为空表示完整执行
+}
+
+// WebhookReceiveResp Webhook 事件处理结果
+type WebhookReceiveResp struct {
+	Ignored   bool             `json:"ignored"`   // 事件被忽略（例如测试事件或非下载事件）
+	Message   string           `json:"message"`   // 处理说明
+	Triggers  []WebhookTrigger `json:"triggers"`  // 已加入防抖等待的执行
+	Unmatched []string         `json:"unmatched"` // 没有匹配到任务的路径
+}
@@ -0,0 +1,36 @@
+package webhook
+
+import (
+	"time"
+)
+
+// Webhook 适配器类型
+const (
+	AdapterGeneric     = "generic"     // 通用 JSON，按字段路径读取文件路径
+	AdapterSonarr      = "sonarr"      // Sonarr Download 事件
+	AdapterRadarr      = "radarr"      // Radarr Download 事件
+	AdapterQBittorrent = "qbittorrent" // qBittorrent 下载完成后执行的外部程序
+)
+
+// Webhook 入站 Webhook 模型
+type Webhook struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+	Name            string     `json:"name" gorm:"type:VARCHAR(100);not null"`
+	Token           string     `json:"token" gorm:"type:VARCHAR(64);not null;uniqueIndex"`       // 调用凭证，作为请求路径的一部分
+	Adapter         string     `json:"adapter" gorm:"type:VARCHAR(20);not null;default:generic"` // 负载适配器：generic/sonarr/radarr/qbittorrent
+	PathField       string     `json:"pathField" gorm:"type:VARCHAR(255)"`                       // generic 适配器读取路径的 JSON 字段，点号分隔，例如 data.path
+	PathFrom        string     `json:"pathFrom" gorm:"type:VARCHAR(255)"`                        // 发送方上报路径的前缀，例如下载器本地路径 /downloads
+	PathTo          string     `json:"pathTo" gorm:"type:VARCHAR(255)"`                          // 替换后的 AList 路径前缀，例如 /115/downloads
+	TaskID          uint       `json:"taskId" gorm:"not null;default:0"`                         // 限定触发的任务，0 表示按路径自动匹配任务
+	Priority        int        `json:"priority" gorm:"not null;default:10"`                      // 加入执行队列时的优先级
+	DebounceSeconds int        `json:"debounceSeconds" gorm:"not null;default:30"`               // 防抖时间，窗口内的多次事件合并为一次执行
+	Enabled         bool       `json:"enabled" gorm:"type:TINYINT(1);not null;default:1"`        // 是否启用
+	LastTriggeredAt *time.Time `json:"lastTriggeredAt"`                                          // 最近一次收到事件的时间
+}
+
+// TableName 表名
+func (Webhook) TableName() string {
+	return "webhooks"
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/MccRay-s/alist2strm/database"
+	"github.com/MccRay-s/alist2strm/model/alistprofile"
+	"gorm.io/gorm"
+)
+
+type AListProfileRepository struct{}
+
+// 包级别的全局实例
+var AListProfile = &AListProfileRepository{}
+
+// Create 创建 AList 配置档案
+func (r *AListProfileRepository) Create(profile *alistprofile.AListProfile) error {
+	return database.DB.Create(profile).Error
+}
+
+// GetByID 根据ID获取 AList 配置档案
+func (r *AListProfileRepository) GetByID(id uint) (*alistprofile.AListProfile, error) {
+	var profile alistprofile.AListProfile
+	err := database.DB.Where("id = ?", id).First(&profile).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// CheckNameExists 检查名称是否已被其他档案使用
+func (r *AListProfileRepository) CheckNameExists(name string, excludeID uint) (bool, error) {
+	var count int64
+	err := database.DB.Model(&alistprofile.AListProfile{}).Where("name = ? AND id <> ?", name, excludeID).Count(&count).Error
+	return count > 0, err
+}
+
+// List 获取所有 AList 配置档案
+func (r *AListProfileRepository) List() ([]alistprofile.AListProfile, error) {
+	var profiles []alistprofile.AListProfile
+	err := database.DB.Order("id ASC").Find(&profiles).Error
+	return profiles, err
+}
+
+// Update 更新 AList 配置档案
+func (r *AListProfileRepository) Update(profile *alistprofile.AListProfile) error {
+	return database.DB.Save(profile).Error
+}
+
+// Delete 删除 AList 配置档案
+func (r *AListProfileRepository) Delete(id uint) error {
+	return database.DB.Delete(&alistprofile.AListProfile{}, id).Error
+}
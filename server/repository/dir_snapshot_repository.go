@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"github.com/MccRay-s/alist2strm/database"
+	"github.com/MccRay-s/alist2strm/model/dirsnapshot"
+	"gorm.io/gorm/clause"
+)
+
+type DirSnapshotRepository struct{}
+
+// 包级别的全局实例
+var DirSnapshot = &DirSnapshotRepository{}
+
+// ListByTaskID 获取指定任务的所有目录快照
+func (r *DirSnapshotRepository) ListByTaskID(taskID uint) ([]dirsnapshot.DirSnapshot, error) {
+	var snapshots []dirsnapshot.DirSnapshot
+	if err := database.DB.Where("task_id = ?", taskID).Find(&snapshots).Error; err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// Upsert 批量写入目录快照，已存在的记录按任务ID和源路径更新
+func (r *DirSnapshotRepository) Upsert(snapshots []dirsnapshot.DirSnapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+	return database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "task_id"}, {Name: "source_path"}},
+		DoUpdates: clause.AssignmentColumns([]string{"modified", "child_count", "size_sum", "updated_at"}),
+	}).CreateInBatches(snapshots, 500).Error
+}
+
+// DeleteByTaskID 删除指定任务的所有目录快照
+func (r *DirSnapshotRepository) DeleteByTaskID(taskID uint) error {
+	return database.DB.Where("task_id = ?", taskID).Delete(&dirsnapshot.DirSnapshot{}).Error
+}
+
+// DeleteBySourcePaths 删除指定任务中指定目录的快照
+func (r *DirSnapshotRepository) DeleteBySourcePaths(taskID uint, sourcePaths []string) error {
+	if len(sourcePaths) == 0 {
+		return nil
+	}
+	return database.DB.Where("task_id = ? AND source_path IN ?", taskID, sourcePaths).Delete(&dirsnapshot.DirSnapshot{}).Error
+}
@@ -107,3 +107,74 @@ func (r *FileHistoryRepository) GetByFileAttributes(sourcePath, fileName string,
 
 	return &fileHistory, nil
 }
+
+// ListByTaskID 获取指定任务的所有文件历史记录
+func (r *FileHistoryRepository) ListByTaskID(taskID uint) ([]*filehistory.FileHistory, error) {
+	var fileHistories []*filehistory.FileHistory
+	if err := database.DB.Where("task_id = ?", taskID).Find(&fileHistories).Error; err != nil {
+		return nil, err
+	}
+	return fileHistories, nil
+}
+
+// DeleteByIDs 根据ID批量删除文件历史记录
+func (r *FileHistoryRepository) DeleteByIDs(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return database.DB.Where("id IN ?", ids).Delete(&filehistory.FileHistory{}).Error
+}
+
+// ListStrm 获取 STRM 文件的历史记录，taskID 为 nil 时返回所有任务的记录，onlyBroken 为 true 时只返回上次检查失效的记录
+func (r *FileHistoryRepository) ListStrm(taskID *uint, onlyBroken bool) ([]*filehistory.FileHistory, error) {
+	var fileHistories []*filehistory.FileHistory
+	query := database.DB.Where("is_strm = ?", true)
+	if taskID != nil {
+		query = query.Where("task_id = ?", *taskID)
+	}
+	if onlyBroken {
+		query = query.Where("link_status NOT IN ?", []string{"", filehistory.LinkStatusOK, filehistory.LinkStatusSkipped})
+	}
+	if err := query.Find(&fileHistories).Error; err != nil {
+		return nil, err
+	}
+	return fileHistories, nil
+}
+
+// GetBrokenLinks 分页获取链接检查失效的 STRM 文件历史记录
+func (r *FileHistoryRepository) GetBrokenLinks(req *fileHistoryRequest.BrokenLinkListReq) ([]*filehistory.FileHistory, int64, error) {
+	var fileHistories []*filehistory.FileHistory
+	var total int64
+
+	query := database.DB.Model(&filehistory.FileHistory{}).Where("is_strm = ?", true)
+	if req.TaskID != nil {
+		query = query.Where("task_id = ?", *req.TaskID)
+	}
+	if req.Status != "" {
+		query = query.Where("link_status = ?", req.Status)
+	} else {
+		query = query.Where("link_status NOT IN ?", []string{"", filehistory.LinkStatusOK, filehistory.LinkStatusSkipped})
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (req.Page - 1) * req.PageSize
+	if err := query.Order("link_checked_at DESC").Offset(offset).Limit(req.PageSize).Find(&fileHistories).Error; err != nil {
+		return nil, 0, err
+	}
+	return fileHistories, total, nil
+}
+
+// GetByIDs 根据ID批量获取文件历史记录
+func (r *FileHistoryRepository) GetByIDs(ids []uint) ([]*filehistory.FileHistory, error) {
+	var fileHistories []*filehistory.FileHistory
+	if len(ids) == 0 {
+		return fileHistories, nil
+	}
+	if err := database.DB.Where("id IN ?", ids).Find(&fileHistories).Error; err != nil {
+		return nil, err
+	}
+	return fileHistories, nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"github.com/MccRay-s/alist2strm/database"
+	"github.com/MccRay-s/alist2strm/model/tasklog"
+	"gorm.io/gorm/clause"
+)
+
+type TaskLogCheckpointRepository struct{}
+
+// 包级别的全局实例
+var TaskLogCheckpoint = &TaskLogCheckpointRepository{}
+
+// Upsert 批量写入断点，已存在的记录按任务日志ID和源路径更新
+func (r *TaskLogCheckpointRepository) Upsert(checkpoints []tasklog.TaskLogCheckpoint) error {
+	if len(checkpoints) == 0 {
+		return nil
+	}
+	return database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "task_log_id"}, {Name: "source_path"}},
+		DoUpdates: clause.AssignmentColumns([]string{"subtree", "updated_at"}),
+	}).CreateInBatches(checkpoints, 500).Error
+}
+
+// ListByTaskLogID 获取指定任务日志的所有断点
+func (r *TaskLogCheckpointRepository) ListByTaskLogID(taskLogID uint) ([]tasklog.TaskLogCheckpoint, error) {
+	var checkpoints []tasklog.TaskLogCheckpoint
+	if err := database.DB.Where("task_log_id = ?", taskLogID).Find(&checkpoints).Error; err != nil {
+		return nil, err
+	}
+	return checkpoints, nil
+}
+
+// CountByTaskLogID 统计指定任务日志的断点数量
+func (r *TaskLogCheckpointRepository) CountByTaskLogID(taskLogID uint) (int64, error) {
+	var count int64
+	err := database.DB.Model(&tasklog.TaskLogCheckpoint{}).Where("task_log_id = ?", taskLogID).Count(&count).Error
+	return count, err
+}
+
+// DeleteByTaskID 删除指定任务的所有断点
+func (r *TaskLogCheckpointRepository) DeleteByTaskID(taskID uint) error {
+	return database.DB.Where("task_id = ?", taskID).Delete(&tasklog.TaskLogCheckpoint{}).Error
+}
+
+// DeleteByTaskLogID 删除指定任务日志的断点
+func (r *TaskLogCheckpointRepository) DeleteByTaskLogID(taskLogID uint) error {
+	return database.DB.Where("task_log_id = ?", taskLogID).Delete(&tasklog.TaskLogCheckpoint{}).Error
+}
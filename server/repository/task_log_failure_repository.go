@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"github.com/MccRay-s/alist2strm/database"
+	"github.com/MccRay-s/alist2strm/model/tasklog"
+)
+
+type TaskLogFailureRepository struct{}
+
+// 包级别的全局实例
+var TaskLogFailure = &TaskLogFailureRepository{}
+
+// CreateBatch 批量创建失败记录
+func (r *TaskLogFailureRepository) CreateBatch(failures []tasklog.TaskLogFailure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	return database.DB.CreateInBatches(failures, 500).Error
+}
+
+// ListByTaskLogID 获取指定任务日志的失败记录
+func (r *TaskLogFailureRepository) ListByTaskLogID(taskLogID uint) ([]tasklog.TaskLogFailure, error) {
+	var failures []tasklog.TaskLogFailure
+	if err := database.DB.Where("task_log_id = ?", taskLogID).Order("id ASC").Find(&failures).Error; err != nil {
+		return nil, err
+	}
+	return failures, nil
+}
+
+// DeleteByTaskID 删除指定任务的所有失败记录
+func (r *TaskLogFailureRepository) DeleteByTaskID(taskID uint) error {
+	return database.DB.Where("task_id = ?", taskID).Delete(&tasklog.TaskLogFailure{}).Error
+}
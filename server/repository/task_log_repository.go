@@ -143,10 +143,19 @@ func (r *TaskLogRepository) GetRunningLogByTaskID(taskID uint) (*tasklog.TaskLog
 	return &tl, nil
 }
 
+// ListByStatus 获取指定状态的所有任务日志
+func (r *TaskLogRepository) ListByStatus(status string) ([]tasklog.TaskLog, error) {
+	var logs []tasklog.TaskLog
+	if err := database.DB.Where("status = ?", status).Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
 // GetFileProcessingStats 获取文件处理统计数据
 func (r *TaskLogRepository) GetFileProcessingStats(timeRange string) (totalFiles, processedFiles, skippedFiles, strmGenerated, metadataDownloaded, subtitleDownloaded int64, err error) {
-	// 创建基础查询，根据时间范围过滤
-	query := database.DB.Model(&tasklog.TaskLog{}).Where("status = ?", tasklog.TaskLogStatusCompleted)
+	// 创建基础查询，根据时间范围过滤（部分完成的执行同样生成了文件，一并统计）
+	query := database.DB.Model(&tasklog.TaskLog{}).Where("status IN ?", []string{tasklog.TaskLogStatusCompleted, tasklog.TaskLogStatusPartial})
 	query = r.addTimeRangeFilter(query, timeRange)
 
 	// 查询已处理文件总数（总文件数）
@@ -152,6 +152,19 @@ func (r *TaskRepository) UpdateRunningStatus(id uint, running bool) error {
 	return database.DB.Model(&task.Task{}).Where("id = ?", id).Updates(updates).Error
 }
 
+// ClaimRunning 将未运行的任务标记为运行中并更新最后运行时间，任务已在运行时返回 false
+// 通过带条件的 UPDATE 完成判断和标记，避免并发执行同一任务
+func (r *TaskRepository) ClaimRunning(id uint) (bool, error) {
+	now := time.Now()
+	result := database.DB.Model(&task.Task{}).
+		Where("id = ? AND running = ?", id, false).
+		Updates(map[string]interface{}{"running": true, "last_run_at": &now})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
 // ResetRunningStatus 重置所有任务运行状态
 func (r *TaskRepository) ResetRunningStatus() error {
 	return database.DB.Model(&task.Task{}).Where("running = ?", true).Update("running", false).Error
@@ -167,6 +180,22 @@ func (r *TaskRepository) GetAllEnabled() ([]task.Task, error) {
 	return tasks, nil
 }
 
+// ListEnabled 获取所有启用的任务
+func (r *TaskRepository) ListEnabled() ([]task.Task, error) {
+	var tasks []task.Task
+	if err := database.DB.Where("enabled = ?", true).Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// CountByAListProfileID 统计使用指定 AList 配置档案的任务数
+func (r *TaskRepository) CountByAListProfileID(profileID uint) (int64, error) {
+	var count int64
+	err := database.DB.Model(&task.Task{}).Where("alist_profile_id = ?", profileID).Count(&count).Error
+	return count, err
+}
+
 // UpdateLastRunAt 更新任务最后执行时间
 func (r *TaskRepository) UpdateLastRunAt(id uint, lastRunAt time.Time) error {
 	return database.DB.Model(&task.Task{}).Where("id = ?", id).Update("last_run_at", lastRunAt).Error
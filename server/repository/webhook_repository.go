@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MccRay-s/alist2strm/database"
+	"github.com/MccRay-s/alist2strm/model/webhook"
+	"gorm.io/gorm"
+)
+
+type WebhookRepository struct{}
+
+// 包级别的全局实例
+var Webhook = &WebhookRepository{}
+
+// Create 创建 Webhook
+func (r *WebhookRepository) Create(hook *webhook.Webhook) error {
+	return database.DB.Create(hook).Error
+}
+
+// GetByID 根据ID获取 Webhook
+func (r *WebhookRepository) GetByID(id uint) (*webhook.Webhook, error) {
+	var hook webhook.Webhook
+	err := database.DB.Where("id = ?", id).First(&hook).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// GetByToken 根据调用凭证获取 Webhook
+func (r *WebhookRepository) GetByToken(token string) (*webhook.Webhook, error) {
+	var hook webhook.Webhook
+	err := database.DB.Where("token = ?", token).First(&hook).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// List 获取所有 Webhook
+func (r *WebhookRepository) List() ([]webhook.Webhook, error) {
+	var hooks []webhook.Webhook
+	err := database.DB.Order("id ASC").Find(&hooks).Error
+	return hooks, err
+}
+
+// Update 更新 Webhook
+func (r *WebhookRepository) Update(hook *webhook.Webhook) error {
+	return database.DB.Save(hook).Error
+}
+
+// Delete 删除 Webhook
+func (r *WebhookRepository) Delete(id uint) error {
+	return database.DB.Delete(&webhook.Webhook{}, id).Error
+}
+
+// UpdateLastTriggeredAt 更新最近一次收到事件的时间
+func (r *WebhookRepository) UpdateLastTriggeredAt(id uint, triggeredAt time.Time) error {
+	return database.DB.Model(&webhook.Webhook{}).Where("id = ?", id).Update("last_triggered_at", triggeredAt).Error
+}
@@ -36,6 +36,9 @@ func SetupRoutes() *gin.Engine {
 		// Emby 图片公开路由（不需要认证）
 		api.GET("/emby/items/:item_id/images/:image_type", controller.Emby.GetImage) // 获取Emby图片
 
+		// Webhook 入站路由（通过调用凭证鉴权，不需要用户认证）
+		api.POST("/webhook/receive/:token", controller.Webhook.Receive) // 接收 Webhook 事件
+
 		// 需要认证的路由
 		auth := api.Group("")
 		auth.Use(middleware.JWTAuth()) // 应用JWT认证中间件
@@ -63,16 +66,34 @@ func SetupRoutes() *gin.Engine {
 			// 任务相关路由
 			task := auth.Group("/task")
 			{
-				task.POST("/", controller.Task.Create)                     // 创建任务
-				task.GET("/:id", controller.Task.GetTaskInfo)              // 获取指定任务信息
-				task.PUT("/:id", controller.Task.UpdateTask)               // 更新任务信息
-				task.DELETE("/:id", controller.Task.DeleteTask)            // 删除任务
-				task.GET("/list", controller.Task.GetTaskList)             // 获取任务列表（分页）
-				task.GET("/all", controller.Task.GetAllTasks)              // 获取所有任务（不分页）
-				task.GET("/stats", controller.Task.GetTaskStats)           // 获取任务统计数据
-				task.PUT("/:id/toggle", controller.Task.ToggleTaskEnabled) // 切换任务启用状态
-				task.PUT("/:id/reset", controller.Task.ResetTaskStatus)    // 重置任务运行状态
-				task.POST("/:id/execute", controller.Task.ExecuteTask)     // 执行任务（支持同步/异步）
+				task.POST("/", controller.Task.Create)                         // 创建任务
+				task.GET("/:id", controller.Task.GetTaskInfo)                  // 获取指定任务信息
+				task.PUT("/:id", controller.Task.UpdateTask)                   // 更新任务信息
+				task.DELETE("/:id", controller.Task.DeleteTask)                // 删除任务
+				task.GET("/list", controller.Task.GetTaskList)                 // 获取任务列表（分页）
+				task.GET("/all", controller.Task.GetAllTasks)                  // 获取所有任务（不分页）
+				task.GET("/stats", controller.Task.GetTaskStats)               // 获取任务统计数据
+				task.PUT("/:id/toggle", controller.Task.ToggleTaskEnabled)     // 切换任务启用状态
+				task.PUT("/:id/reset", controller.Task.ResetTaskStatus)        // 重置任务运行状态
+				task.POST("/:id/execute", controller.Task.ExecuteTask)         // 执行任务（支持同步/异步）
+				task.POST("/:id/resume", controller.Task.ResumeTask)           // 从断点继续执行任务
+				task.POST("/:id/cancel", controller.Task.CancelTask)           // 取消运行中或排队中的任务
+				task.POST("/:id/resign", controller.Task.ResignTask)           // 重新签名已生成的 STRM 文件
+				task.GET("/queue", controller.Task.GetQueue)                   // 获取任务执行队列
+				task.PUT("/queue/:id/up", controller.Task.MoveQueueTaskUp)     // 队列中上移任务
+				task.PUT("/queue/:id/down", controller.Task.MoveQueueTaskDown) // 队列中下移任务
+				task.DELETE("/queue/:id", controller.Task.RemoveQueueTask)     // 从队列中移除任务
+			}
+
+			// Webhook 管理路由
+			hook := auth.Group("/webhook")
+			{
+				hook.POST("/", controller.Webhook.Create)                  // 创建 Webhook
+				hook.GET("/list", controller.Webhook.GetWebhookList)       // 获取 Webhook 列表
+				hook.GET("/:id", controller.Webhook.GetWebhookInfo)        // 获取指定 Webhook 信息
+				hook.PUT("/:id", controller.Webhook.UpdateWebhook)         // 更新 Webhook
+				hook.DELETE("/:id", controller.Webhook.DeleteWebhook)      // 删除 Webhook
+				hook.PUT("/:id/token", controller.Webhook.RegenerateToken) // 重新生成调用凭证
 			}
 
 			// 任务日志相关路由
@@ -87,14 +108,25 @@ func SetupRoutes() *gin.Engine {
 			fileHistory := auth.Group("/file-history")
 			{
 				fileHistoryController := &controller.FileHistoryController{}
-				fileHistory.GET("/", fileHistoryController.GetFileList)           // 获取主文件分页列表
-				fileHistory.GET("/:id", fileHistoryController.GetFileHistoryInfo) // 获取文件历史详情
+				fileHistory.GET("/", fileHistoryController.GetFileList)                         // 获取主文件分页列表
+				fileHistory.GET("/:id", fileHistoryController.GetFileHistoryInfo)               // 获取文件历史详情
+				fileHistory.POST("/link-check", fileHistoryController.StartLinkCheck)           // 启动 STRM 链接检查
+				fileHistory.GET("/link-check/status", fileHistoryController.GetLinkCheckStatus) // 获取 STRM 链接检查进度
+				fileHistory.GET("/broken-links", fileHistoryController.GetBrokenLinks)          // 获取失效链接报告
+				fileHistory.POST("/broken-links/fix", fileHistoryController.FixBrokenLinks)     // 重新生成或删除失效的 STRM 文件
 			}
 
 			// AList 相关路由
 			alist := auth.Group("/alist")
 			{
-				alist.POST("/test", controller.AList.TestConnection) // 测试AList连接
+				alist.POST("/test", controller.AList.TestConnection)                    // 测试AList连接
+				alist.GET("/metrics", controller.AList.GetMetrics)                      // 获取AList主机请求统计
+				alist.POST("/profile", controller.AList.CreateProfile)                  // 创建AList配置档案
+				alist.GET("/profile/list", controller.AList.GetProfileList)             // 获取AList配置档案列表
+				alist.GET("/profile/:id", controller.AList.GetProfileInfo)              // 获取AList配置档案信息
+				alist.PUT("/profile/:id", controller.AList.UpdateProfile)               // 更新AList配置档案
+				alist.DELETE("/profile/:id", controller.AList.DeleteProfile)            // 删除AList配置档案
+				alist.POST("/profile/:id/test", controller.AList.TestProfileConnection) // 测试AList配置档案连接
 			}
 
 			// Emby 相关需认证路由
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/MccRay-s/alist2strm/model/configs/response"
+	"go.uber.org/zap"
+)
+
+// AList 认证方式
+const (
+	AListAuthModeToken    = "token"    // 使用配置的 API Token，失效后如配置了用户名密码则重新登录
+	AListAuthModePassword = "password" // 使用用户名密码登录获取令牌
+	AListAuthModeGuest    = "guest"    // 未配置认证信息，以游客身份访问
+)
+
+// alistCodeUnauthorized AList 认证失败时返回的业务状态码
+const alistCodeUnauthorized = 401
+
+// alistAuth 客户端的认证状态
+type alistAuth struct {
+	loginToken string    // 通过用户名密码登录获取的令牌，为空表示尚未登录
+	loggedInAt time.Time // 最近一次登录成功的时间
+	lastError  string    // 最近一次认证失败的信息，认证成功后清空
+
+	loginCall *loginCall // 正在进行的登录，并发请求共享同一次登录结果
+}
+
+// loginCall 一次进行中的登录，done 关闭后 token 和 err 为登录结果
+type loginCall struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+// authMode 获取客户端的认证方式
+func (c *AListClient) authMode() string {
+	switch {
+	case c.config.Token != "":
+		return AListAuthModeToken
+	case c.canLogin():
+		return AListAuthModePassword
+	default:
+		return AListAuthModeGuest
+	}
+}
+
+// canLogin 是否配置了用户名和密码
+func (c *AListClient) canLogin() bool {
+	return c.config.Username != "" && c.config.Password != ""
+}
+
+// token 获取请求使用的令牌：优先使用登录获取的令牌，其次为配置的 Token，
+// 两者都没有且配置了用户名密码时先登录；返回空字符串表示以游客身份访问
+func (c *AListClient) token(ctx context.Context) (string, error) {
+	c.authMu.Lock()
+	loginToken := c.auth.loginToken
+	c.authMu.Unlock()
+
+	if loginToken != "" {
+		return loginToken, nil
+	}
+	if c.config.Token != "" {
+		return c.config.Token, nil
+	}
+	if c.canLogin() {
+		return c.login(ctx)
+	}
+	return "", nil
+}
+
+// refreshToken 令牌认证失败后重新登录；staleToken 为失败请求使用的令牌，
+// 其他请求已经重新登录时直接返回新令牌，避免并发请求重复登录
+func (c *AListClient) refreshToken(ctx context.Context, staleToken string) (string, error) {
+	c.authMu.Lock()
+	loginToken := c.auth.loginToken
+	c.authMu.Unlock()
+
+	if loginToken != "" && loginToken != staleToken {
+		return loginToken, nil
+	}
+	return c.login(ctx)
+}
+
+// login 登录获取令牌，同一时间只发起一次登录，其他调用等待并共享结果
+// authMu 只在读取和写入认证状态时持有，登录请求（包括重试）期间不持有
+func (c *AListClient) login(ctx context.Context) (string, error) {
+	c.authMu.Lock()
+	if call := c.auth.loginCall; call != nil {
+		c.authMu.Unlock()
+		select {
+		case <-call.done:
+			return call.token, call.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	call := &loginCall{done: make(chan struct{})}
+	c.auth.loginCall = call
+	c.auth.loginToken = ""
+	c.authMu.Unlock()
+
+	token, failure, err := c.requestLogin(ctx)
+
+	c.authMu.Lock()
+	c.auth.loginCall = nil
+	if err == nil {
+		c.auth.loginToken = token
+		c.auth.loggedInAt = time.Now()
+		c.auth.lastError = ""
+	} else if failure != "" {
+		c.auth.lastError = failure
+	}
+	c.authMu.Unlock()
+
+	call.token, call.err = token, err
+	close(call.done)
+	if err == nil {
+		c.logger.Info("AList 登录成功", zap.String("profile", c.name), zap.String("username", c.config.Username))
+	}
+	return token, err
+}
+
+// requestLogin 使用用户名密码调用 /api/auth/login 获取令牌
+// AList 拒绝登录时 failure 为记录到认证状态的失败信息
+func (c *AListClient) requestLogin(ctx context.Context) (token, failure string, err error) {
+	jsonData, err := json.Marshal(map[string]string{
+		"username": c.config.Username,
+		"password": c.config.Password,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.Host+"/api/auth/login", bytes.NewReader(jsonData))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, body, err := c.doRequest(req)
+	if err != nil {
+		return "", "", fmt.Errorf("AList 登录失败: %w", err)
+	}
+
+	var loginResp struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &loginResp); err != nil {
+		return "", "", fmt.Errorf("AList 登录失败，解析响应失败: %w", err)
+	}
+	if loginResp.Code != http.StatusOK || loginResp.Data.Token == "" {
+		return "", fmt.Sprintf("登录失败: %s", loginResp.Message), fmt.Errorf("AList 登录失败: %s", loginResp.Message)
+	}
+	return loginResp.Data.Token, "", nil
+}
+
+// setAuthError 记录认证失败信息
+func (c *AListClient) setAuthError(message string) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	c.auth.lastError = message
+}
+
+// fillAuthStatus 将认证状态写入连接测试结果
+func (c *AListClient) fillAuthStatus(result *response.AListConnectionTestResult) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	result.Profile = c.name
+	result.AuthMode = c.authMode()
+	result.AuthError = c.auth.lastError
+	switch {
+	case c.auth.loginToken != "":
+		result.Authenticated = true
+		result.TokenSource = "login"
+		loggedInAt := c.auth.loggedInAt
+		result.LoggedInAt = &loggedInAt
+	case c.config.Token != "":
+		result.Authenticated = c.auth.lastError == ""
+		result.TokenSource = "static"
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MccRay-s/alist2strm/model/configs/response"
+)
+
+// alistHostMetrics 单个 AList 主机的请求统计，每次 HTTP 请求（包括重试）计数一次
+type alistHostMetrics struct {
+	requests     int64
+	errors       int64 // 网络错误和 HTTP 4xx/5xx 响应
+	retries      int64
+	throttled    int64 // HTTP 429 或 AList 返回的限流错误
+	totalLatency time.Duration
+	maxLatency   time.Duration
+	lastError    string
+	lastErrorAt  time.Time
+}
+
+// alistMetricsRegistry 按主机汇总的请求统计，同一主机的多个配置档案共享统计，进程重启后清零
+type alistMetricsRegistry struct {
+	mu    sync.Mutex
+	hosts map[string]*alistHostMetrics
+}
+
+var alistMetrics = &alistMetricsRegistry{hosts: make(map[string]*alistHostMetrics)}
+
+// record 记录一次请求的耗时和结果，errMessage 为空表示请求成功
+func (r *alistMetricsRegistry) record(host string, latency time.Duration, retry, throttled bool, errMessage string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m, ok := r.hosts[host]
+	if !ok {
+		m = &alistHostMetrics{}
+		r.hosts[host] = m
+	}
+	m.requests++
+	m.totalLatency += latency
+	if latency > m.maxLatency {
+		m.maxLatency = latency
+	}
+	if retry {
+		m.retries++
+	}
+	if throttled {
+		m.throttled++
+	}
+	if errMessage != "" {
+		m.errors++
+		m.lastError = errMessage
+		m.lastErrorAt = time.Now()
+	}
+}
+
+// snapshot 获取所有主机的统计，按主机排序
+func (r *alistMetricsRegistry) snapshot() []response.AListHostMetrics {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]response.AListHostMetrics, 0, len(r.hosts))
+	for host, m := range r.hosts {
+		item := response.AListHostMetrics{
+			Host:         host,
+			Requests:     m.requests,
+			Errors:       m.errors,
+			Retries:      m.retries,
+			Throttled:    m.throttled,
+			MaxLatencyMs: m.maxLatency.Milliseconds(),
+			LastError:    m.lastError,
+		}
+		if m.requests > 0 {
+			item.AvgLatencyMs = m.totalLatency.Milliseconds() / m.requests
+		}
+		if !m.lastErrorAt.IsZero() {
+			lastErrorAt := m.lastErrorAt
+			item.LastErrorAt = &lastErrorAt
+		}
+		result = append(result, item)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Host < result[j].Host })
+	return result
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/MccRay-s/alist2strm/model/alistprofile"
+	alistProfileRequest "github.com/MccRay-s/alist2strm/model/alistprofile/request"
+	configResponse "github.com/MccRay-s/alist2strm/model/configs/response"
+	"github.com/MccRay-s/alist2strm/repository"
+)
+
+// AListProfileService AList 配置档案服务
+type AListProfileService struct{}
+
+// 包级别的全局实例
+var AListProfile = &AListProfileService{}
+
+// Create 创建配置档案
+func (s *AListProfileService) Create(req *alistProfileRequest.AListProfileCreateReq) (*alistprofile.AListProfile, error) {
+	if err := s.checkName(req.Name, 0); err != nil {
+		return nil, err
+	}
+
+	profile := &alistprofile.AListProfile{
+		Name:             req.Name,
+		Host:             req.Host,
+		Username:         req.Username,
+		Password:         req.Password,
+		Token:            req.Token,
+		Domain:           req.Domain,
+		ReqRetryCount:    req.ReqRetryCount,
+		ReqInterval:      req.ReqInterval,
+		ReqBurst:         req.ReqBurst,
+		ReqRetryInterval: req.ReqRetryInterval,
+		ScanConcurrency:  req.ScanConcurrency,
+		HostConcurrency:  req.HostConcurrency,
+	}
+	if err := repository.AListProfile.Create(profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// GetInfo 获取配置档案信息
+func (s *AListProfileService) GetInfo(id uint) (*alistprofile.AListProfile, error) {
+	profile, err := repository.AListProfile.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, errors.New("AList 配置档案不存在")
+	}
+	return profile, nil
+}
+
+// List 获取所有配置档案
+func (s *AListProfileService) List() ([]alistprofile.AListProfile, error) {
+	return repository.AListProfile.List()
+}
+
+// Update 更新配置档案，已缓存的客户端随之失效
+func (s *AListProfileService) Update(req *alistProfileRequest.AListProfileUpdateReq) error {
+	profile, err := s.GetInfo(req.ID)
+	if err != nil {
+		return err
+	}
+
+	if req.Name != "" {
+		if err := s.checkName(req.Name, profile.ID); err != nil {
+			return err
+		}
+		profile.Name = req.Name
+	}
+	if req.Host != "" {
+		profile.Host = req.Host
+	}
+	if req.Username != nil {
+		profile.Username = *req.Username
+	}
+	if req.Password != nil {
+		profile.Password = *req.Password
+	}
+	if req.Token != nil {
+		profile.Token = *req.Token
+	}
+	if req.Domain != nil {
+		profile.Domain = *req.Domain
+	}
+	if req.ReqRetryCount != nil {
+		profile.ReqRetryCount = *req.ReqRetryCount
+	}
+	if req.ReqInterval != nil {
+		profile.ReqInterval = *req.ReqInterval
+	}
+	if req.ReqBurst != nil {
+		profile.ReqBurst = *req.ReqBurst
+	}
+	if req.ReqRetryInterval != nil {
+		profile.ReqRetryInterval = *req.ReqRetryInterval
+	}
+	if req.ScanConcurrency != nil {
+		profile.ScanConcurrency = *req.ScanConcurrency
+	}
+	if req.HostConcurrency != nil {
+		profile.HostConcurrency = *req.HostConcurrency
+	}
+
+	if err := repository.AListProfile.Update(profile); err != nil {
+		return err
+	}
+	if alistService := GetAListService(); alistService != nil {
+		alistService.InvalidateProfile(profile.ID)
+	}
+	return nil
+}
+
+// Delete 删除配置档案，仍有任务使用时不允许删除
+func (s *AListProfileService) Delete(id uint) error {
+	if _, err := s.GetInfo(id); err != nil {
+		return err
+	}
+	count, err := repository.Task.CountByAListProfileID(id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("仍有 %d 个任务使用该配置档案，无法删除", count)
+	}
+
+	if err := repository.AListProfile.Delete(id); err != nil {
+		return err
+	}
+	if alistService := GetAListService(); alistService != nil {
+		alistService.InvalidateProfile(id)
+	}
+	return nil
+}
+
+// TestConnection 测试配置档案的连接并返回认证状态
+func (s *AListProfileService) TestConnection(id uint) (*configResponse.AListConnectionTestResult, error) {
+	if _, err := s.GetInfo(id); err != nil {
+		return nil, err
+	}
+	alistService := GetAListService()
+	if alistService == nil {
+		return nil, errors.New("AList 服务未初始化")
+	}
+	return alistService.TestConnection(id)
+}
+
+// checkName 检查名称是否与其他档案重复
+func (s *AListProfileService) checkName(name string, excludeID uint) error {
+	exists, err := repository.AListProfile.CheckNameExists(name, excludeID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("配置档案名称已存在")
+	}
+	return nil
+}
+
+// checkAListProfile 检查任务选择的配置档案是否存在，0 表示使用全局 ALIST 配置
+func checkAListProfile(profileID uint) error {
+	if profileID == 0 {
+		return nil
+	}
+	profile, err := repository.AListProfile.GetByID(profileID)
+	if err != nil {
+		return err
+	}
+	if profile == nil {
+		return errors.New("AList 配置档案不存在")
+	}
+	return nil
+}
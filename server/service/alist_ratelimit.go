@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultReqRetryCount    = 3                // 默认重试次数
+	defaultReqRetryInterval = 1000             // 默认重试基础间隔(毫秒)
+	maxRetryBackoff         = 30 * time.Second // 指数退避的最长间隔
+	maxRetryAfter           = 2 * time.Minute  // Retry-After 允许的最长等待时间，超过时按该值等待
+)
+
+// alistThrottleKeywords AList 以业务状态码 500 转发云盘限流错误时消息中常见的关键字（小写）
+var alistThrottleKeywords = []string{
+	"too many requests",
+	"rate limit",
+	"429",
+	"频繁",
+	"限流",
+	"请求过快",
+}
+
+// tokenBucket 令牌桶限流器
+// 以 ReqInterval 为间隔补充令牌，桶容量为 ReqBurst，空闲后允许短时间内连续发出 burst 个请求；
+// 令牌不足时预约下一个令牌并等待，所有并发请求按预约顺序发出，不持有锁等待
+type tokenBucket struct {
+	mu       sync.Mutex
+	interval time.Duration // 补充一个令牌的间隔，<= 0 表示不限速
+	burst    float64
+	tokens   float64
+	last     time.Time // 上次计算令牌的时间
+	paused   time.Time // 被限流后暂停发送请求直到该时间
+}
+
+// newTokenBucket 创建令牌桶，初始为满桶
+func newTokenBucket(interval time.Duration, burst int) *tokenBucket {
+	if burst <= 0 {
+		burst = 1
+	}
+	return &tokenBucket{
+		interval: interval,
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+// wait 获取一个令牌，令牌不足或处于暂停期时等待，ctx 取消时返回 ctx 的错误
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	var delay time.Duration
+	if b.paused.After(now) {
+		delay = b.paused.Sub(now)
+	}
+	if b.interval > 0 {
+		b.tokens += float64(now.Sub(b.last)) / float64(b.interval)
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+		// 预约令牌：令牌可以为负数，表示已被排队中的请求预约
+		b.tokens--
+		if b.tokens < 0 {
+			if wait := time.Duration(-b.tokens * float64(b.interval)); wait > delay {
+				delay = wait
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	return sleepContext(ctx, delay)
+}
+
+// pause 被限流后暂停所有请求，直到指定时间
+func (b *tokenBucket) pause(until time.Time) {
+	b.mu.Lock()
+	if until.After(b.paused) {
+		b.paused = until
+	}
+	b.mu.Unlock()
+}
+
+// retryBackoff 计算第 attempt 次重试（从 1 开始）的等待时间
+// 以 base 为基础按 2 的幂递增，不超过 maxRetryBackoff，并在 [d/2, d] 范围内加入随机抖动，避免并发请求同时重试
+func retryBackoff(base time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	if d > maxRetryBackoff {
+		d = maxRetryBackoff
+	}
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// parseRetryAfter 解析 Retry-After 响应头（秒数或 HTTP 日期），无法解析时返回 0
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	var d time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		d = time.Duration(seconds) * time.Second
+	} else if t, err := http.ParseTime(value); err == nil {
+		d = time.Until(t)
+	}
+	if d < 0 {
+		return 0
+	}
+	if d > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return d
+}
+
+// isRetryableStatus HTTP 429 和 5xx 可以重试
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// isThrottleResponse 判断响应是否表示被限流：HTTP 429，或 AList 业务状态码为 429、
+// 以及以状态码 500 转发的云盘限流错误
+func isThrottleResponse(status int, code int, message string) bool {
+	if status == http.StatusTooManyRequests || code == http.StatusTooManyRequests {
+		return true
+	}
+	if code != http.StatusInternalServerError {
+		return false
+	}
+	message = strings.ToLower(message)
+	for _, keyword := range alistThrottleKeywords {
+		if strings.Contains(message, keyword) {
+			return true
+		}
+	}
+	return false
+}
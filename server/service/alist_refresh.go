@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/MccRay-s/alist2strm/model/task"
+)
+
+// providerRefreshIntervals 各云盘驱动刷新目录列表的最小间隔
+// 刷新会让 AList 跳过缓存直接请求云盘接口，频繁刷新容易触发云盘风控；未列出的驱动（本地存储等）不限制
+var providerRefreshIntervals = map[string]time.Duration{
+	"115 Cloud":        2 * time.Second,
+	"115 Share":        2 * time.Second,
+	"Aliyundrive":      time.Second,
+	"AliyundriveOpen":  time.Second,
+	"AliyundriveShare": time.Second,
+	"BaiduNetdisk":     2 * time.Second,
+	"BaiduPhoto":       2 * time.Second,
+	"Quark":            time.Second,
+	"UC":               time.Second,
+	"123Pan":           time.Second,
+	"Thunder":          time.Second,
+	"PikPak":           time.Second,
+}
+
+// waitRefresh 按驱动的刷新间隔为刷新请求分配发送时间并等待，同一客户端上同一驱动的刷新请求共享节奏
+func (c *AListClient) waitRefresh(ctx context.Context, provider string) error {
+	interval, ok := providerRefreshIntervals[provider]
+	if !ok || interval <= 0 {
+		return nil
+	}
+
+	c.refreshMu.Lock()
+	if c.nextRefreshAt == nil {
+		c.nextRefreshAt = make(map[string]time.Time)
+	}
+	now := time.Now()
+	next := c.nextRefreshAt[provider]
+	if next.Before(now) {
+		next = now
+	}
+	c.nextRefreshAt[provider] = next.Add(interval)
+	c.refreshMu.Unlock()
+
+	return sleepContext(ctx, next.Sub(now))
+}
+
+// listRefresh 单次执行的目录列表刷新策略
+type listRefresh struct {
+	mode  string    // task.RefreshModeAlways 或 task.RefreshModeRecent
+	since time.Time // recent 模式下修改时间晚于该时间的目录才刷新
+}
+
+// newListRefresh 根据任务配置和执行来源创建刷新策略，不需要刷新时返回 nil
+func newListRefresh(taskInfo *task.Task, source string) *listRefresh {
+	switch taskInfo.RefreshMode {
+	case task.RefreshModeAlways, task.RefreshModeRecent:
+	default:
+		return nil
+	}
+	if taskInfo.RefreshManualOnly && source != QueueSourceManual {
+		return nil
+	}
+
+	hours := taskInfo.RefreshRecentHours
+	if hours <= 0 {
+		hours = task.DefaultRefreshRecentHours
+	}
+	return &listRefresh{
+		mode:  taskInfo.RefreshMode,
+		since: time.Now().Add(-time.Duration(hours) * time.Hour),
+	}
+}
+
+// shouldRefresh 判断目录是否需要刷新；扫描起点没有上级列表中的修改时间，只要启用刷新就刷新
+func (r *listRefresh) shouldRefresh(dirInfo *AListFile) bool {
+	if r == nil {
+		return false
+	}
+	if r.mode == task.RefreshModeAlways || dirInfo == nil {
+		return true
+	}
+	return dirInfo.Modified.After(r.since)
+}
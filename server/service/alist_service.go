@@ -2,7 +2,9 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,6 +12,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/MccRay-s/alist2strm/model/alistprofile"
+	"github.com/MccRay-s/alist2strm/model/configs/response"
 	"github.com/MccRay-s/alist2strm/repository"
 	"go.uber.org/zap"
 )
@@ -22,10 +26,22 @@ type AListConfig struct {
 	Token            string `json:"token"`            // API Token
 	Domain           string `json:"domain"`           // 访问域名（用于生成文件URL）
 	ReqRetryCount    int    `json:"reqRetryCount"`    // 重试次数
-	ReqInterval      int64  `json:"reqInterval"`      // 请求间隔时间(毫秒)
-	ReqRetryInterval int64  `json:"reqRetryInterval"` // 重试间隔时间(毫秒)
+	ReqInterval      int64  `json:"reqInterval"`      // 请求间隔时间(毫秒)，即令牌桶补充一个令牌的间隔
+	ReqBurst         int    `json:"reqBurst"`         // 空闲后允许连续发出的请求数（令牌桶容量），0 表示 1
+	ReqRetryInterval int64  `json:"reqRetryInterval"` // 重试基础间隔时间(毫秒)，按重试次数指数递增
+	ScanConcurrency  int    `json:"scanConcurrency"`  // 目录扫描并发数，0 表示使用默认值
+	TaskConcurrency  int    `json:"taskConcurrency"`  // 同时执行的任务数，0 表示使用默认值
+	HostConcurrency  int    `json:"hostConcurrency"`  // 同一 AList 主机上同时执行的任务数，0 表示使用默认值
 }
 
+const (
+	defaultScanConcurrency = 4  // 默认目录扫描并发数
+	maxScanConcurrency     = 32 // 目录扫描并发数上限
+	defaultTaskConcurrency = 2  // 默认同时执行的任务数
+	maxTaskConcurrency     = 16 // 同时执行的任务数上限
+	defaultHostConcurrency = 1  // 默认同一 AList 主机上同时执行的任务数
+)
+
 // AListFile Alist 文件信息
 type AListFile struct {
 	Name     string    `json:"name"`     // 文件名
@@ -53,19 +69,44 @@ type AListListResponse struct {
 }
 
 // AListClient Alist API 客户端
+// 每个 AList 配置（全局 ALIST 配置或配置档案）对应一个客户端，请求限流在同一客户端的所有请求间共享
 type AListClient struct {
+	name       string // 配置名称，用于日志
 	config     *AListConfig
 	httpClient *http.Client
 	logger     *zap.Logger
-	mu         sync.Mutex
+	limiter    *tokenBucket // 请求限流，所有并发请求共享
+	authMu     sync.Mutex
+	auth       alistAuth // 认证状态，登录获取的令牌在同一客户端的所有请求间共享
+	refreshMu  sync.Mutex
+
+	nextRefreshAt map[string]time.Time // 驱动名称 -> 下一个刷新请求允许发出的时间
+}
+
+// ListOptions 获取目录列表的选项
+type ListOptions struct {
+	Password string // 受密码保护目录的密码，未设置密码的目录为空
+	Refresh  bool   // 是否让 AList 跳过缓存重新获取目录列表
+	Provider string // 目录所在存储的驱动名称（来自上级目录的列表），用于控制刷新频率，未知时为空
+}
+
+// alistAPIError AList API 返回的业务错误
+type alistAPIError struct {
+	Code    int
+	Message string
+}
+
+func (e *alistAPIError) Error() string {
+	return fmt.Sprintf("API错误: %s", e.Message)
 }
 
 // AListService AList 服务
 type AListService struct {
-	client *AListClient
-	config *AListConfig
-	logger *zap.Logger
-	mu     sync.RWMutex
+	client   *AListClient          // 全局 ALIST 配置的客户端
+	config   *AListConfig          // 全局 ALIST 配置
+	profiles map[uint]*AListClient // 配置档案ID -> 客户端，首次使用时创建，档案变更或删除后失效
+	logger   *zap.Logger
+	mu       sync.RWMutex
 }
 
 // OnConfigUpdate 实现配置更新监听器接口
@@ -87,7 +128,8 @@ var (
 func InitializeAListService(logger *zap.Logger) *AListService {
 	alistServiceOnce.Do(func() {
 		alistServiceInstance = &AListService{
-			logger: logger,
+			logger:   logger,
+			profiles: make(map[uint]*AListClient),
 		}
 
 		// 加载配置，但不阻断启动
@@ -150,79 +192,218 @@ func (s *AListService) loadConfig() error {
 
 	s.mu.Lock()
 	s.config = &alistConfig
-	s.client = &AListClient{
-		config: &alistConfig,
+	s.client = newAListClient("默认配置", &alistConfig, s.logger)
+	s.mu.Unlock()
+
+	s.logger.Info("AList 配置加载成功", zap.String("host", alistConfig.Host))
+	return nil
+}
+
+// newAListClient 创建 AList 客户端
+func newAListClient(name string, config *AListConfig, logger *zap.Logger) *AListClient {
+	return &AListClient{
+		name:   name,
+		config: config,
 		httpClient: &http.Client{
 			Timeout: time.Second * 30,
 		},
-		logger: s.logger,
+		logger:  logger,
+		limiter: newTokenBucket(time.Duration(config.ReqInterval)*time.Millisecond, config.ReqBurst),
 	}
-	s.mu.Unlock()
+}
 
-	s.logger.Info("AList 配置加载成功", zap.String("host", alistConfig.Host))
-	return nil
+// profileConfig 将配置档案转换为客户端配置
+func profileConfig(profile *alistprofile.AListProfile) *AListConfig {
+	return &AListConfig{
+		Host:             profile.Host,
+		Username:         profile.Username,
+		Password:         profile.Password,
+		Token:            profile.Token,
+		Domain:           profile.Domain,
+		ReqRetryCount:    profile.ReqRetryCount,
+		ReqInterval:      profile.ReqInterval,
+		ReqBurst:         profile.ReqBurst,
+		ReqRetryInterval: profile.ReqRetryInterval,
+		ScanConcurrency:  profile.ScanConcurrency,
+		HostConcurrency:  profile.HostConcurrency,
+	}
 }
 
-// TestConnection 测试连接
-func (s *AListService) TestConnection() error {
+// GetClient 获取 AList 客户端，profileID 为 0 时返回全局 ALIST 配置的客户端
+func (s *AListService) GetClient(profileID uint) (*AListClient, error) {
+	if profileID == 0 {
+		s.mu.RLock()
+		client := s.client
+		s.mu.RUnlock()
+		if client == nil {
+			return nil, fmt.Errorf("未配置 AList，请先完成配置")
+		}
+		return client, nil
+	}
+
 	s.mu.RLock()
-	client := s.client
+	client, ok := s.profiles[profileID]
 	s.mu.RUnlock()
+	if ok {
+		return client, nil
+	}
 
-	if client == nil {
-		return fmt.Errorf("未配置 AList，请先完成配置")
+	profile, err := repository.AListProfile.GetByID(profileID)
+	if err != nil {
+		return nil, fmt.Errorf("获取 AList 配置档案失败: %w", err)
+	}
+	if profile == nil {
+		return nil, fmt.Errorf("AList 配置档案 #%d 不存在", profileID)
 	}
 
-	// 尝试获取根目录列表来测试连接
-	_, err := client.ListFiles("/")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 并发创建时沿用先创建的客户端，保证同一档案的请求间隔共享
+	if client, ok := s.profiles[profileID]; ok {
+		return client, nil
+	}
+	client = newAListClient(profile.Name, profileConfig(profile), s.logger)
+	s.profiles[profileID] = client
+	return client, nil
+}
+
+// InvalidateProfile 配置档案变更或删除后丢弃缓存的客户端，下次使用时重新加载
+// 正在执行的任务继续使用已获取的客户端直到结束
+func (s *AListService) InvalidateProfile(profileID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.profiles, profileID)
+}
+
+// TestConnection 测试连接并返回认证状态，profileID 为 0 时测试全局 ALIST 配置
+// 连接失败时在结果中返回错误信息，只有配置无法加载时返回错误
+func (s *AListService) TestConnection(profileID uint) (*response.AListConnectionTestResult, error) {
+	client, err := s.GetClient(profileID)
 	if err != nil {
-		return fmt.Errorf("连接测试失败: %w", err)
+		return nil, err
 	}
 
-	return nil
+	result := &response.AListConnectionTestResult{}
+	if err := client.TestConnection(context.Background()); err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Connected = true
+	}
+	client.fillAuthStatus(result)
+	return result, nil
 }
 
-// ListFiles 获取指定目录下的文件列表
-func (s *AListService) ListFiles(dirPath string) ([]AListFile, error) {
+// ListFiles 使用全局 ALIST 配置获取指定目录下的文件列表，ctx 取消时中止请求
+func (s *AListService) ListFiles(ctx context.Context, dirPath string, opts ListOptions) ([]AListFile, string, error) {
 	s.mu.RLock()
 	client := s.client
 	s.mu.RUnlock()
 
 	if client == nil {
-		return nil, fmt.Errorf("AList 客户端未初始化")
+		return nil, "", fmt.Errorf("AList 客户端未初始化")
 	}
 
-	return client.ListFiles(dirPath)
+	return client.ListFiles(ctx, dirPath, opts)
 }
 
-// GetFileURL 获取文件的完整访问 URL
-func (s *AListService) GetFileURL(sourcePath, filename, sign string) string {
+// Metrics 获取各 AList 主机的请求统计
+func (s *AListService) Metrics() []response.AListHostMetrics {
+	return alistMetrics.snapshot()
+}
+
+// GetTaskConcurrency 获取同时执行的任务数
+func (s *AListService) GetTaskConcurrency() int {
 	s.mu.RLock()
-	if s.config == nil {
-		s.mu.RUnlock()
-		s.logger.Warn("获取文件 URL 失败：AList 配置未初始化")
-		return ""
+	defer s.mu.RUnlock()
+	if s.config == nil || s.config.TaskConcurrency <= 0 {
+		return defaultTaskConcurrency
 	}
+	if s.config.TaskConcurrency > maxTaskConcurrency {
+		return maxTaskConcurrency
+	}
+	return s.config.TaskConcurrency
+}
+
+// ReloadConfig 重新加载配置
+func (s *AListService) ReloadConfig() error {
+	return s.loadConfig()
+}
 
+// IsConfigured 检查是否已配置
+func (s *AListService) IsConfigured() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config != nil && s.config.Host != ""
+}
+
+// =============================================================================
+// AListClient 客户端实现
+// =============================================================================
+
+// Name 获取客户端对应的配置名称
+func (c *AListClient) Name() string {
+	return c.name
+}
+
+// TestConnection 尝试获取根目录列表来测试连接
+func (c *AListClient) TestConnection(ctx context.Context) error {
+	if _, _, err := c.ListFiles(ctx, "/", ListOptions{}); err != nil {
+		return fmt.Errorf("连接测试失败: %w", err)
+	}
+	return nil
+}
+
+// ScanConcurrency 获取目录扫描并发数
+func (c *AListClient) ScanConcurrency() int {
+	if c.config.ScanConcurrency <= 0 {
+		return defaultScanConcurrency
+	}
+	if c.config.ScanConcurrency > maxScanConcurrency {
+		return maxScanConcurrency
+	}
+	return c.config.ScanConcurrency
+}
+
+// HostConcurrency 获取同一 AList 主机上同时执行的任务数
+func (c *AListClient) HostConcurrency() int {
+	if c.config.HostConcurrency <= 0 {
+		return defaultHostConcurrency
+	}
+	if c.config.HostConcurrency > maxTaskConcurrency {
+		return maxTaskConcurrency
+	}
+	return c.config.HostConcurrency
+}
+
+// HostKey 获取 AList 主机标识，用于限制同一主机上同时执行的任务数
+func (c *AListClient) HostKey() string {
+	return strings.TrimSuffix(strings.ToLower(c.config.Host), "/")
+}
+
+// GetBaseURL 获取用于生成文件 URL 的访问地址（优先 Domain，否则为 Host），不含末尾斜杠
+func (c *AListClient) GetBaseURL() string {
 	// 优先使用 Domain，如果为空则尝试使用 Host
 	var baseURL string
-	if s.config.Domain != "" {
-		baseURL = s.config.Domain
-	} else if s.config.Host != "" {
+	if c.config.Domain != "" {
+		baseURL = c.config.Domain
+	} else if c.config.Host != "" {
 		// 确保 Host 有正确的 http:// 或 https:// 前缀
-		baseURL = s.config.Host
+		baseURL = c.config.Host
 		if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
 			baseURL = "http://" + baseURL
 		}
 	}
-	s.mu.RUnlock()
+	// 确保域名格式正确 (去除末尾的斜杠)
+	return strings.TrimSuffix(baseURL, "/")
+}
 
+// GetFileURL 获取文件的完整访问 URL
+func (c *AListClient) GetFileURL(sourcePath, filename, sign string) string {
+	baseURL := c.GetBaseURL()
 	if baseURL == "" {
-		s.logger.Warn("获取文件 URL 失败：AList Domain 和 Host 均为空")
+		c.logger.Warn("获取文件 URL 失败：AList 配置的 Domain 和 Host 均为空", zap.String("profile", c.name))
 		return ""
 	}
-	// 确保域名格式正确 (去除末尾的斜杠)
-	baseURL = strings.TrimSuffix(baseURL, "/")
 
 	// 标准化路径处理
 	cleanPath := strings.TrimPrefix(sourcePath, "/")
@@ -249,113 +430,243 @@ func (s *AListService) GetFileURL(sourcePath, filename, sign string) string {
 	return fileURL
 }
 
-// ReloadConfig 重新加载配置
-func (s *AListService) ReloadConfig() error {
-	return s.loadConfig()
-}
-
-// IsConfigured 检查是否已配置
-func (s *AListService) IsConfigured() bool {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return s.config != nil && s.config.Host != ""
+// sleepContext 等待指定时间，ctx 取消时提前返回 ctx 的错误
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
 
-// =============================================================================
-// AListClient 客户端实现
-// =============================================================================
-
-// doRequest 执行 HTTP 请求，包含重试和请求间隔逻辑
-func (c *AListClient) doRequest(req *http.Request) (*http.Response, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// doRequest 执行 HTTP 请求并读取响应体，包含限流和重试逻辑
+// 请求经令牌桶限流后发出，请求本身不持有锁，可并发执行；
+// 网络错误、HTTP 429/5xx 和 AList 返回的限流错误按指数退避加随机抖动重试，响应带有 Retry-After 时按其等待；
+// 被限流时暂停该客户端的所有请求。重试耗尽后返回最后一次的错误，业务错误的响应原样返回由调用方处理
+// 请求的 context 取消后不再重试，直接返回取消错误
+func (c *AListClient) doRequest(req *http.Request) (*http.Response, []byte, error) {
+	ctx := req.Context()
+	hostKey := c.HostKey()
 
-	// 添加认证头
-	if c.config.Token != "" {
-		req.Header.Set("Authorization", c.config.Token)
-	}
-
-	var lastErr error
 	maxRetries := c.config.ReqRetryCount
 	if maxRetries <= 0 {
-		maxRetries = 3
+		maxRetries = defaultReqRetryCount
 	}
+	retryInterval := c.config.ReqRetryInterval
+	if retryInterval <= 0 {
+		retryInterval = defaultReqRetryInterval
+	}
+	baseDelay := time.Duration(retryInterval) * time.Millisecond
 
+	var lastErr error
+	var retryAfter time.Duration
 	for i := 0; i <= maxRetries; i++ {
 		if i > 0 {
-			// 重试间隔
-			retryInterval := c.config.ReqRetryInterval
-			if retryInterval <= 0 {
-				retryInterval = 1000
+			delay := retryBackoff(baseDelay, i)
+			if retryAfter > delay {
+				delay = retryAfter
+			}
+			c.logger.Debug("AList 请求重试",
+				zap.String("profile", c.name),
+				zap.String("path", req.URL.Path),
+				zap.Int("attempt", i),
+				zap.Duration("delay", delay),
+				zap.Error(lastErr))
+			if err := sleepContext(ctx, delay); err != nil {
+				return nil, nil, err
+			}
+
+			// 重试时需要重新获取请求体，原请求体已在上次发送时被读取
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, nil, err
+				}
+				req.Body = body
 			}
-			time.Sleep(time.Duration(retryInterval) * time.Millisecond)
 		}
 
-		// 请求间隔
-		if c.config.ReqInterval > 0 {
-			time.Sleep(time.Duration(c.config.ReqInterval) * time.Millisecond)
+		if err := c.limiter.wait(ctx); err != nil {
+			return nil, nil, err
 		}
 
+		start := time.Now()
 		resp, err := c.httpClient.Do(req)
+		var body []byte
 		if err == nil {
-			return resp, nil
+			body, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		latency := time.Since(start)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			alistMetrics.record(hostKey, latency, i > 0, false, err.Error())
+			lastErr = err
+			retryAfter = 0
+			continue
+		}
+
+		var envelope struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(body, &envelope)
+		throttled := isThrottleResponse(resp.StatusCode, envelope.Code, envelope.Message)
+		if !throttled && !isRetryableStatus(resp.StatusCode) {
+			var errMessage string
+			if resp.StatusCode >= http.StatusBadRequest {
+				errMessage = resp.Status
+			}
+			alistMetrics.record(hostKey, latency, i > 0, false, errMessage)
+			return resp, body, nil
+		}
+
+		if throttled {
+			message := envelope.Message
+			if message == "" {
+				message = resp.Status
+			}
+			lastErr = fmt.Errorf("AList 请求被限流: %s", message)
+		} else {
+			lastErr = fmt.Errorf("AList 服务暂时不可用: %s", resp.Status)
+		}
+		alistMetrics.record(hostKey, latency, i > 0, throttled, lastErr.Error())
+		retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		if throttled {
+			pause := retryAfter
+			if pause <= 0 {
+				pause = baseDelay
+			}
+			c.limiter.pause(time.Now().Add(pause))
+			c.logger.Warn("AList 请求被限流，暂停发送请求",
+				zap.String("profile", c.name),
+				zap.String("path", req.URL.Path),
+				zap.Duration("pause", pause))
 		}
-		lastErr = err
 	}
 
-	return nil, lastErr
+	return nil, nil, lastErr
 }
 
-// ListFiles 获取指定目录下的所有文件（非递归，支持分页查询）
-func (c *AListClient) ListFiles(dirPath string) ([]AListFile, error) {
-	if c.config == nil {
-		return nil, fmt.Errorf("客户端未配置")
+// doAPI 调用 AList API 并将响应中的 data 解析到 data 参数
+// 认证失败（HTTP 401 或业务状态码 401）且配置了用户名密码时，重新登录并重试一次
+func (c *AListClient) doAPI(ctx context.Context, apiPath string, payload interface{}, data interface{}) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return err
 	}
 
-	var allFiles []AListFile
-	page := 1
-	perPage := 100 // 每页100个文件，可以根据需要调整
+	token, err := c.token(ctx)
+	if err != nil {
+		return err
+	}
 
-	for {
-		// 构建请求
-		reqBody := map[string]interface{}{
-			"path":     dirPath,
-			"password": "",
-			"page":     page,
-			"per_page": perPage,
-			"refresh":  false,
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.Host+apiPath, bytes.NewReader(jsonData))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", token)
 		}
 
-		jsonData, err := json.Marshal(reqBody)
+		resp, body, err := c.doRequest(req)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		req, err := http.NewRequest("POST", c.config.Host+"/api/fs/list", bytes.NewBuffer(jsonData))
-		if err != nil {
-			return nil, err
+		var apiResp struct {
+			Code    int             `json:"code"`
+			Message string          `json:"message"`
+			Data    json.RawMessage `json:"data"`
 		}
+		parseErr := json.Unmarshal(body, &apiResp)
 
-		req.Header.Set("Content-Type", "application/json")
+		if resp.StatusCode == http.StatusUnauthorized || (parseErr == nil && apiResp.Code == alistCodeUnauthorized) {
+			message := apiResp.Message
+			if message == "" {
+				message = resp.Status
+			}
+			if attempt == 0 && c.canLogin() {
+				c.logger.Warn("AList 认证失败，使用用户名密码重新登录",
+					zap.String("profile", c.name),
+					zap.String("message", message))
+				if token, err = c.refreshToken(ctx, token); err != nil {
+					return err
+				}
+				continue
+			}
+			c.setAuthError(message)
+			return fmt.Errorf("AList 认证失败: %s", message)
+		}
 
-		resp, err := c.doRequest(req)
-		if err != nil {
-			return nil, err
+		if parseErr != nil {
+			return parseErr
+		}
+		if apiResp.Code != http.StatusOK {
+			return &alistAPIError{Code: apiResp.Code, Message: apiResp.Message}
+		}
+		if data != nil && len(apiResp.Data) > 0 {
+			return json.Unmarshal(apiResp.Data, data)
 		}
+		return nil
+	}
+}
 
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			return nil, err
+// ListFiles 获取指定目录下的所有文件（非递归，支持分页查询），同时返回目录所在存储的驱动名称
+// 设置 Refresh 时只在第一页请求刷新，AList 刷新后后续分页直接使用新缓存；
+// 刷新请求按驱动限制频率，当前用户没有刷新权限时退回使用缓存
+func (c *AListClient) ListFiles(ctx context.Context, dirPath string, opts ListOptions) ([]AListFile, string, error) {
+	if c.config == nil {
+		return nil, "", fmt.Errorf("客户端未配置")
+	}
+
+	var allFiles []AListFile
+	var provider string
+	page := 1
+	perPage := 100 // 每页100个文件，可以根据需要调整
+	refresh := opts.Refresh
+
+	for {
+		if refresh && page == 1 {
+			if err := c.waitRefresh(ctx, opts.Provider); err != nil {
+				return nil, "", err
+			}
 		}
 
-		var listResp AListListResponse
-		if err := json.Unmarshal(body, &listResp); err != nil {
-			return nil, err
+		// 构建请求
+		reqBody := map[string]interface{}{
+			"path":     dirPath,
+			"password": opts.Password,
+			"page":     page,
+			"per_page": perPage,
+			"refresh":  refresh && page == 1,
 		}
 
-		if listResp.Code != 200 {
-			return nil, fmt.Errorf("API错误: %s", listResp.Message)
+		var listResp AListListResponse
+		if err := c.doAPI(ctx, "/api/fs/list", reqBody, &listResp.Data); err != nil {
+			var apiErr *alistAPIError
+			if refresh && page == 1 && errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden {
+				c.logger.Warn("没有刷新目录的权限，使用缓存的目录列表",
+					zap.String("profile", c.name),
+					zap.String("path", dirPath),
+					zap.String("message", apiErr.Message))
+				refresh = false
+				continue
+			}
+			return nil, "", err
+		}
+		if listResp.Data.Provider != "" {
+			provider = listResp.Data.Provider
 		}
 
 		// 添加当前页的文件到总列表
@@ -375,5 +686,31 @@ func (c *AListClient) ListFiles(dirPath string) ([]AListFile, error) {
 		page++
 	}
 
-	return allFiles, nil
+	return allFiles, provider, nil
+}
+
+// AListFileInfo /api/fs/get 返回的文件信息
+type AListFileInfo struct {
+	AListFile
+	RawURL   string `json:"raw_url"`  // 云盘直链，通常有有效期
+	Provider string `json:"provider"` // 所在存储的驱动名称
+}
+
+// GetFile 调用 /api/fs/get 获取单个文件的信息，签名和直链以 AList 当前的签名设置为准
+// password 为文件所在目录的密码，未设置密码的目录传空字符串
+func (c *AListClient) GetFile(ctx context.Context, filePath, password string) (*AListFileInfo, error) {
+	if c.config == nil {
+		return nil, fmt.Errorf("客户端未配置")
+	}
+
+	reqBody := map[string]interface{}{
+		"path":     filePath,
+		"password": password,
+	}
+
+	var info AListFileInfo
+	if err := c.doAPI(ctx, "/api/fs/get", reqBody, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
 }
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MccRay-s/alist2strm/model/filehistory"
+	fileHistoryRequest "github.com/MccRay-s/alist2strm/model/filehistory/request"
+	fileHistoryResponse "github.com/MccRay-s/alist2strm/model/filehistory/response"
+	"github.com/MccRay-s/alist2strm/repository"
+	"github.com/MccRay-s/alist2strm/utils"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	defaultLinkCheckConcurrency = 4                // 默认同时检查的链接数
+	linkCheckTimeout            = 15 * time.Second // 单个链接的检查超时
+	maxLinkErrorLength          = 500              // 记录的失败信息最大长度，与 LinkError 列宽一致
+)
+
+// LinkCheckService STRM 链接健康检查服务
+// 读取本地 STRM 文件中的链接并发起 HEAD 请求，HEAD 未成功时再用 Range GET 复核，避免不支持 HEAD 的存储被误判；
+// 每个文件的检查结果记录在文件历史中，同一时间只运行一次检查；可通过接口手动启动，也可按任务的 LinkCheckCron 定时执行
+type LinkCheckService struct {
+	mu     sync.Mutex
+	status fileHistoryResponse.LinkCheckStatusResp
+	client *http.Client
+}
+
+// 包级别的全局实例
+var LinkCheck = &LinkCheckService{
+	client: &http.Client{Timeout: linkCheckTimeout},
+}
+
+// ValidateLinkCheckCron 校验定时链接检查的 Cron 表达式，为空表示不执行
+func ValidateLinkCheckCron(expr string) error {
+	if strings.TrimSpace(expr) == "" {
+		return nil
+	}
+	if _, err := cron.ParseStandard(expr); err != nil {
+		return fmt.Errorf("链接检查 Cron 表达式格式错误: %w", err)
+	}
+	return nil
+}
+
+// Start 在后台启动链接检查，已有检查在进行时返回错误
+func (s *LinkCheckService) Start(req *fileHistoryRequest.LinkCheckReq) (*fileHistoryResponse.LinkCheckStatusResp, error) {
+	histories, err := repository.FileHistory.ListStrm(req.TaskID, req.OnlyBroken)
+	if err != nil {
+		return nil, err
+	}
+	if req.SampleSize > 0 && req.SampleSize < len(histories) {
+		rand.Shuffle(len(histories), func(i, j int) { histories[i], histories[j] = histories[j], histories[i] })
+		histories = histories[:req.SampleSize]
+	}
+
+	concurrency := req.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultLinkCheckConcurrency
+	}
+
+	s.mu.Lock()
+	if s.status.Running {
+		s.mu.Unlock()
+		return nil, errors.New("链接检查正在进行中")
+	}
+	now := time.Now()
+	s.status = fileHistoryResponse.LinkCheckStatusResp{
+		Running:   true,
+		TaskID:    req.TaskID,
+		Total:     len(histories),
+		ByStatus:  make(map[string]int),
+		StartedAt: &now,
+	}
+	s.mu.Unlock()
+
+	utils.Info("开始检查 STRM 链接", "total", len(histories), "concurrency", concurrency, "only_broken", req.OnlyBroken)
+	go s.run(histories, concurrency)
+	return s.Status(), nil
+}
+
+// Status 获取当前或最近一次链接检查的进度
+func (s *LinkCheckService) Status() *fileHistoryResponse.LinkCheckStatusResp {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := s.status
+	status.ByStatus = make(map[string]int, len(s.status.ByStatus))
+	for k, v := range s.status.ByStatus {
+		status.ByStatus[k] = v
+	}
+	return &status
+}
+
+// run 使用有限并发检查所有链接
+func (s *LinkCheckService) run(histories []*filehistory.FileHistory, concurrency int) {
+	jobs := make(chan *filehistory.FileHistory)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for history := range jobs {
+				status := s.checkHistory(context.Background(), history)
+				s.mu.Lock()
+				s.status.Checked++
+				s.status.ByStatus[status]++
+				switch status {
+				case filehistory.LinkStatusOK:
+					s.status.OK++
+				case filehistory.LinkStatusSkipped:
+					// 非 http(s) 链接未检查，只计入 ByStatus
+				default:
+					s.status.Broken++
+				}
+				s.mu.Unlock()
+			}
+		}()
+	}
+	for _, history := range histories {
+		jobs <- history
+	}
+	close(jobs)
+	wg.Wait()
+
+	s.mu.Lock()
+	now := time.Now()
+	s.status.Running = false
+	s.status.FinishedAt = &now
+	checked, broken := s.status.Checked, s.status.Broken
+	s.mu.Unlock()
+
+	utils.Info("STRM 链接检查完成", "checked", checked, "broken", broken)
+}
+
+// isBrokenLinkStatus 链接检查结果是否为失效，未检查、可以访问和未检查的非 http(s) 内容都不算失效
+func isBrokenLinkStatus(status string) bool {
+	return status != "" && status != filehistory.LinkStatusOK && status != filehistory.LinkStatusSkipped
+}
+
+// checkHistory 检查单个 STRM 文件的链接并记录结果
+func (s *LinkCheckService) checkHistory(ctx context.Context, history *filehistory.FileHistory) string {
+	status, message := s.checkStrmFile(ctx, history.TargetFilePath)
+	if len(message) > maxLinkErrorLength {
+		message = message[:maxLinkErrorLength]
+	}
+
+	now := time.Now()
+	if err := repository.FileHistory.UpdateByID(history.ID, map[string]interface{}{
+		"link_status":     status,
+		"link_error":      message,
+		"link_checked_at": &now,
+	}); err != nil {
+		utils.Warn("记录 STRM 链接检查结果失败", "id", history.ID, "error", err.Error())
+	}
+	if status != filehistory.LinkStatusOK && status != filehistory.LinkStatusSkipped {
+		utils.Warn("STRM 链接失效", "strm_file", history.TargetFilePath, "status", status, "error", message)
+	}
+	return status
+}
+
+// checkStrmFile 读取 STRM 文件中的链接并检查，返回检查状态和失败信息
+func (s *LinkCheckService) checkStrmFile(ctx context.Context, strmPath string) (string, string) {
+	content, err := os.ReadFile(strmPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return filehistory.LinkStatusMissing, "本地 STRM 文件不存在"
+	}
+	if err != nil {
+		return filehistory.LinkStatusError, err.Error()
+	}
+
+	// 每行非空内容是一个链接，多分段播放列表有多行，任一链接失效即视为失效
+	var links []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			links = append(links, line)
+		}
+	}
+	if len(links) == 0 {
+		return filehistory.LinkStatusInvalid, "STRM 内容为空"
+	}
+
+	for _, link := range links {
+		if !strings.HasPrefix(link, "http://") && !strings.HasPrefix(link, "https://") {
+			return filehistory.LinkStatusSkipped, "STRM 内容不是 http(s) 链接，未检查"
+		}
+
+		status, message := s.checkLink(ctx, link, http.MethodHead)
+		if status != filehistory.LinkStatusOK && status != filehistory.LinkStatusTimeout {
+			// 部分存储和直链不支持 HEAD 请求，用只读取 1 字节的 GET 请求复核
+			status, message = s.checkLink(ctx, link, http.MethodGet)
+		}
+		if status != filehistory.LinkStatusOK {
+			return status, message
+		}
+	}
+	return filehistory.LinkStatusOK, ""
+}
+
+// checkLink 请求链接并按响应分类，GET 请求只读取第一个字节
+func (s *LinkCheckService) checkLink(ctx context.Context, link, method string) (string, string) {
+	ctx, cancel := context.WithTimeout(ctx, linkCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, link, nil)
+	if err != nil {
+		return filehistory.LinkStatusInvalid, err.Error()
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		var netErr net.Error
+		if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+			return filehistory.LinkStatusTimeout, err.Error()
+		}
+		return filehistory.LinkStatusError, err.Error()
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return filehistory.LinkStatusOK, ""
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return filehistory.LinkStatusNotFound, fmt.Sprintf("%s %s", method, resp.Status)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return filehistory.LinkStatusAuthFailed, fmt.Sprintf("%s %s", method, resp.Status)
+	default:
+		return filehistory.LinkStatusError, fmt.Sprintf("%s %s", method, resp.Status)
+	}
+}
+
+// GetBrokenLinks 分页获取失效链接报告
+func (s *LinkCheckService) GetBrokenLinks(req *fileHistoryRequest.BrokenLinkListReq) (*fileHistoryResponse.FileHistoryListResp, error) {
+	fileHistories, total, err := repository.FileHistory.GetBrokenLinks(req)
+	if err != nil {
+		return nil, err
+	}
+	return &fileHistoryResponse.FileHistoryListResp{
+		List:  fileHistories,
+		Total: total,
+		Page:  req.Page,
+		Size:  req.PageSize,
+	}, nil
+}
+
+// Fix 处理失效链接：按任务当前配置重新生成后立即复查，或删除 STRM 文件、关联的字幕和刮削数据以及文件历史
+// 只处理上次检查结果为失效的记录，与重新签名一样按任务占用，任务正在执行或重新签名时不处理
+func (s *LinkCheckService) Fix(ctx context.Context, req *fileHistoryRequest.BrokenLinkFixReq) (*fileHistoryResponse.BrokenLinkFixResp, error) {
+	histories, err := repository.FileHistory.GetByIDs(req.IDs)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &fileHistoryResponse.BrokenLinkFixResp{Errors: make([]string, 0)}
+	fail := func(history *filehistory.FileHistory, err error) {
+		resp.Failed++
+		resp.Errors = append(resp.Errors, fmt.Sprintf("%s: %v", history.TargetFilePath, err))
+	}
+
+	var strmHistories []*filehistory.FileHistory
+	for _, history := range histories {
+		if !history.IsStrm {
+			fail(history, errors.New("不是 STRM 文件"))
+			continue
+		}
+		if !isBrokenLinkStatus(history.LinkStatus) {
+			fail(history, errors.New("上次链接检查结果不是失效，请重新检查后再处理"))
+			continue
+		}
+		strmHistories = append(strmHistories, history)
+	}
+
+	// 按任务分组处理，每个任务处理期间占用该任务
+	byTask := make(map[uint][]*filehistory.FileHistory)
+	for _, history := range strmHistories {
+		byTask[history.TaskID] = append(byTask[history.TaskID], history)
+	}
+
+	if req.Action == "delete" {
+		for taskID, items := range byTask {
+			results, err := GetStrmGeneratorService().DeleteStrmFiles(taskID, items)
+			for _, history := range items {
+				if err != nil {
+					fail(history, err)
+				} else if itemErr := results[history.ID]; itemErr != nil {
+					fail(history, itemErr)
+				} else {
+					resp.Deleted++
+				}
+			}
+		}
+		utils.Info("已删除失效的 STRM 文件", "deleted", resp.Deleted, "failed", resp.Failed)
+		return resp, nil
+	}
+
+	for taskID, items := range byTask {
+		results, err := GetStrmGeneratorService().RegenerateStrmFiles(ctx, taskID, items)
+		if err != nil && results == nil {
+			for _, history := range items {
+				fail(history, err)
+			}
+			continue
+		}
+		for _, history := range items {
+			itemErr, done := results[history.ID]
+			if !done {
+				fail(history, err)
+				continue
+			}
+			if itemErr != nil {
+				fail(history, itemErr)
+				continue
+			}
+			resp.Regenerated++
+			s.checkHistory(ctx, history)
+		}
+	}
+	utils.Info("已重新生成失效的 STRM 文件", "regenerated", resp.Regenerated, "failed", resp.Failed)
+	return resp, nil
+}
@@ -236,17 +236,62 @@ func (s *NotificationService) SendTaskNotification(taskInfo *task.Task, taskLogI
 	if failedCount, ok := stats["failed_count"].(int); ok {
 		data.FailedCount = failedCount
 	}
+	if orphanFile, ok := stats["orphan_file"].(int); ok {
+		data.OrphanFile = orphanFile
+	}
+	if deletedFile, ok := stats["deleted_file"].(int); ok {
+		data.DeletedFile = deletedFile
+	}
+	if mirrorDryRun, ok := stats["mirror_dry_run"].(bool); ok {
+		data.MirrorDryRun = mirrorDryRun
+	}
+	if unchangedFile, ok := stats["unchanged_file"].(int); ok {
+		data.UnchangedFile = unchangedFile
+	}
+	if skippedDir, ok := stats["skipped_dir"].(int); ok {
+		data.SkippedDir = skippedDir
+	}
+	if failedDirCount, ok := stats["failed_dir_count"].(int); ok {
+		data.FailedDirCount = failedDirCount
+	}
+	if failedDirs, ok := stats["failed_dirs"].([]string); ok {
+		data.FailedDirs = failedDirs
+	}
+	if subPath, ok := stats["sub_path"].(string); ok {
+		data.SubPath = subPath
+	}
+	if excludedDir, ok := stats["excluded_dir"].(int); ok {
+		data.ExcludedDir = excludedDir
+	}
+	if excludedFile, ok := stats["excluded_file"].(int); ok {
+		data.ExcludedFile = excludedFile
+	}
+	if notIncludedFile, ok := stats["not_included_file"].(int); ok {
+		data.NotIncludedFile = notIncludedFile
+	}
+	if subtitleUnmatched, ok := stats["subtitle_unmatched"].(int); ok {
+		data.SubtitleUnmatched = subtitleUnmatched
+	}
+	if unmatchedSubtitles, ok := stats["unmatched_subtitles"].([]string); ok {
+		data.UnmatchedSubtitles = unmatchedSubtitles
+	}
+	if discFragmentFile, ok := stats["disc_fragment_file"].(int); ok {
+		data.DiscFragmentFile = discFragmentFile
+	}
+	if nfoGenerated, ok := stats["nfo_generated"].(int); ok {
+		data.NfoGenerated = nfoGenerated
+	}
 
 	// 设置错误信息（如果有）
-	if status == "failed" && stats["message"] != nil {
+	if (status == "failed" || status == "cancelled") && stats["message"] != nil {
 		if errMsg, ok := stats["message"].(string); ok {
 			data.ErrorMessage = errMsg
 		}
 	}
 
-	// 选择模板类型
+	// 选择模板类型（部分完成同样使用完成模板，模板中展示失败目录）
 	var templateType notification.TemplateType
-	if status == "completed" {
+	if status == "completed" || status == "partial" {
 		templateType = notification.TemplateTypeTaskComplete
 	} else {
 		templateType = notification.TemplateTypeTaskFailed
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"path/filepath"
+	"sync"
+
+	"github.com/MccRay-s/alist2strm/model/tasklog"
+	"github.com/MccRay-s/alist2strm/repository"
+	"go.uber.org/zap"
+)
+
+// dirProgress 单个目录的处理进度
+type dirProgress struct {
+	parent       string // 上级目录，根目录为空
+	pending      int    // 未完成的文件数 + 未完成的子目录数 + 扫描占位
+	filesPending int    // 未完成的文件数
+	filesFailed  bool   // 目录内有文件处理失败
+	treeFailed   bool   // 子树内有文件或目录处理失败
+}
+
+// checkpointTracker 记录本次执行中已完成的目录，并提供断点续传时需要跳过的目录
+// 目录内文件全部处理完成时写入文件断点；目录内文件和所有子目录都完成时写入子树断点
+type checkpointTracker struct {
+	taskID    uint
+	taskLogID uint
+	logger    *zap.Logger
+	mu        sync.Mutex
+	dirs      map[string]*dirProgress
+	filesDone map[string]struct{} // 断点中文件已全部处理的目录
+	treesDone map[string]struct{} // 断点中整个子树已完成的目录
+}
+
+// newCheckpointTracker 创建断点跟踪器，resumeFrom 大于 0 时加载该任务日志的断点
+// 加载的断点会复制到本次执行的任务日志下，以便本次执行再次中断时仍可继续
+func newCheckpointTracker(taskID, taskLogID, resumeFrom uint, logger *zap.Logger) (*checkpointTracker, error) {
+	t := &checkpointTracker{
+		taskID:    taskID,
+		taskLogID: taskLogID,
+		logger:    logger,
+		dirs:      make(map[string]*dirProgress),
+		filesDone: make(map[string]struct{}),
+		treesDone: make(map[string]struct{}),
+	}
+	if resumeFrom == 0 {
+		return t, nil
+	}
+
+	checkpoints, err := repository.TaskLogCheckpoint.ListByTaskLogID(resumeFrom)
+	if err != nil {
+		return nil, err
+	}
+	for i := range checkpoints {
+		path := filepath.Clean(checkpoints[i].SourcePath)
+		t.filesDone[path] = struct{}{}
+		if checkpoints[i].Subtree {
+			t.treesDone[path] = struct{}{}
+		}
+		checkpoints[i].ID = 0
+		checkpoints[i].TaskLogID = taskLogID
+	}
+	if err := repository.TaskLogCheckpoint.Upsert(checkpoints); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// isFilesDone 检查目录内的文件在断点中是否已处理完成
+func (t *checkpointTracker) isFilesDone(sourcePath string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, ok := t.filesDone[filepath.Clean(sourcePath)]
+	return ok
+}
+
+// isTreeDone 检查目录的整个子树在断点中是否已完成
+func (t *checkpointTracker) isTreeDone(sourcePath string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, ok := t.treesDone[filepath.Clean(sourcePath)]
+	return ok
+}
+
+// beginDir 开始处理目录，在目录中的文件加入处理队列之前调用
+// 目录持有一个扫描占位，直到 endDirScan 调用前都不会被判定为完成
+func (t *checkpointTracker) beginDir(sourcePath string) {
+	sourcePath = filepath.Clean(sourcePath)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	parent := filepath.Dir(sourcePath)
+	if _, ok := t.dirs[parent]; !ok || parent == sourcePath {
+		parent = ""
+	}
+	t.dirs[sourcePath] = &dirProgress{parent: parent, pending: 1}
+}
+
+// addFiles 登记目录中加入处理队列的文件数
+func (t *checkpointTracker) addFiles(sourcePath string, count int) {
+	if count <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if p, ok := t.dirs[filepath.Clean(sourcePath)]; ok {
+		p.pending += count
+		p.filesPending += count
+	}
+}
+
+// endDirScan 目录扫描结束，登记需要继续扫描的子目录数并释放扫描占位
+func (t *checkpointTracker) endDirScan(sourcePath string, subDirCount int) {
+	sourcePath = filepath.Clean(sourcePath)
+	t.mu.Lock()
+	p, ok := t.dirs[sourcePath]
+	if !ok {
+		t.mu.Unlock()
+		return
+	}
+	p.pending += subDirCount - 1
+	var completed []tasklog.TaskLogCheckpoint
+	if p.pending == 0 {
+		// 文件和子目录均已完成，直接写入子树断点
+		completed = t.complete(sourcePath, completed)
+	} else if p.filesPending == 0 && !p.filesFailed {
+		completed = append(completed, t.checkpoint(sourcePath, false))
+	}
+	t.mu.Unlock()
+
+	t.save(completed)
+}
+
+// fileDone 文件处理结束
+func (t *checkpointTracker) fileDone(sourceFilePath string, success bool) {
+	dir := filepath.Dir(filepath.Clean(sourceFilePath))
+	t.mu.Lock()
+	p, ok := t.dirs[dir]
+	if !ok {
+		t.mu.Unlock()
+		return
+	}
+	if !success {
+		p.filesFailed = true
+		p.treeFailed = true
+	}
+	p.filesPending--
+	p.pending--
+
+	var completed []tasklog.TaskLogCheckpoint
+	// 扫描占位未释放前 pending 不会为 0，此时文件断点由 endDirScan 负责写入
+	if p.filesPending == 0 && !p.filesFailed && p.pending > 0 {
+		completed = append(completed, t.checkpoint(dir, false))
+	}
+	if p.pending == 0 {
+		completed = t.complete(dir, completed)
+	}
+	t.mu.Unlock()
+
+	t.save(completed)
+}
+
+// failDir 子目录扫描失败，上级目录不再记为子树完成
+func (t *checkpointTracker) failDir(sourcePath string) {
+	parent := filepath.Dir(filepath.Clean(sourcePath))
+	t.mu.Lock()
+	p, ok := t.dirs[parent]
+	if !ok {
+		t.mu.Unlock()
+		return
+	}
+	p.treeFailed = true
+	p.pending--
+
+	var completed []tasklog.TaskLogCheckpoint
+	if p.pending == 0 {
+		completed = t.complete(parent, completed)
+	}
+	t.mu.Unlock()
+
+	t.save(completed)
+}
+
+// complete 目录及其子树处理结束，逐级通知上级目录，调用方需持有锁
+func (t *checkpointTracker) complete(dir string, completed []tasklog.TaskLogCheckpoint) []tasklog.TaskLogCheckpoint {
+	for dir != "" {
+		p := t.dirs[dir]
+		delete(t.dirs, dir)
+		if !p.treeFailed {
+			completed = append(completed, t.checkpoint(dir, true))
+		}
+
+		parent, ok := t.dirs[p.parent]
+		if !ok {
+			return completed
+		}
+		if p.treeFailed {
+			parent.treeFailed = true
+		}
+		parent.pending--
+		if parent.pending > 0 {
+			return completed
+		}
+		dir = p.parent
+	}
+	return completed
+}
+
+// checkpoint 构建断点记录
+func (t *checkpointTracker) checkpoint(sourcePath string, subtree bool) tasklog.TaskLogCheckpoint {
+	return tasklog.TaskLogCheckpoint{
+		TaskLogID:  t.taskLogID,
+		TaskID:     t.taskID,
+		SourcePath: sourcePath,
+		Subtree:    subtree,
+	}
+}
+
+// save 写入断点，失败只记录日志，不影响任务执行
+func (t *checkpointTracker) save(checkpoints []tasklog.TaskLogCheckpoint) {
+	if len(checkpoints) == 0 {
+		return
+	}
+	if err := repository.TaskLogCheckpoint.Upsert(checkpoints); err != nil {
+		t.logger.Error("保存任务断点失败", zap.Uint("taskLogId", t.taskLogID), zap.Error(err))
+	}
+}
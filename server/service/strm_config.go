@@ -0,0 +1,62 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/MccRay-s/alist2strm/model/task"
+)
+
+// loadEffectiveStrmConfig 加载全局 STRM 配置并应用任务级覆盖，得到任务实际生效的配置
+func loadEffectiveStrmConfig(taskInfo *task.Task) (*StrmConfig, error) {
+	strmConfig, err := loadStrmConfig()
+	if err != nil {
+		return nil, err
+	}
+	return applyStrmOverride(strmConfig, &taskInfo.StrmOverride), nil
+}
+
+// applyStrmOverride 将任务级覆盖应用到全局配置的副本上，未设置的字段沿用全局配置
+func applyStrmOverride(global *StrmConfig, override *task.StrmOverride) *StrmConfig {
+	effective := *global
+	if override.DefaultSuffix != nil {
+		effective.DefaultSuffix = *override.DefaultSuffix
+	}
+	if override.ReplaceSuffix != nil {
+		effective.ReplaceSuffix = *override.ReplaceSuffix
+	}
+	if override.URLEncode != nil {
+		effective.URLEncode = *override.URLEncode
+	}
+	if override.MinFileSize != nil {
+		effective.MinFileSize = *override.MinFileSize
+	}
+	return &effective
+}
+
+// ValidateStrmOverride 校验任务级 STRM 配置覆盖
+func ValidateStrmOverride(override *task.StrmOverride) error {
+	if override.DefaultSuffix != nil {
+		for _, suffix := range strings.Split(*override.DefaultSuffix, ",") {
+			suffix = strings.TrimSpace(suffix)
+			if suffix == "" || strings.HasPrefix(suffix, ".") {
+				return errors.New("媒体文件后缀格式错误，应为逗号分隔且不含点号的扩展名，例如 mkv,iso")
+			}
+		}
+	}
+	if override.MinFileSize != nil && *override.MinFileSize < 0 {
+		return errors.New("最小文件大小不能小于 0")
+	}
+	return nil
+}
+
+// strmOverrideScopeChanged 检查覆盖中影响文件选择范围的字段（后缀和最小文件大小）是否变化
+func strmOverrideScopeChanged(old, new *task.StrmOverride) bool {
+	if (old.DefaultSuffix == nil) != (new.DefaultSuffix == nil) || (old.MinFileSize == nil) != (new.MinFileSize == nil) {
+		return true
+	}
+	if old.DefaultSuffix != nil && *old.DefaultSuffix != *new.DefaultSuffix {
+		return true
+	}
+	return old.MinFileSize != nil && *old.MinFileSize != *new.MinFileSize
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// discLayout 原盘目录结构：识别目录名、视频流所在的子目录和视频流扩展名
+type discLayout struct {
+	Kind       string
+	MarkerDir  string // 原盘目录下的标识目录（小写）
+	StreamDir  string // 视频流所在目录，相对标识目录，为空表示标识目录本身
+	StreamExts []string
+}
+
+var discLayouts = []discLayout{
+	{Kind: "Blu-ray", MarkerDir: "bdmv", StreamDir: "STREAM", StreamExts: []string{".m2ts", ".mts"}},
+	{Kind: "DVD", MarkerDir: "video_ts", StreamDir: "", StreamExts: []string{".vob"}},
+}
+
+// discStructureDirs 原盘目录中不再单独扫描的结构目录（小写）
+var discStructureDirs = map[string]bool{
+	"bdmv":        true,
+	"certificate": true,
+	"aacs":        true,
+	"video_ts":    true,
+	"audio_ts":    true,
+}
+
+// discTitle 识别到的原盘及其主标题
+type discTitle struct {
+	Kind        string
+	Main        AListFile // 主标题：同目录下的 ISO，或最大的视频流
+	MainPath    string
+	StreamFiles int // 视频流目录中的文件数
+	Fragments   int // 跳过的分段文件数
+}
+
+// detectDiscTitle 检查目录是否为原盘目录（包含 BDMV 或 VIDEO_TS），是则列出视频流目录并选出主标题
+// 目录中同时存在 ISO 文件时以最大的 ISO 为主标题；不是原盘或视频流目录中没有视频流时返回 nil
+// 视频流目录与原盘目录使用相同的刷新设置；原盘目录列表未变化且上次生成的 STRM 仍在时沿用文件历史中的主标题，不再列出视频流目录
+func (s *generatorRun) detectDiscTitle(ctx context.Context, sourcePath string, files []AListFile, provider string, refresh, listingUnchanged bool) (*discTitle, error) {
+	var layout *discLayout
+	var markerName string
+	for i := range files {
+		if !files[i].IsDir {
+			continue
+		}
+		for j := range discLayouts {
+			if strings.EqualFold(files[i].Name, discLayouts[j].MarkerDir) {
+				layout, markerName = &discLayouts[j], files[i].Name
+				break
+			}
+		}
+		if layout != nil {
+			break
+		}
+	}
+	if layout == nil {
+		return nil, nil
+	}
+
+	streamDir := filepath.Join(sourcePath, markerName, layout.StreamDir)
+	if listingUnchanged {
+		if history := s.snapshots.largestHistoryIn(sourcePath, streamDir); history != nil && s.fileExistsLocally(history.TargetFilePath) {
+			disc := &discTitle{Kind: layout.Kind, MainPath: history.SourcePath}
+			disc.Main = AListFile{Name: filepath.Base(history.SourcePath), Size: history.FileSize}
+			if history.ModifiedAt != nil {
+				disc.Main.Modified = *history.ModifiedAt
+			}
+			return disc, nil
+		}
+	}
+
+	streamFiles, _, err := s.alist.ListFiles(ctx, streamDir, ListOptions{
+		Password: s.passwords.lookup(streamDir),
+		Refresh:  refresh,
+		Provider: provider,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取原盘视频流目录失败 [%s]: %w", streamDir, err)
+	}
+
+	disc := &discTitle{Kind: layout.Kind}
+	for i := range files {
+		if !files[i].IsDir && strings.EqualFold(filepath.Ext(files[i].Name), ".iso") && files[i].Size > disc.Main.Size {
+			disc.Main, disc.MainPath = files[i], filepath.Join(sourcePath, files[i].Name)
+		}
+	}
+	fromISO := disc.MainPath != ""
+
+	for i := range streamFiles {
+		if streamFiles[i].IsDir {
+			continue
+		}
+		disc.StreamFiles++
+		if fromISO || !hasExtension(streamFiles[i].Name, layout.StreamExts) {
+			continue
+		}
+		if disc.MainPath == "" || streamFiles[i].Size > disc.Main.Size {
+			disc.Main, disc.MainPath = streamFiles[i], filepath.Join(streamDir, streamFiles[i].Name)
+		}
+	}
+	if disc.MainPath == "" {
+		return nil, nil
+	}
+
+	disc.Fragments = disc.StreamFiles
+	if !fromISO {
+		disc.Fragments--
+	}
+	return disc, nil
+}
+
+// entry 构建主标题的媒体文件条目，STRM 以原盘目录名命名，放在原盘目录对应的目标目录中
+func (d *discTitle) entry(sourcePath, targetPath string) FileEntry {
+	name := filepath.Base(sourcePath)
+	main := d.Main
+	return FileEntry{
+		File:           &main,
+		FileType:       FileTypeMedia,
+		SourcePath:     d.MainPath,
+		TargetPath:     filepath.Join(targetPath, name+filepath.Ext(main.Name)),
+		NameWithoutExt: name,
+		DiscRoot:       sourcePath,
+	}
+}
+
+// isDiscStructureDir 判断目录是否为原盘的结构目录
+func isDiscStructureDir(name string) bool {
+	return discStructureDirs[strings.ToLower(name)]
+}
+
+// hasExtension 判断文件扩展名是否在列表中（不区分大小写）
+func hasExtension(name string, exts []string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range exts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// checkpointPath 断点按扫描目录记录文件的完成情况，原盘主标题位于视频流子目录中，按原盘目录记录
+func (e *FileEntry) checkpointPath() string {
+	if e.DiscRoot != "" {
+		return filepath.Join(e.DiscRoot, filepath.Base(e.SourcePath))
+	}
+	return e.SourcePath
+}
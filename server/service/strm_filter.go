@@ -0,0 +1,133 @@
+package service
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// regexRulePrefix 以该前缀开头的规则按正则表达式匹配，否则按 glob 通配符匹配
+const regexRulePrefix = "re:"
+
+// fileFilterRule 单条包含/排除规则
+type fileFilterRule struct {
+	pattern string         // glob 通配符（已转换为小写），正则规则为空
+	re      *regexp.Regexp // 正则表达式，glob 规则为 nil
+	dirOnly bool           // 以 / 结尾的规则只匹配目录
+}
+
+// fileFilter 任务的文件和目录过滤规则，规则只匹配文件名或目录名，不匹配完整路径
+// 规则每行一条，空行和以 # 开头的行忽略：
+//
+//	Extras/          排除名为 Extras 的目录（以 / 结尾只匹配目录）
+//	@eaDir           排除名为 @eaDir 的目录或文件
+//	*sample*         glob 通配符，忽略大小写
+//	*.partial        排除未下载完成的文件
+//	re:S\d{2}E\d{2}  正则表达式，区分大小写，可使用 (?i) 忽略大小写
+//
+// 排除规则同时作用于目录和文件，被排除的目录不再扫描；包含规则只作用于媒体文件，
+// 设置后只为匹配的媒体文件生成 STRM，字幕和元数据文件仍按扩展名和媒体文件匹配处理
+type fileFilter struct {
+	include []fileFilterRule
+	exclude []fileFilterRule
+}
+
+// parseFileFilter 解析任务的包含/排除规则，两者均为空时返回 nil 表示不过滤
+func parseFileFilter(includeRules, excludeRules string) (*fileFilter, error) {
+	include, err := parseFilterRules(includeRules)
+	if err != nil {
+		return nil, fmt.Errorf("包含规则格式错误: %w", err)
+	}
+	for _, rule := range include {
+		if rule.dirOnly {
+			return nil, fmt.Errorf("包含规则只匹配文件名，不支持目录规则")
+		}
+	}
+	exclude, err := parseFilterRules(excludeRules)
+	if err != nil {
+		return nil, fmt.Errorf("排除规则格式错误: %w", err)
+	}
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil, nil
+	}
+	return &fileFilter{include: include, exclude: exclude}, nil
+}
+
+// ValidateFileFilterRules 校验任务的包含/排除规则
+func ValidateFileFilterRules(includeRules, excludeRules string) error {
+	_, err := parseFileFilter(includeRules, excludeRules)
+	return err
+}
+
+// parseFilterRules 按行解析规则
+func parseFilterRules(text string) ([]fileFilterRule, error) {
+	var rules []fileFilterRule
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, regexRulePrefix) {
+			re, err := regexp.Compile(strings.TrimPrefix(line, regexRulePrefix))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", line, err)
+			}
+			rules = append(rules, fileFilterRule{re: re})
+			continue
+		}
+
+		rule := fileFilterRule{pattern: strings.ToLower(line)}
+		if strings.HasSuffix(rule.pattern, "/") {
+			rule.pattern = strings.TrimRight(rule.pattern, "/")
+			rule.dirOnly = true
+		}
+		if rule.pattern == "" {
+			return nil, fmt.Errorf("%s: 规则不能为空", line)
+		}
+		if _, err := path.Match(rule.pattern, ""); err != nil {
+			return nil, fmt.Errorf("%s: %w", line, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// match 检查名称是否匹配规则
+func (r *fileFilterRule) match(name string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.re != nil {
+		return r.re.MatchString(name)
+	}
+	matched, _ := path.Match(r.pattern, strings.ToLower(name))
+	return matched
+}
+
+// excludes 检查目录或文件是否被排除规则排除
+func (f *fileFilter) excludes(name string, isDir bool) bool {
+	if f == nil {
+		return false
+	}
+	for i := range f.exclude {
+		if f.exclude[i].match(name, isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// includesMedia 检查媒体文件是否满足包含规则，未设置包含规则时全部满足
+func (f *fileFilter) includesMedia(name string) bool {
+	if f == nil || len(f.include) == 0 {
+		return true
+	}
+	for i := range f.include {
+		if f.include[i].match(name, false) {
+			return true
+		}
+	}
+	return false
+}
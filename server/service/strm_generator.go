@@ -1,15 +1,20 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/MccRay-s/alist2strm/model/filehistory"
@@ -35,6 +40,8 @@ const (
 	FileTypeMetadata
 	FileTypeSubtitle
 	FileTypeOther
+	FileTypeExcluded    // 被排除规则排除的文件
+	FileTypeNotIncluded // 不满足包含规则的媒体文件
 )
 
 // ProcessedFile 处理后的文件信息
@@ -44,6 +51,8 @@ type ProcessedFile struct {
 	FileType     FileType
 	Success      bool
 	ErrorMessage string
+
+	Skipped bool // STRM 文件已存在且不允许覆盖，未重新生成，不属于处理失败
 }
 
 // FileProcessResult 文件处理结果
@@ -75,19 +84,60 @@ type ProcessingStats struct {
 	SubtitleSkipped        int          // 已跳过的字幕文件数
 	OtherSkipped           int          // 跳过的其他类型文件数
 	FailedCount            int          // 处理失败的文件数 (与 TaskLog 字段保持一致)
+	OrphanFile             int          // 镜像模式发现的孤立文件数 (与 TaskLog 字段保持一致)
+	DeletedFile            int          // 镜像模式删除的文件数 (与 TaskLog 字段保持一致)
+	UnchangedFile          int          // 增量扫描判定未变化而跳过的媒体文件数（已计入 SkipFile）
+	SkippedDir             int          // 增量扫描判定目录列表未变化而跳过文件处理的目录数
+	ResumedDir             int          // 断点续传时跳过的已完成目录数
+	ExcludedDir            int          // 被排除规则排除的目录数
+	ExcludedFile           int          // 被排除规则排除的文件数
+	NotIncludedFile        int          // 不满足包含规则的媒体文件数
+	RefreshedDir           int          // 请求 AList 刷新列表的目录数
+	DiscTitle              int          // 识别到的原盘目录数，每个原盘只生成一个 STRM
+	DiscFragment           int          // 原盘中跳过的分段文件数
+	StackedFile            int          // 合并到播放列表而未单独生成 STRM 的分段数（已计入 SkipFile）
+	SubtitleUnmatched      int          // 未匹配到媒体文件的字幕数（不计入 SubtitleSkipped）
+	RenamedFile            int          // 按解析出的媒体信息改名的媒体文件数
+	RenameConflict         int          // 改名后与其他文件冲突而保留原文件名的媒体文件数
+	NfoGenerated           int          // 本地生成的 NFO 文件数
 	ScanFinished           bool         // 目录扫描是否已完成
 	StrmProcessingDone     bool         // STRM 文件处理是否已完成
 	DownloadProcessingDone bool         // 下载文件处理是否已完成
 	Mutex                  sync.RWMutex // 用于安全访问统计的互斥锁
+
+	UnmatchedSubtitles []string // 未匹配的字幕文件路径，最多记录 maxReportedUnmatchedSubtitles 条
 }
 
 // StrmGeneratorService STRM 文件生成服务
+// 服务本身只保存共享依赖，每次生成的队列和统计保存在 generatorRun 中，多个任务可以同时执行
 type StrmGeneratorService struct {
 	alistService *AListService
 	logger       *zap.Logger
 	mu           sync.RWMutex
-	queue        *FileProcessQueue // 文件处理队列
-	stats        *ProcessingStats  // 处理统计
+}
+
+// generatorRun 单次生成的运行状态，每次调用 GenerateStrmFiles 时创建
+type generatorRun struct {
+	*StrmGeneratorService
+	queue        *FileProcessQueue  // 文件处理队列
+	stats        *ProcessingStats   // 处理统计
+	mirror       *mirrorTracker     // 镜像模式目标文件跟踪
+	snapshots    *snapshotTracker   // 增量扫描目录快照
+	strmTemplate *template.Template // 本次任务的 STRM 内容模板，nil 表示使用默认直链
+	checkpoints  *checkpointTracker // 断点记录
+	filter       *fileFilter        // 包含/排除规则，nil 表示不过滤
+	alist        *AListClient       // 任务选择的 AList 配置对应的客户端
+	passwords    *folderPasswords   // 受密码保护目录的密码，nil 表示未配置
+	refresh      *listRefresh       // 目录列表刷新策略，nil 表示使用 AList 缓存
+	renamer      *mediaRenamer      // 媒体文件改名器，nil 表示保留原文件名
+	nfos         *nfoWriter         // 本地 NFO 生成器，nil 表示不生成
+}
+
+// GenerateOptions 单次生成的执行选项
+type GenerateOptions struct {
+	ResumeFromLogID uint   // 从指定任务日志的断点继续执行，0 表示完整执行
+	SubPath         string // 只扫描任务源路径下的子路径（已校验的相对路径），为空表示扫描整个源路径
+	Source          string // 执行来源（QueueSource*），用于判断是否为手动执行
 }
 
 var (
@@ -98,13 +148,7 @@ var (
 // GetStrmGeneratorService 获取 STRM 生成服务实例
 func GetStrmGeneratorService() *StrmGeneratorService {
 	strmGeneratorOnce.Do(func() {
-		strmGeneratorInstance = &StrmGeneratorService{
-			queue: &FileProcessQueue{
-				StrmFiles:     make([]FileEntry, 0),
-				DownloadFiles: make([]FileEntry, 0),
-			},
-			stats: &ProcessingStats{},
-		}
+		strmGeneratorInstance = &StrmGeneratorService{}
 	})
 	return strmGeneratorInstance
 }
@@ -116,13 +160,6 @@ func (s *StrmGeneratorService) Initialize(logger *zap.Logger) {
 	s.logger = logger
 	s.alistService = GetAListService()
 
-	// 初始化队列和统计信息
-	s.queue = &FileProcessQueue{
-		StrmFiles:     make([]FileEntry, 0),
-		DownloadFiles: make([]FileEntry, 0),
-	}
-	s.stats = &ProcessingStats{}
-
 	logger.Info("STRM 生成服务初始化完成")
 }
 
@@ -134,36 +171,63 @@ func (s *StrmGeneratorService) IsInitialized() bool {
 }
 
 // GenerateStrmFiles 生成 STRM 文件主方法
-func (s *StrmGeneratorService) GenerateStrmFiles(taskID uint) error {
+// ctx 取消后停止扫描和处理，已完成的统计写入任务日志并标记为已取消，返回 ctx 的错误
+// 每次调用使用独立的处理队列和统计信息，可被多个任务并发调用
+func (s *StrmGeneratorService) GenerateStrmFiles(ctx context.Context, taskID uint, opts GenerateOptions) error {
 	// 检查服务是否已初始化
 	if !s.IsInitialized() {
 		return fmt.Errorf("STRM 生成服务未正确初始化")
 	}
 
+	run := &generatorRun{
+		StrmGeneratorService: s,
+		queue: &FileProcessQueue{
+			StrmFiles:     make([]FileEntry, 0),
+			DownloadFiles: make([]FileEntry, 0),
+		},
+		stats:  &ProcessingStats{},
+		mirror: newMirrorTracker(),
+	}
+	return run.generate(ctx, taskID, opts)
+}
+
+// generate 执行一次 STRM 文件生成
+func (s *generatorRun) generate(ctx context.Context, taskID uint, opts GenerateOptions) error {
 	// 获取任务信息
 	taskInfo, err := repository.Task.GetByID(taskID)
 	if err != nil {
 		return fmt.Errorf("获取任务信息失败: %w", err)
 	}
 
-	// 重置处理队列和统计信息
-	s.queue = &FileProcessQueue{
-		StrmFiles:     make([]FileEntry, 0),
-		DownloadFiles: make([]FileEntry, 0),
+	// 断点续传沿用被继续执行的那次运行的扫描范围
+	if opts.ResumeFromLogID > 0 && opts.SubPath == "" {
+		if resumeLog, logErr := repository.TaskLog.GetByID(opts.ResumeFromLogID); logErr == nil && resumeLog != nil {
+			opts.SubPath = resumeLog.SubPath
+		}
+	}
+
+	// 确定本次扫描的源目录和目标目录
+	root := dirScanJob{SourcePath: taskInfo.SourcePath, TargetPath: taskInfo.TargetPath}
+	startMessage := "开始生成 STRM 文件"
+	if opts.SubPath != "" {
+		root.SourcePath = path.Join(taskInfo.SourcePath, opts.SubPath)
+		root.TargetPath = filepath.Join(taskInfo.TargetPath, filepath.FromSlash(opts.SubPath))
+		startMessage = fmt.Sprintf("开始生成 STRM 文件（扫描范围: %s）", opts.SubPath)
 	}
-	s.stats = &ProcessingStats{}
 
 	// 创建任务日志
 	taskLog := &tasklog.TaskLog{
 		TaskID:        taskID,
 		Status:        tasklog.TaskLogStatusRunning,
-		Message:       "开始生成 STRM 文件",
+		Message:       startMessage,
 		StartTime:     time.Now(),
 		TotalFile:     0,
 		GeneratedFile: 0,
 		SkipFile:      0,
 		MetadataCount: 0,
 		SubtitleCount: 0,
+		SubPath:       opts.SubPath,
+		PartialScope:  opts.SubPath != "",
 	}
 
 	taskLogID, err := s.createTaskLog(taskLog)
@@ -171,18 +235,77 @@ func (s *StrmGeneratorService) GenerateStrmFiles(taskID uint) error {
 		return fmt.Errorf("创建任务日志失败: %w", err)
 	}
 
-	// 加载 STRM 配置
-	strmConfig, err := s.loadStrmConfig()
+	// 获取任务使用的 AList 客户端
+	s.alist, err = s.alistService.GetClient(taskInfo.AListProfileID)
 	if err != nil {
+		s.updateTaskLogWithError(taskLogID, "获取 AList 配置失败: "+err.Error())
+		return err
+	}
+
+	s.passwords = newFolderPasswords(taskInfo)
+	s.refresh = newListRefresh(taskInfo, opts.Source)
+	s.renamer, err = newMediaRenamer(taskInfo)
+	if err != nil {
+		s.updateTaskLogWithError(taskLogID, "加载媒体改名记录失败: "+err.Error())
+		return err
+	}
+	s.nfos = newNFOWriter(taskInfo)
+
+	// 加载 STRM 配置，任务级覆盖在本次执行开始时一次性应用
+	strmConfig, err := loadEffectiveStrmConfig(taskInfo)
+	if err != nil {
+		s.logger.Error("加载 STRM 配置失败", zap.Error(err))
 		s.updateTaskLogWithError(taskLogID, "加载 STRM 配置失败: "+err.Error())
 		return err
 	}
+	s.logger.Info("本次执行的 STRM 配置",
+		zap.String("defaultSuffix", strmConfig.DefaultSuffix),
+		zap.Bool("replaceSuffix", strmConfig.ReplaceSuffix),
+		zap.Bool("urlEncode", strmConfig.URLEncode),
+		zap.Int64("minFileSize", strmConfig.MinFileSize))
+
+	// 解析 STRM 内容模板
+	s.strmTemplate, err = parseStrmTemplate(taskInfo.StrmTemplate)
+	if err != nil {
+		s.updateTaskLogWithError(taskLogID, "STRM 内容模板格式错误: "+err.Error())
+		return err
+	}
+
+	// 解析包含/排除规则
+	s.filter, err = parseFileFilter(taskInfo.IncludeRules, taskInfo.ExcludeRules)
+	if err != nil {
+		s.updateTaskLogWithError(taskLogID, err.Error())
+		return err
+	}
+
+	// 加载断点
+	s.checkpoints, err = newCheckpointTracker(taskID, taskLogID, opts.ResumeFromLogID, s.logger)
+	if err != nil {
+		s.updateTaskLogWithError(taskLogID, "加载任务断点失败: "+err.Error())
+		return err
+	}
+	if opts.ResumeFromLogID > 0 {
+		resumeMessage := fmt.Sprintf("从任务日志 #%d 的断点继续生成 STRM 文件", opts.ResumeFromLogID)
+		if updateErr := repository.TaskLog.UpdatePartial(taskLogID, map[string]interface{}{"message": resumeMessage}); updateErr != nil {
+			s.logger.Error("更新任务日志失败", zap.Error(updateErr))
+		}
+		s.logger.Info(resumeMessage, zap.Uint("taskId", taskID))
+	}
+
+	// 加载增量扫描快照
+	s.snapshots, err = newSnapshotTracker(taskID, taskInfo.SkipUnchanged)
+	if err != nil {
+		s.updateTaskLogWithError(taskLogID, "加载目录快照失败: "+err.Error())
+		return err
+	}
 
 	// 开始处理文件
 	s.logger.Info("开始处理任务",
 		zap.Uint("taskId", taskID),
-		zap.String("sourcePath", taskInfo.SourcePath),
-		zap.String("targetPath", taskInfo.TargetPath))
+		zap.String("sourcePath", root.SourcePath),
+		zap.String("targetPath", root.TargetPath),
+		zap.String("subPath", opts.SubPath),
+		zap.String("alistProfile", s.alist.Name()))
 
 	// 先启动STRM文件处理协程（并发），让它等待队列中的项目
 	var strmProcessingErr error
@@ -195,13 +318,14 @@ func (s *StrmGeneratorService) GenerateStrmFiles(taskID uint) error {
 	// 1. 先启动STRM文件生成协程（并发），它会立即开始处理媒体文件
 	go func() {
 		defer wg.Done()
-		strmProcessingErr = s.processStrmFileQueueAsync(taskInfo, strmConfig, taskLogID, strmScanDoneChan)
+		strmProcessingErr = s.processStrmFileQueueAsync(ctx, taskInfo, strmConfig, taskLogID, strmScanDoneChan)
 	}()
 
-	// 现在开始递归扫描，边扫描边将媒体文件加入队列（立即处理）
+	// 现在开始并发扫描，边扫描边将媒体文件加入队列（立即处理）
 	startTime := time.Now()
-	err = s.scanDirectoryRecursive(taskInfo, strmConfig, taskLogID, taskInfo.SourcePath, taskInfo.TargetPath)
-	if err != nil {
+	scanFailures, err := s.scanDirectories(ctx, taskInfo, strmConfig, taskLogID, root, s.alist.ScanConcurrency())
+	// 取消导致的扫描中断不按失败处理，继续写入已完成部分的统计
+	if err != nil && ctx.Err() == nil {
 		// 通知STRM协程扫描已结束（失败）
 		close(strmScanDoneChan)
 
@@ -213,6 +337,13 @@ func (s *StrmGeneratorService) GenerateStrmFiles(taskID uint) error {
 	}
 	scanDuration := time.Since(startTime)
 
+	// 保存扫描失败的目录
+	if len(scanFailures) > 0 {
+		if saveErr := repository.TaskLogFailure.CreateBatch(scanFailures); saveErr != nil {
+			s.logger.Error("保存目录扫描失败记录失败", zap.Error(saveErr))
+		}
+	}
+
 	// 目录扫描完成后，标记扫描结束，并更新任务日志中的总文件数
 	s.stats.Mutex.Lock()
 	s.stats.ScanFinished = true
@@ -222,6 +353,7 @@ func (s *StrmGeneratorService) GenerateStrmFiles(taskID uint) error {
 	s.logger.Info("目录扫描完成",
 		zap.Int("总文件数", totalFiles),
 		zap.Duration("扫描用时", scanDuration),
+		zap.Int("失败目录数", len(scanFailures)),
 		zap.Int("STRM队列长度", len(s.queue.StrmFiles)),
 		zap.Int("下载队列长度", len(s.queue.DownloadFiles)))
 
@@ -242,11 +374,11 @@ func (s *StrmGeneratorService) GenerateStrmFiles(taskID uint) error {
 	hasDownloadFiles := len(s.queue.DownloadFiles) > 0
 	s.queue.FilesMutex.RUnlock()
 
-	if hasDownloadFiles {
+	if hasDownloadFiles && ctx.Err() == nil {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			downloadProcessingErr = s.processDownloadFileQueue(taskInfo, strmConfig, taskLogID)
+			downloadProcessingErr = s.processDownloadFileQueue(ctx, taskInfo, strmConfig, taskLogID)
 		}()
 	} else {
 		// 无需下载，直接标记下载处理完成
@@ -259,14 +391,33 @@ func (s *StrmGeneratorService) GenerateStrmFiles(taskID uint) error {
 	// 等待所有处理都完成
 	wg.Wait()
 
+	// 刮削数据下载完成后再生成 NFO，源端已有的 NFO 优先
+	if strmProcessingErr == nil && downloadProcessingErr == nil {
+		s.writeNFOs(ctx, taskInfo, taskLogID)
+	}
+
+	// 镜像模式：扫描与处理全部成功后再清理孤立文件，避免因处理失败或取消误删
+	if taskInfo.MirrorMode && strmProcessingErr == nil && downloadProcessingErr == nil && ctx.Err() == nil {
+		mirrorResult, mirrorErr := s.cleanupOrphans(taskInfo, root.TargetPath, totalFiles)
+		if mirrorErr != nil {
+			s.logger.Error("镜像清理失败", zap.String("taskName", taskInfo.Name), zap.Error(mirrorErr))
+		}
+		s.stats.Mutex.Lock()
+		s.stats.OrphanFile = mirrorResult.OrphanFiles
+		s.stats.DeletedFile = mirrorResult.DeletedFiles
+		s.stats.FailedCount += mirrorResult.FailedFiles
+		s.stats.Mutex.Unlock()
+	}
+
 	// 更新任务日志
 	s.stats.Mutex.RLock()
 	// 计算统计数据
 	generatedFiles := s.stats.GeneratedFile
-	// 所有跳过的文件总和：STRM文件跳过 + 元数据文件跳过 + 字幕文件跳过 + 其他文件跳过
-	skippedFiles := s.stats.SkipFile + s.stats.MetadataSkipped + s.stats.SubtitleSkipped + s.stats.OtherSkipped
+	// 所有跳过的文件总和：STRM文件跳过 + 元数据文件跳过 + 字幕文件跳过 + 其他文件跳过 + 规则过滤 + 原盘分段
+	skippedFiles := s.stats.SkipFile + s.stats.MetadataSkipped + s.stats.SubtitleSkipped + s.stats.OtherSkipped +
+		s.stats.ExcludedFile + s.stats.NotIncludedFile + s.stats.DiscFragment
 	// 元数据处理总数：下载 + 跳过
-	metadataFiles := s.stats.MetadataDownloaded + s.stats.MetadataSkipped
+	metadataFiles := s.stats.MetadataDownloaded + s.stats.MetadataSkipped + s.stats.NfoGenerated
 	// 字幕处理总数：下载 + 跳过
 	subtitleFiles := s.stats.SubtitleDownloaded + s.stats.SubtitleSkipped
 	s.stats.Mutex.RUnlock()
@@ -275,8 +426,13 @@ func (s *StrmGeneratorService) GenerateStrmFiles(taskID uint) error {
 	status := tasklog.TaskLogStatusCompleted
 	message := "STRM 文件生成完成"
 
-	// 如果任一处理出错，标记任务失败
-	if strmProcessingErr != nil {
+	// 任务被取消时保留已完成部分的统计；否则任一处理出错，标记任务失败
+	if ctx.Err() != nil {
+		status = tasklog.TaskLogStatusCancelled
+		message = "任务已取消"
+		err = ctx.Err()
+		s.logger.Info("任务已取消", zap.Uint("taskId", taskID), zap.String("taskName", taskInfo.Name))
+	} else if strmProcessingErr != nil {
 		status = tasklog.TaskLogStatusFailed
 		message = "STRM 文件生成失败: " + strmProcessingErr.Error()
 		err = strmProcessingErr
@@ -284,6 +440,9 @@ func (s *StrmGeneratorService) GenerateStrmFiles(taskID uint) error {
 		status = tasklog.TaskLogStatusFailed
 		message = "下载文件处理失败: " + downloadProcessingErr.Error()
 		err = downloadProcessingErr
+	} else if len(scanFailures) > 0 {
+		status = tasklog.TaskLogStatusPartial
+		message = fmt.Sprintf("STRM 文件生成部分完成，%d 个目录扫描失败", len(scanFailures))
 	}
 
 	// 获取当前任务日志记录以获取开始时间
@@ -310,8 +469,105 @@ func (s *StrmGeneratorService) GenerateStrmFiles(taskID uint) error {
 	subtitleSkipped := s.stats.SubtitleSkipped
 	otherSkipped := s.stats.OtherSkipped
 	failedCount := s.stats.FailedCount
+	orphanFiles := s.stats.OrphanFile
+	deletedFiles := s.stats.DeletedFile
+	unchangedFiles := s.stats.UnchangedFile
+	skippedDirs := s.stats.SkippedDir
+	resumedDirs := s.stats.ResumedDir
+	excludedDirs := s.stats.ExcludedDir
+	excludedFiles := s.stats.ExcludedFile
+	notIncludedFiles := s.stats.NotIncludedFile
+	refreshedDirs := s.stats.RefreshedDir
+	subtitleUnmatched := s.stats.SubtitleUnmatched
+	unmatchedSubtitles := s.stats.UnmatchedSubtitles
+	discTitles := s.stats.DiscTitle
+	discFragments := s.stats.DiscFragment
+	stackedFiles := s.stats.StackedFile
+	renamedFiles := s.stats.RenamedFile
+	renameConflicts := s.stats.RenameConflict
+	nfoGenerated := s.stats.NfoGenerated
 	s.stats.Mutex.RUnlock()
 
+	// 执行结束后断点不再需要，失败的执行保留断点以便继续执行
+	// 指定子路径的执行只清理本次的断点，保留之前完整执行中断时留下的断点
+	if status == tasklog.TaskLogStatusCompleted || status == tasklog.TaskLogStatusPartial {
+		var cleanErr error
+		if opts.SubPath != "" {
+			cleanErr = repository.TaskLogCheckpoint.DeleteByTaskLogID(taskLogID)
+		} else {
+			cleanErr = repository.TaskLogCheckpoint.DeleteByTaskID(taskID)
+		}
+		if cleanErr != nil {
+			s.logger.Error("清理任务断点失败", zap.Error(cleanErr))
+		}
+	}
+	if opts.ResumeFromLogID > 0 {
+		s.logger.Info("断点续传统计",
+			zap.String("taskName", taskInfo.Name),
+			zap.Int("跳过已完成目录数", resumedDirs))
+	}
+
+	// 只有文件全部处理成功的运行才更新目录快照，避免失败的文件在下次运行时被当作未变化跳过
+	// 扫描失败的目录及其上级目录已从本次快照中剔除，部分完成时同样可以保存
+	if (status == tasklog.TaskLogStatusCompleted || status == tasklog.TaskLogStatusPartial) && failedCount == 0 {
+		if flushErr := s.snapshots.flush(); flushErr != nil {
+			s.logger.Error("保存目录快照失败", zap.Error(flushErr))
+		}
+	}
+	if taskInfo.SkipUnchanged {
+		s.logger.Info("增量扫描统计",
+			zap.String("taskName", taskInfo.Name),
+			zap.Int("未变化文件数", unchangedFiles),
+			zap.Int("跳过目录数", skippedDirs))
+	}
+
+	if s.filter != nil {
+		s.logger.Info("过滤规则统计",
+			zap.String("taskName", taskInfo.Name),
+			zap.Int("排除目录数", excludedDirs),
+			zap.Int("排除文件数", excludedFiles),
+			zap.Int("未包含文件数", notIncludedFiles))
+	}
+
+	if s.refresh != nil {
+		s.logger.Info("目录刷新统计",
+			zap.String("taskName", taskInfo.Name),
+			zap.String("刷新模式", s.refresh.mode),
+			zap.Int("刷新目录数", refreshedDirs))
+	}
+
+	if discTitles > 0 {
+		s.logger.Info("原盘目录统计",
+			zap.String("taskName", taskInfo.Name),
+			zap.Int("原盘数", discTitles),
+			zap.Int("跳过分段文件数", discFragments))
+	}
+
+	if stackedFiles > 0 {
+		s.logger.Info("多分段统计",
+			zap.String("taskName", taskInfo.Name),
+			zap.String("处理方式", taskInfo.StackMode),
+			zap.Int("合并分段数", stackedFiles))
+	}
+
+	if s.renamer != nil {
+		s.logger.Info("媒体改名统计",
+			zap.String("taskName", taskInfo.Name),
+			zap.String("媒体类型", taskInfo.MediaType),
+			zap.Int("改名文件数", renamedFiles),
+			zap.Int("冲突保留原名数", renameConflicts))
+	}
+
+	if s.nfos != nil {
+		s.logger.Info("NFO 生成统计",
+			zap.String("taskName", taskInfo.Name),
+			zap.Int("生成 NFO 数", nfoGenerated))
+	}
+
+	if taskInfo.MirrorMode && taskInfo.MirrorDryRun && orphanFiles > 0 {
+		message = fmt.Sprintf("%s（镜像演练：发现 %d 个孤立文件，未删除）", message, orphanFiles)
+	}
+
 	// 只包含 TaskLog 模型中存在的字段
 	updateData := map[string]interface{}{
 		"status":              status,
@@ -326,6 +582,15 @@ func (s *StrmGeneratorService) GenerateStrmFiles(taskID uint) error {
 		"metadata_downloaded": metadataDownloaded,
 		"subtitle_downloaded": subtitleDownloaded,
 		"failed_count":        failedCount,
+		"orphan_file":         orphanFiles,
+		"deleted_file":        deletedFiles,
+		"failed_dir_count":    len(scanFailures),
+		"excluded_dir":        excludedDirs,
+		"excluded_file":       excludedFiles,
+		"not_included_file":   notIncludedFiles,
+		"subtitle_unmatched":  subtitleUnmatched,
+		"disc_fragment_file":  discFragments,
+		"nfo_generated":       nfoGenerated,
 	}
 
 	// 额外的统计信息保留在通知中，但不更新到数据库
@@ -345,6 +610,21 @@ func (s *StrmGeneratorService) GenerateStrmFiles(taskID uint) error {
 		"subtitle_skipped":    subtitleSkipped,
 		"other_skipped":       otherSkipped,
 		"failed_count":        failedCount,
+		"orphan_file":         orphanFiles,
+		"deleted_file":        deletedFiles,
+		"mirror_dry_run":      taskInfo.MirrorMode && taskInfo.MirrorDryRun,
+		"unchanged_file":      unchangedFiles,
+		"skipped_dir":         skippedDirs,
+		"failed_dir_count":    len(scanFailures),
+		"failed_dirs":         failedDirPaths(scanFailures),
+		"sub_path":            opts.SubPath,
+		"excluded_dir":        excludedDirs,
+		"excluded_file":       excludedFiles,
+		"not_included_file":   notIncludedFiles,
+		"subtitle_unmatched":  subtitleUnmatched,
+		"unmatched_subtitles": unmatchedSubtitles,
+		"disc_fragment_file":  discFragments,
+		"nfo_generated":       nfoGenerated,
 	}
 
 	if updateErr := repository.TaskLog.UpdatePartial(taskLogID, updateData); updateErr != nil {
@@ -358,7 +638,7 @@ func (s *StrmGeneratorService) GenerateStrmFiles(taskID uint) error {
 	}
 
 	// 如果任务成功完成，则刷新 Emby 媒体库
-	if status == tasklog.TaskLogStatusCompleted && (generatedFiles > 0 || metadataDownloaded > 0 || subtitleDownloaded > 0) {
+	if (status == tasklog.TaskLogStatusCompleted || status == tasklog.TaskLogStatusPartial) && (generatedFiles > 0 || metadataDownloaded > 0 || subtitleDownloaded > 0 || deletedFiles > 0 || nfoGenerated > 0) {
 		s.logger.Info("开始刷新 Emby 媒体库", zap.String("taskName", taskInfo.Name))
 		if refreshErr := Emby.RefreshAllLibraries(); refreshErr != nil {
 			s.logger.Error("刷新 Emby 媒体库失败", zap.Error(refreshErr))
@@ -371,16 +651,14 @@ func (s *StrmGeneratorService) GenerateStrmFiles(taskID uint) error {
 	return err
 }
 
-// loadStrmConfig 加载 STRM 配置
-func (s *StrmGeneratorService) loadStrmConfig() (*StrmConfig, error) {
+// loadStrmConfig 加载全局 STRM 配置
+func loadStrmConfig() (*StrmConfig, error) {
 	config, err := repository.Config.GetByCode("STRM")
 	if err != nil || config == nil {
 		var errorMessage string
 		if err != nil {
-			s.logger.Error("获取 STRM 配置失败", zap.Error(err))
 			errorMessage = fmt.Sprintf("获取 STRM 配置失败: %v", err)
 		} else {
-			s.logger.Error("STRM 配置未找到")
 			errorMessage = "STRM 配置未找到"
 		}
 		return nil, fmt.Errorf("获取 STRM 配置失败: %s", errorMessage)
@@ -401,36 +679,117 @@ type FileEntry struct {
 	SourcePath     string
 	TargetPath     string
 	NameWithoutExt string // 不含扩展名的文件名
+	ForceOverwrite bool   // 源文件已变化，无论任务是否允许覆盖都重新生成
+	DiscRoot       string // 原盘主标题所属的原盘目录，普通文件为空
+
+	StackParts []FileEntry // 多分段合并为播放列表时，除第一段外的其他分段
+	Media      *mediaInfo  // 按媒体信息改名后解析出的标题、年份、季和集，未改名时为 nil
 }
 
 // processDirectory 方法已被重构，使用了新的任务队列设计
 
-// scanDirectoryRecursive 递归扫描目录，只收集文件信息，不进行处理
-func (s *StrmGeneratorService) scanDirectoryRecursive(taskInfo *task.Task, strmConfig *StrmConfig,
-	taskLogID uint, sourcePath, targetPath string) error {
+// scanDirectory 扫描单个目录，只收集文件信息，不进行处理，返回需要继续扫描的子目录
+func (s *generatorRun) scanDirectory(ctx context.Context, taskInfo *task.Task, strmConfig *StrmConfig,
+	taskLogID uint, job dirScanJob) ([]dirScanJob, error) {
+	sourcePath, targetPath, dirInfo := job.SourcePath, job.TargetPath, job.DirInfo
+
+	// 获取当前目录的文件列表，按任务的刷新策略决定是否跳过 AList 缓存
+	refresh := s.refresh.shouldRefresh(dirInfo)
+	files, provider, err := s.alist.ListFiles(ctx, sourcePath, ListOptions{
+		Password: s.passwords.lookup(sourcePath),
+		Refresh:  refresh,
+		Provider: job.Provider,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取目录文件列表失败 [%s]: %w", sourcePath, err)
+	}
+	if refresh {
+		s.stats.Mutex.Lock()
+		s.stats.RefreshedDir++
+		s.stats.Mutex.Unlock()
+	}
+
+	// 字幕子目录随当前目录一起列出，其中的字幕与当前目录的媒体文件匹配，不再作为子目录扫描
+	var subtitleDirFiles []subtitleDirFile
+	if taskInfo.DownloadSubtitle {
+		for i := range files {
+			if !files[i].IsDir || !isSubtitleDir(files[i].Name) || s.filter.excludes(files[i].Name, true) {
+				continue
+			}
+			dirFiles, err := s.listSubtitleDir(ctx, filepath.Join(sourcePath, files[i].Name), &files[i], provider, "")
+			if err != nil {
+				return nil, err
+			}
+			subtitleDirFiles = append(subtitleDirFiles, dirFiles...)
+		}
+	}
+
+	// 增量扫描：目录自身的列表与上次快照一致时跳过其中文件的处理，子目录仍然继续扫描，
+	// 大多数存储只在直接子项变化时更新目录的修改时间，深层目录中新增的文件不会反映到上级目录
+	listingUnchanged := s.snapshots.isListingUnchanged(sourcePath, dirInfo, files)
+	if listingUnchanged {
+		s.logger.Debug("目录列表未变化，跳过文件处理", zap.String("sourcePath", sourcePath))
+		s.stats.Mutex.Lock()
+		s.stats.SkippedDir++
+		s.stats.Mutex.Unlock()
+	}
 
-	// 获取当前目录的文件列表
-	files, err := s.alistService.ListFiles(sourcePath)
+	// 原盘目录（BDMV/VIDEO_TS）只为主标题生成一个以目录名命名的 STRM，其余分段文件跳过
+	disc, err := s.detectDiscTitle(ctx, sourcePath, files, provider, refresh, listingUnchanged)
 	if err != nil {
-		return fmt.Errorf("获取目录文件列表失败 [%s]: %w", sourcePath, err)
+		return nil, err
 	}
+	s.snapshots.record(taskInfo.ID, sourcePath, dirInfo, files)
+	filesDone := s.checkpoints.isFilesDone(sourcePath)
 
 	s.logger.Info("扫描目录",
 		zap.String("sourcePath", sourcePath),
 		zap.String("targetPath", targetPath),
 		zap.Int("fileCount", len(files)))
 
-	// 创建目标目录
-	if err := os.MkdirAll(targetPath, 0755); err != nil {
-		return fmt.Errorf("创建目标目录失败 [%s]: %w", targetPath, err)
+	// 创建目标目录，媒体文件改名时目标目录结构与源目录不同，由写入文件时按需创建
+	if s.renamer == nil {
+		if err := os.MkdirAll(targetPath, 0755); err != nil {
+			return nil, fmt.Errorf("创建目标目录失败 [%s]: %w", targetPath, err)
+		}
 	}
+	s.checkpoints.beginDir(sourcePath)
 
 	// 收集各种文件信息
 	var mediaFileEntries []FileEntry
-	var subtitleFileEntries []FileEntry
+	var subtitleCandidates []subtitleCandidate
+	var matchableMediaEntries []FileEntry // 用于匹配字幕的媒体文件，包括本次跳过生成的文件
+	var validMediaEntries []FileEntry     // 大小满足要求的媒体文件，识别多分段后再加入生成队列
 	var metadataFileEntries []FileEntry
 	var directoryFiles []*AListFile
 
+	// addMediaEntry 将大小满足要求的媒体文件加入 STRM 生成队列，断点续传或增量扫描判定无需处理时计入跳过
+	addMediaEntry := func(entry FileEntry) {
+		s.renameMedia(&entry, strmConfig)
+		strmFilePath := buildStrmFilePath(strmConfig, entry.TargetPath)
+		s.mirror.keep(strmFilePath)
+		matchableMediaEntries = append(matchableMediaEntries, entry)
+
+		// 断点续传：该目录的文件在上次执行中已处理完成
+		if filesDone {
+			s.stats.Mutex.Lock()
+			s.stats.SkipFile++
+			s.stats.Mutex.Unlock()
+			return
+		}
+
+		// 增量扫描：目录列表未变化，或文件大小和修改时间均未变化，且 STRM 文件仍在时跳过
+		if (listingUnchanged || s.isEntryUnchanged(entry)) && s.fileExistsLocally(strmFilePath) {
+			s.stats.Mutex.Lock()
+			s.stats.SkipFile++
+			s.stats.UnchangedFile++
+			s.stats.Mutex.Unlock()
+			return
+		}
+		entry.ForceOverwrite = taskInfo.SkipUnchanged
+		mediaFileEntries = append(mediaFileEntries, entry)
+	}
+
 	// 先对文件进行分类
 	var currentDirectoryFileCount int // 当前目录的文件数量（不包含文件夹）
 
@@ -447,8 +806,26 @@ func (s *StrmGeneratorService) scanDirectoryRecursive(taskInfo *task.Task, strmC
 		currentSourcePath := filepath.Join(sourcePath, file.Name)
 		currentTargetPath := filepath.Join(targetPath, file.Name)
 
+		// 作为原盘主标题的 ISO 文件在下面单独处理
+		if disc != nil && disc.MainPath == currentSourcePath {
+			continue
+		}
+
 		// 确定文件类型
 		fileType := s.determineFileType(&file, taskInfo, strmConfig)
+		if fileType == FileTypeExcluded || fileType == FileTypeNotIncluded {
+			s.logger.Debug("文件被过滤规则跳过",
+				zap.String("path", currentSourcePath),
+				zap.String("文件类型", getFileTypeString(fileType)))
+			s.stats.Mutex.Lock()
+			if fileType == FileTypeExcluded {
+				s.stats.ExcludedFile++
+			} else {
+				s.stats.NotIncludedFile++
+			}
+			s.stats.Mutex.Unlock()
+			continue
+		}
 
 		// 获取不含扩展名的文件名
 		nameWithoutExt := strings.TrimSuffix(file.Name, filepath.Ext(file.Name))
@@ -466,7 +843,7 @@ func (s *StrmGeneratorService) scanDirectoryRecursive(taskInfo *task.Task, strmC
 		case FileTypeMedia:
 			// 对于媒体文件，需要检查文件大小是否满足最小要求
 			if s.isMediaFileSizeValid(&file, strmConfig) {
-				mediaFileEntries = append(mediaFileEntries, entry)
+				validMediaEntries = append(validMediaEntries, entry)
 			} else {
 				// 媒体文件大小不满足要求，计入跳过文件
 				s.stats.Mutex.Lock()
@@ -474,7 +851,7 @@ func (s *StrmGeneratorService) scanDirectoryRecursive(taskInfo *task.Task, strmC
 				s.stats.Mutex.Unlock()
 			}
 		case FileTypeSubtitle:
-			subtitleFileEntries = append(subtitleFileEntries, entry)
+			subtitleCandidates = append(subtitleCandidates, subtitleCandidate{FileEntry: entry})
 		case FileTypeMetadata:
 			metadataFileEntries = append(metadataFileEntries, entry)
 		default:
@@ -485,6 +862,72 @@ func (s *StrmGeneratorService) scanDirectoryRecursive(taskInfo *task.Task, strmC
 		}
 	}
 
+	// 多分段文件按任务配置改名或合并为播放列表，合并的分段计入跳过
+	stackedEntries, mergedParts := stackMediaEntries(validMediaEntries, taskInfo.StackMode)
+	if mergedParts > 0 {
+		s.stats.Mutex.Lock()
+		s.stats.SkipFile += mergedParts
+		s.stats.StackedFile += mergedParts
+		s.stats.Mutex.Unlock()
+	}
+	// 改名冲突时按源路径顺序分配，同一目录中先出现的文件不受列表返回顺序影响
+	if s.renamer != nil {
+		slices.SortStableFunc(stackedEntries, func(a, b FileEntry) int { return strings.Compare(a.SourcePath, b.SourcePath) })
+	}
+	for _, entry := range stackedEntries {
+		addMediaEntry(entry)
+	}
+
+	// 原盘主标题与普通媒体文件一样处理，视频流目录中的其他文件计入跳过的原盘分段文件
+	if disc != nil {
+		currentDirectoryFileCount += disc.StreamFiles
+		s.logger.Info("识别到原盘目录",
+			zap.String("sourcePath", sourcePath),
+			zap.String("类型", disc.Kind),
+			zap.String("主标题", disc.MainPath),
+			zap.Int("跳过分段文件数", disc.Fragments))
+
+		s.stats.Mutex.Lock()
+		s.stats.DiscTitle++
+		s.stats.DiscFragment += disc.Fragments
+		s.stats.Mutex.Unlock()
+
+		if s.isMediaFileSizeValid(&disc.Main, strmConfig) {
+			addMediaEntry(disc.entry(sourcePath, targetPath))
+		} else {
+			s.stats.Mutex.Lock()
+			s.stats.SkipFile++
+			s.stats.Mutex.Unlock()
+		}
+	}
+
+	// 字幕子目录中的文件只保留字幕，其他文件计入跳过
+	for i := range subtitleDirFiles {
+		dirFile := &subtitleDirFiles[i]
+		currentDirectoryFileCount++
+		fileType := s.determineFileType(&dirFile.File, taskInfo, strmConfig)
+		if fileType != FileTypeSubtitle {
+			s.stats.Mutex.Lock()
+			if fileType == FileTypeExcluded {
+				s.stats.ExcludedFile++
+			} else {
+				s.stats.OtherSkipped++
+			}
+			s.stats.Mutex.Unlock()
+			continue
+		}
+		subtitleCandidates = append(subtitleCandidates, subtitleCandidate{
+			FileEntry: FileEntry{
+				File:           &dirFile.File,
+				FileType:       fileType,
+				SourcePath:     dirFile.SourcePath,
+				NameWithoutExt: strings.TrimSuffix(dirFile.File.Name, filepath.Ext(dirFile.File.Name)),
+			},
+			InSubDir:  true,
+			MediaHint: dirFile.MediaHint,
+		})
+	}
+
 	// 文件分类完成后，增加总文件计数（只统计文件，不包含文件夹）
 	s.stats.Mutex.Lock()
 	s.stats.TotalFiles += currentDirectoryFileCount
@@ -507,36 +950,16 @@ func (s *StrmGeneratorService) scanDirectoryRecursive(taskInfo *task.Task, strmC
 		zap.Int("累计总文件数", totalFiles),
 		zap.Int("文件夹数", len(directoryFiles)))
 
-	// 筛选需要处理的字幕文件（需要与媒体文件匹配）
-	var matchedSubtitleEntries []FileEntry
-	for _, subEntry := range subtitleFileEntries {
-		matched := false
-
-		// 检查是否与任何媒体文件匹配
-		for _, mediaEntry := range mediaFileEntries {
-			// 字幕文件名需要以媒体文件名为前缀（如movie.mp4与movie.srt）
-			if strings.HasPrefix(subEntry.NameWithoutExt, mediaEntry.NameWithoutExt) {
-				matched = true
-				break
-			}
-		}
-
-		if matched {
-			matchedSubtitleEntries = append(matchedSubtitleEntries, subEntry)
-		} else {
-			s.logger.Info("跳过未匹配的字幕文件",
-				zap.String("fileName", subEntry.File.Name),
-				zap.String("path", subEntry.SourcePath))
-
-			s.stats.Mutex.Lock()
-			s.stats.SubtitleSkipped++ // 将未匹配的字幕文件计入已跳过的字幕文件
-			s.stats.Mutex.Unlock()
-		}
+	// 字幕文件与媒体文件匹配，并改名为对应 STRM 文件的字幕名
+	matchedSubtitleEntries := s.matchSubtitles(subtitleCandidates, matchableMediaEntries, strmConfig)
+	for _, entry := range matchedSubtitleEntries {
+		s.mirror.keep(entry.TargetPath)
 	}
 
 	// 将收集到的文件添加到相应的处理队列
 	// 媒体文件立即添加到STRM生成队列，这样边扫描边处理
 	if len(mediaFileEntries) > 0 {
+		s.checkpoints.addFiles(sourcePath, len(mediaFileEntries))
 		s.queue.FilesMutex.Lock()
 		// 添加媒体文件到 STRM 生成队列
 		s.queue.StrmFiles = append(s.queue.StrmFiles, mediaFileEntries...)
@@ -557,7 +980,7 @@ func (s *StrmGeneratorService) scanDirectoryRecursive(taskInfo *task.Task, strmC
 				zap.String("targetPath", entry.TargetPath))
 
 			// 记录文件历史（已存在的文件）
-			s.recordFileHistory(taskInfo.ID, taskLogID, entry.File, entry.SourcePath, entry.TargetPath, entry.FileType, true)
+			s.recordFileHistory(taskInfo.ID, taskLogID, entry.File, entry.SourcePath, entry.TargetPath, entry.FileType, true, nil)
 
 			// 更新统计信息
 			s.stats.Mutex.Lock()
@@ -566,8 +989,13 @@ func (s *StrmGeneratorService) scanDirectoryRecursive(taskInfo *task.Task, strmC
 		}
 	}
 
-	// 检查元数据文件是否已存在于本地
+	// 源端没有 NFO 的媒体文件在本地生成 NFO，下载完刮削数据后统一写入
+	s.collectNFOs(sourcePath, files, matchableMediaEntries, strmConfig)
+
+	// 检查元数据文件是否已存在于本地，媒体文件改名时随之改名
+	s.renameMetadata(metadataFileEntries, sourcePath, matchableMediaEntries, strmConfig)
 	for _, entry := range metadataFileEntries {
+		s.mirror.keep(entry.TargetPath)
 		if !s.fileExistsLocally(entry.TargetPath) {
 			needDownloadEntries = append(needDownloadEntries, entry)
 		} else {
@@ -576,7 +1004,7 @@ func (s *StrmGeneratorService) scanDirectoryRecursive(taskInfo *task.Task, strmC
 				zap.String("targetPath", entry.TargetPath))
 
 			// 记录文件历史（已存在的文件）
-			s.recordFileHistory(taskInfo.ID, taskLogID, entry.File, entry.SourcePath, entry.TargetPath, entry.FileType, true)
+			s.recordFileHistory(taskInfo.ID, taskLogID, entry.File, entry.SourcePath, entry.TargetPath, entry.FileType, true, nil)
 
 			// 更新统计信息
 			s.stats.Mutex.Lock()
@@ -585,8 +1013,14 @@ func (s *StrmGeneratorService) scanDirectoryRecursive(taskInfo *task.Task, strmC
 		}
 	}
 
+	// 断点续传：该目录的文件在上次执行中已处理完成，无需再次下载
+	if filesDone {
+		needDownloadEntries = nil
+	}
+
 	// 只将需要下载的文件添加到下载队列
 	if len(needDownloadEntries) > 0 {
+		s.checkpoints.addFiles(sourcePath, len(needDownloadEntries))
 		s.queue.FilesMutex.Lock()
 		s.queue.DownloadFiles = append(s.queue.DownloadFiles, needDownloadEntries...)
 		s.queue.FilesMutex.Unlock()
@@ -604,28 +1038,67 @@ func (s *StrmGeneratorService) scanDirectoryRecursive(taskInfo *task.Task, strmC
 		s.stats.Mutex.RUnlock()
 	}
 
-	// 递归处理子目录
+	// 收集子目录，交由扫描工作池继续处理
+	var subDirs []dirScanJob
 	for _, dirFile := range directoryFiles {
 		currentSourcePath := filepath.Join(sourcePath, dirFile.Name)
 		currentTargetPath := filepath.Join(targetPath, dirFile.Name)
 
-		// 递归处理子目录
-		if err := s.scanDirectoryRecursive(taskInfo, strmConfig, taskLogID, currentSourcePath, currentTargetPath); err != nil {
-			return err
+		// 被排除规则排除的目录不再扫描
+		if s.filter.excludes(dirFile.Name, true) {
+			s.logger.Debug("目录被排除规则排除，跳过扫描", zap.String("sourcePath", currentSourcePath))
+			s.stats.Mutex.Lock()
+			s.stats.ExcludedDir++
+			s.stats.Mutex.Unlock()
+			continue
+		}
+
+		// 字幕子目录已随当前目录处理
+		if taskInfo.DownloadSubtitle && isSubtitleDir(dirFile.Name) {
+			continue
+		}
+
+		// 原盘的结构目录已由主标题代替，不再扫描
+		if disc != nil && isDiscStructureDir(dirFile.Name) {
+			continue
+		}
+
+		// 断点续传：整个子树在上次执行中已完成
+		if s.checkpoints.isTreeDone(currentSourcePath) {
+			s.mirror.keepTree(currentSourcePath, currentTargetPath)
+			s.stats.Mutex.Lock()
+			s.stats.ResumedDir++
+			s.stats.Mutex.Unlock()
+			continue
 		}
+
+		subDirs = append(subDirs, dirScanJob{
+			SourcePath: currentSourcePath,
+			TargetPath: currentTargetPath,
+			DirInfo:    dirFile,
+			Provider:   provider,
+		})
 	}
 
-	return nil
+	s.checkpoints.endDirScan(sourcePath, len(subDirs))
+	return subDirs, nil
 }
 
-// determineFileType 确定文件类型
-func (s *StrmGeneratorService) determineFileType(file *AListFile, taskInfo *task.Task, strmConfig *StrmConfig) FileType {
+// determineFileType 确定文件类型，被排除规则排除或不满足包含规则的文件单独返回，便于分别统计
+func (s *generatorRun) determineFileType(file *AListFile, taskInfo *task.Task, strmConfig *StrmConfig) FileType {
+	if s.filter.excludes(file.Name, false) {
+		return FileTypeExcluded
+	}
+
 	ext := strings.ToLower(filepath.Ext(file.Name))
 
 	// 检查是否为媒体文件
 	mediaExtensions := strings.Split(strings.ToLower(strmConfig.DefaultSuffix), ",")
 	for _, mediaExt := range mediaExtensions {
 		if ext == "."+strings.TrimSpace(mediaExt) {
+			if !s.filter.includesMedia(file.Name) {
+				return FileTypeNotIncluded
+			}
 			return FileTypeMedia
 		}
 	}
@@ -677,7 +1150,8 @@ func (s *StrmGeneratorService) isMediaFileSizeValid(file *AListFile, strmConfig
 }
 
 // processFile 处理单个文件
-func (s *StrmGeneratorService) processFile(file *AListFile, fileType FileType, taskInfo *task.Task, strmConfig *StrmConfig, taskLogID uint, sourcePath, targetPath string) *ProcessedFile {
+// stackParts 为合并到同一个 STRM 播放列表中的其他分段，只对媒体文件有效
+func (s *generatorRun) processFile(ctx context.Context, file *AListFile, fileType FileType, taskInfo *task.Task, strmConfig *StrmConfig, taskLogID uint, sourcePath, targetPath string, forceOverwrite bool, stackParts []FileEntry) *ProcessedFile {
 	result := &ProcessedFile{
 		SourceFile: file,
 		TargetPath: targetPath,
@@ -695,22 +1169,32 @@ func (s *StrmGeneratorService) processFile(file *AListFile, fileType FileType, t
 
 	switch fileType {
 	case FileTypeMedia:
+		// 检查是否需要覆盖现有文件
+		if !forceOverwrite && !s.shouldOverwrite(buildStrmFilePath(strmConfig, targetPath), taskInfo) {
+			result.Skipped = true
+			result.ErrorMessage = "文件已存在且不允许覆盖"
+			break
+		}
 		// 生成 STRM 文件 - 仅使用 AListFile 中已有信息
 		var strmFilePath string
-		result.Success, result.ErrorMessage, strmFilePath = s.generateStrmFile(file, strmConfig, taskInfo, sourcePath, targetPath)
+		result.Success, result.ErrorMessage, strmFilePath = s.generateStrmFile(ctx, file, strmConfig, taskInfo, sourcePath, targetPath, stackParts)
 		if result.Success {
 			// 如果成功生成STRM文件，更新目标路径为实际的STRM文件路径
 			result.TargetPath = strmFilePath
 		}
 	case FileTypeMetadata, FileTypeSubtitle:
 		// 下载元数据或字幕文件 - 仅使用 AListFile 中已有信息
-		result.Success, result.ErrorMessage = s.downloadFile(file, sourcePath, targetPath, taskInfo)
+		result.Success, result.ErrorMessage = s.downloadFile(ctx, file, strmConfig, sourcePath, targetPath)
 	default:
 		result.ErrorMessage = "不支持的文件类型，已跳过"
 	}
 
 	// 记录处理结果
-	if !result.Success {
+	if result.Skipped {
+		s.logger.Debug("跳过文件",
+			zap.String("文件名", file.Name),
+			zap.String("原因", result.ErrorMessage))
+	} else if !result.Success {
 		s.logger.Warn("处理文件失败",
 			zap.String("文件名", file.Name),
 			zap.String("文件类型", getFileTypeString(fileType)),
@@ -733,13 +1217,75 @@ func getFileTypeString(fileType FileType) string {
 		return "元数据文件"
 	case FileTypeSubtitle:
 		return "字幕文件"
+	case FileTypeExcluded:
+		return "已排除文件"
+	case FileTypeNotIncluded:
+		return "未包含文件"
 	default:
 		return "其他文件"
 	}
 }
 
 // generateStrmFile 生成 STRM 文件，返回成功状态、错误消息和STRM文件路径
-func (s *StrmGeneratorService) generateStrmFile(file *AListFile, strmConfig *StrmConfig, taskConfig *task.Task, sourcePath, targetPath string) (bool, string, string) {
+// stackParts 不为空时生成播放列表，按分段顺序每行写入一个链接
+func (s *generatorRun) generateStrmFile(ctx context.Context, file *AListFile, strmConfig *StrmConfig, taskConfig *task.Task, sourcePath, targetPath string, stackParts []FileEntry) (bool, string, string) {
+	// 构建完整的 STRM 文件路径
+	strmFilePath := buildStrmFilePath(strmConfig, targetPath)
+
+	content, err := s.fetchStrmContent(ctx, file, strmConfig, taskConfig, sourcePath)
+	if err != nil {
+		return false, err.Error(), ""
+	}
+	for i := range stackParts {
+		partContent, err := s.fetchStrmContent(ctx, stackParts[i].File, strmConfig, taskConfig, stackParts[i].SourcePath)
+		if err != nil {
+			return false, fmt.Sprintf("分段 %s: %v", stackParts[i].File.Name, err), ""
+		}
+		content += "\n" + partContent
+	}
+
+	// 确保目标目录存在
+	if err := os.MkdirAll(filepath.Dir(strmFilePath), 0755); err != nil {
+		return false, fmt.Sprintf("创建目标目录失败: %v", err), strmFilePath
+	}
+
+	// 写入 STRM 文件
+	if err := os.WriteFile(strmFilePath, []byte(content), 0644); err != nil {
+		return false, fmt.Sprintf("写入 STRM 文件失败: %v", err), strmFilePath
+	}
+
+	s.logger.Info("生成 STRM 文件成功",
+		zap.String("sourceFile", file.Name),
+		zap.String("strmFile", strmFilePath),
+		zap.String("content", content))
+
+	return true, "", strmFilePath
+}
+
+// fetchStrmContent 按任务的链接来源构建媒体文件的 STRM 内容
+// 链接来源为 get/raw 时逐个调用 /api/fs/get，获取到的签名写回 file，随文件历史一起记录；
+// list 模式下受密码保护目录中列表未返回签名的文件同样通过 fs/get 获取签名
+func (s *generatorRun) fetchStrmContent(ctx context.Context, file *AListFile, strmConfig *StrmConfig, taskConfig *task.Task, sourcePath string) (string, error) {
+	var rawURL string
+	if taskConfig.URLMode == task.URLModeGet || taskConfig.URLMode == task.URLModeRaw {
+		info, err := s.alist.GetFile(ctx, sourcePath, s.passwords.lookup(path.Dir(sourcePath)))
+		if err != nil {
+			return "", fmt.Errorf("获取文件信息失败: %w", err)
+		}
+		file.Sign = info.Sign
+		rawURL = info.RawURL
+	} else if _, err := s.fileSign(ctx, file, sourcePath); err != nil {
+		return "", err
+	}
+	return s.buildStrmContent(file, rawURL, strmConfig, taskConfig, sourcePath)
+}
+
+// buildStrmContent 构建媒体文件的 STRM 内容，rawURL 为 /api/fs/get 返回的直链，只在 raw 模式下使用
+func (s *generatorRun) buildStrmContent(file *AListFile, rawURL string, strmConfig *StrmConfig, taskConfig *task.Task, sourcePath string) (string, error) {
+	if taskConfig.URLMode == task.URLModeRaw && rawURL == "" {
+		return "", errors.New("AList 未返回文件直链，请检查存储是否支持直链")
+	}
+
 	// 处理路径和文件名的 URL 编码
 	dirPath := filepath.Dir(sourcePath)
 	fileName := file.Name
@@ -764,64 +1310,54 @@ func (s *StrmGeneratorService) generateStrmFile(file *AListFile, strmConfig *Str
 			zap.String("编码后文件名", fileName))
 	}
 
-	// 构建 STRM 文件内容 - 直接使用 AListFile 中的信息，避免多余的 API 调用
+	// 构建 STRM 文件内容 - list 模式直接使用 AListFile 中的信息，避免多余的 API 调用
 	// 注意：GetFileURL 方法不会发起额外的 API 请求，仅使用配置和参数构建 URL
-	fileURL := s.alistService.GetFileURL(dirPath, fileName, file.Sign)
-	if fileURL == "" {
-		return false, "无法生成文件URL，请检查 AList 配置是否完整", ""
+	fileURL := s.alist.GetFileURL(dirPath, fileName, file.Sign)
+	if taskConfig.URLMode == task.URLModeRaw {
+		fileURL = rawURL
 	}
 
-	// 生成 STRM 文件名
+	// 配置了 STRM 内容模板时使用模板渲染结果，否则使用默认直链
+	content := fileURL
+	if s.strmTemplate != nil {
+		data := newStrmTemplateData(file, sourcePath, s.alist.GetBaseURL(), fileURL)
+		rendered, err := renderStrmTemplate(s.strmTemplate, data)
+		if err != nil {
+			return "", fmt.Errorf("渲染 STRM 内容模板失败: %w", err)
+		}
+		content = rendered
+	} else if fileURL == "" {
+		return "", errors.New("无法生成文件URL，请检查 AList 配置是否完整")
+	}
+
+	return content, nil
+}
+
+// buildStrmFilePath 根据 STRM 配置计算媒体文件对应的 STRM 文件路径
+// STRM 文件名由目标路径的文件名决定，通常与源文件同名，原盘主标题使用原盘目录名
+func buildStrmFilePath(strmConfig *StrmConfig, targetPath string) string {
+	name := filepath.Base(targetPath)
 	var strmFileName string
 	if strmConfig.ReplaceSuffix {
 		// 替换后缀为 .strm
-		nameWithoutExt := strings.TrimSuffix(file.Name, filepath.Ext(file.Name))
+		nameWithoutExt := strings.TrimSuffix(name, filepath.Ext(name))
 		strmFileName = nameWithoutExt + ".strm"
 	} else {
 		// 在原文件名后添加 .strm
-		strmFileName = file.Name + ".strm"
-	}
-
-	// 构建完整的 STRM 文件路径
-	strmFilePath := filepath.Join(filepath.Dir(targetPath), strmFileName)
-
-	// 检查是否需要覆盖现有文件
-	if !s.shouldOverwrite(strmFilePath, taskConfig) {
-		return false, "文件已存在且不允许覆盖", strmFilePath
-	}
-
-	// 确保目标目录存在
-	if err := os.MkdirAll(filepath.Dir(strmFilePath), 0755); err != nil {
-		return false, fmt.Sprintf("创建目标目录失败: %v", err), strmFilePath
+		strmFileName = name + ".strm"
 	}
-
-	// 写入 STRM 文件
-	if err := os.WriteFile(strmFilePath, []byte(fileURL), 0644); err != nil {
-		return false, fmt.Sprintf("写入 STRM 文件失败: %v", err), strmFilePath
-	}
-
-	s.logger.Info("生成 STRM 文件成功",
-		zap.String("sourceFile", file.Name),
-		zap.String("strmFile", strmFilePath),
-		zap.String("url", fileURL))
-
-	return true, "", strmFilePath
+	return filepath.Join(filepath.Dir(targetPath), strmFileName)
 }
 
 // downloadFile 下载文件（元数据和字幕）
-func (s *StrmGeneratorService) downloadFile(file *AListFile, sourcePath, targetPath string, taskConfig *task.Task) (bool, string) {
+// strmConfig 为本次执行的有效配置，用于判断是否需要 URL 编码
+func (s *generatorRun) downloadFile(ctx context.Context, file *AListFile, strmConfig *StrmConfig, sourcePath, targetPath string) (bool, string) {
 
 	// 确保目标目录存在
 	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
 		return false, fmt.Sprintf("创建目标目录失败: %v", err)
 	}
 
-	// 获取 STRM 配置以检查是否需要 URL 编码
-	strmConfig, err := s.loadStrmConfig()
-	if err != nil {
-		return false, fmt.Sprintf("加载 STRM 配置失败: %v", err)
-	}
-
 	// 处理路径和文件名
 	dirPath := filepath.Dir(sourcePath)
 	fileName := file.Name
@@ -841,13 +1377,17 @@ func (s *StrmGeneratorService) downloadFile(file *AListFile, sourcePath, targetP
 
 	// 直接使用 AListFile 中的信息构建文件 URL，不需要额外的 API 调用
 	// 注意：GetFileURL 方法不会发起额外的 API 请求，仅使用配置和参数构建 URL
-	fileURL := s.alistService.GetFileURL(dirPath, fileName, file.Sign)
+	sign, err := s.fileSign(ctx, file, sourcePath)
+	if err != nil {
+		return false, err.Error()
+	}
+	fileURL := s.alist.GetFileURL(dirPath, fileName, sign)
 	if fileURL == "" {
 		return false, "无法生成文件下载URL，请检查 AList 配置是否完整"
 	}
 
 	// 实现 HTTP 下载逻辑
-	if err := s.downloadFileFromURL(fileURL, targetPath); err != nil {
+	if err := s.downloadFileFromURL(ctx, fileURL, targetPath); err != nil {
 		return false, fmt.Sprintf("下载文件失败: %v", err)
 	}
 
@@ -873,15 +1413,20 @@ func humanizeSize(bytes int64) string {
 	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
-// downloadFileFromURL 从 URL 下载文件
-func (s *StrmGeneratorService) downloadFileFromURL(fileURL, targetPath string) error {
+// downloadFileFromURL 从 URL 下载文件，ctx 取消时中止下载
+func (s *StrmGeneratorService) downloadFileFromURL(ctx context.Context, fileURL, targetPath string) error {
 	// 创建 HTTP 客户端
 	client := &http.Client{
 		Timeout: 60 * time.Second,
 	}
 
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return fmt.Errorf("创建下载请求失败: %w", err)
+	}
+
 	// 发送 GET 请求
-	resp, err := client.Get(fileURL)
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("下载文件失败: %w", err)
 	}
@@ -925,13 +1470,25 @@ func (s *StrmGeneratorService) shouldOverwrite(filePath string, taskConfig *task
 }
 
 // recordFileHistory 记录文件历史
-func (s *StrmGeneratorService) recordFileHistory(taskID, taskLogID uint, file *AListFile, sourcePath, targetPath string, fileType FileType, success bool) {
+// mediaEntry 为生成 STRM 的媒体文件条目，用于记录合并的多分段和改名信息，其他文件为 nil
+func (s *StrmGeneratorService) recordFileHistory(taskID, taskLogID uint, file *AListFile, sourcePath, targetPath string, fileType FileType, success bool, mediaEntry *FileEntry) {
 	if !success {
 		return // 只记录成功处理的文件
 	}
 
 	fileTypeStr := s.getFileTypeString(fileType)
 
+	// 多分段来源和改名后解析出的媒体信息
+	var stackSources, stackSigns []string
+	var media mediaInfo
+	if mediaEntry != nil {
+		stackSources, stackSigns = mediaEntry.stackSources(), mediaEntry.stackSigns()
+		if mediaEntry.Media != nil {
+			media = *mediaEntry.Media
+		}
+	}
+	renamed := media.Title != ""
+
 	// 获取文件Hash
 	hash := ""
 	if file.HashInfo.Sha1 != "" {
@@ -987,11 +1544,27 @@ func (s *StrmGeneratorService) recordFileHistory(taskID, taskLogID uint, file *A
 	if existingRecord != nil {
 		now := time.Now()
 		updateData := map[string]interface{}{
-			"task_id":     taskID,
-			"task_log_id": taskLogID,
-			"updated_at":  now,
-			"file_size":   file.Size,
-			"modified_at": &file.Modified,
+			"task_id":          taskID,
+			"task_log_id":      taskLogID,
+			"updated_at":       now,
+			"file_size":        file.Size,
+			"modified_at":      &file.Modified,
+			"target_file_path": targetPath,
+		}
+		if fileType == FileTypeMedia {
+			// 记录 STRM 中写入的签名，供重新签名时判断是否过期
+			updateData["sign"] = file.Sign
+			updateData["signed_at"] = &now
+			// 按列名更新时 GORM 不会调用字段的 JSON 序列化器，需要自行编码
+			stackSourcesJSON, _ := json.Marshal(stackSources)
+			updateData["stack_sources"] = string(stackSourcesJSON)
+			stackSignsJSON, _ := json.Marshal(stackSigns)
+			updateData["stack_signs"] = string(stackSignsJSON)
+			updateData["renamed"] = renamed
+			updateData["media_title"] = media.Title
+			updateData["media_year"] = media.Year
+			updateData["season"] = media.Season
+			updateData["episode"] = media.Episode
 		}
 
 		// 处理 hash 字段更新
@@ -1039,6 +1612,19 @@ func (s *StrmGeneratorService) recordFileHistory(taskID, taskLogID uint, file *A
 		ModifiedAt:     &file.Modified,
 	}
 
+	if fileHistory.IsStrm {
+		now := time.Now()
+		fileHistory.Sign = file.Sign
+		fileHistory.SignedAt = &now
+		fileHistory.StackSources = stackSources
+		fileHistory.StackSigns = stackSigns
+		fileHistory.Renamed = renamed
+		fileHistory.MediaTitle = media.Title
+		fileHistory.MediaYear = media.Year
+		fileHistory.Season = media.Season
+		fileHistory.Episode = media.Episode
+	}
+
 	// 只有当 hash 不为空时才设置 Hash 字段，否则保持为 nil (数据库中的 NULL)
 	if hash != "" {
 		fileHistory.Hash = &hash
@@ -1118,8 +1704,8 @@ func (s *StrmGeneratorService) updateTaskLogWithError(taskLogID uint, errorMessa
 	}
 }
 
-// processDownloadFileQueue 处理下载文件队列（串行处理，带延迟）
-func (s *StrmGeneratorService) processDownloadFileQueue(taskInfo *task.Task, strmConfig *StrmConfig, taskLogID uint) error {
+// processDownloadFileQueue 处理下载文件队列（串行处理，带延迟），ctx 取消后不再处理剩余文件
+func (s *generatorRun) processDownloadFileQueue(ctx context.Context, taskInfo *task.Task, strmConfig *StrmConfig, taskLogID uint) error {
 	s.queue.FilesMutex.RLock()
 	totalDownloadFiles := len(s.queue.DownloadFiles)
 	s.queue.FilesMutex.RUnlock()
@@ -1148,14 +1734,19 @@ func (s *StrmGeneratorService) processDownloadFileQueue(taskInfo *task.Task, str
 			// 设置随机延迟，更好地模拟人工操作
 			randomDelay := time.Duration(1000+(time.Now().UnixNano()%2000)) * time.Millisecond
 			s.logger.Info("等待随机延迟", zap.Duration("delay", randomDelay))
-			time.Sleep(randomDelay)
+			if sleepContext(ctx, randomDelay) != nil {
+				break
+			}
+		} else if ctx.Err() != nil {
+			break
 		}
 
 		// 处理文件
-		processed := s.processFile(entry.File, entry.FileType, taskInfo, strmConfig, taskLogID, entry.SourcePath, entry.TargetPath)
+		processed := s.processFile(ctx, entry.File, entry.FileType, taskInfo, strmConfig, taskLogID, entry.SourcePath, entry.TargetPath, false, nil)
 
 		// 记录文件历史
-		s.recordFileHistory(taskInfo.ID, taskLogID, entry.File, entry.SourcePath, processed.TargetPath, entry.FileType, processed.Success)
+		s.recordFileHistory(taskInfo.ID, taskLogID, entry.File, entry.SourcePath, processed.TargetPath, entry.FileType, processed.Success, nil)
+		s.checkpoints.fileDone(entry.SourcePath, processed.Success)
 
 		// 更新统计信息
 		s.stats.Mutex.Lock()
@@ -1182,7 +1773,8 @@ func (s *StrmGeneratorService) processDownloadFileQueue(taskInfo *task.Task, str
 			// 计算数据库中需要的汇总数值
 			subtitleCount := s.stats.SubtitleDownloaded + s.stats.SubtitleSkipped
 			metadataCount := s.stats.MetadataDownloaded + s.stats.MetadataSkipped
-			skipFileCount := s.stats.SkipFile + s.stats.MetadataSkipped + s.stats.SubtitleSkipped + s.stats.OtherSkipped
+			skipFileCount := s.stats.SkipFile + s.stats.MetadataSkipped + s.stats.SubtitleSkipped + s.stats.OtherSkipped + s.stats.ExcludedFile + s.stats.NotIncludedFile +
+				s.stats.DiscFragment
 
 			updateData := map[string]interface{}{
 				"subtitle_count": subtitleCount,
@@ -1224,7 +1816,8 @@ func (s *StrmGeneratorService) processDownloadFileQueue(taskInfo *task.Task, str
 }
 
 // processStrmFileQueueAsync 异步处理STRM文件队列（并发处理），可以在目录扫描时就开始处理
-func (s *StrmGeneratorService) processStrmFileQueueAsync(taskInfo *task.Task, strmConfig *StrmConfig, taskLogID uint, scanDoneChan chan bool) error {
+// ctx 取消后停止分发队列中的文件，已分发但未处理的文件直接丢弃
+func (s *generatorRun) processStrmFileQueueAsync(ctx context.Context, taskInfo *task.Task, strmConfig *StrmConfig, taskLogID uint, scanDoneChan chan bool) error {
 	// 设置并发数
 	const defaultConcurrency = 50
 	// TODO: 未来可考虑从任务配置或全局配置中读取并发参数
@@ -1247,8 +1840,12 @@ func (s *StrmGeneratorService) processStrmFileQueueAsync(taskInfo *task.Task, st
 		go func() {
 			defer wg.Done()
 			for entry := range jobChan {
+				if ctx.Err() != nil {
+					continue
+				}
+
 				// 处理媒体文件，生成STRM文件
-				processed := s.processFile(entry.File, entry.FileType, taskInfo, strmConfig, taskLogID, entry.SourcePath, entry.TargetPath)
+				processed := s.processFile(ctx, entry.File, entry.FileType, taskInfo, strmConfig, taskLogID, entry.SourcePath, entry.TargetPath, entry.ForceOverwrite, entry.StackParts)
 
 				// 发送结果
 				resultChan <- FileProcessResult{
@@ -1279,14 +1876,21 @@ func (s *StrmGeneratorService) processStrmFileQueueAsync(taskInfo *task.Task, st
 				targetPath,
 				result.FileType,
 				result.Success,
+				&result.Entry,
 			)
+			// 已存在且不允许覆盖的 STRM 计入跳过，断点视为已完成；获取链接或写入失败的计入失败，
+			// 断点保留以便续传时重试，失败也会阻止本次目录快照写入，避免下次运行将其当作未变化跳过
+			s.checkpoints.fileDone(result.Entry.checkpointPath(), result.Success || result.Processed.Skipped)
 
 			// 统计结果
 			s.stats.Mutex.Lock()
-			if result.Success {
+			switch {
+			case result.Success:
 				s.stats.GeneratedFile++ // 成功生成的STRM文件
-			} else {
+			case result.Processed.Skipped:
 				s.stats.SkipFile++ // 跳过的STRM文件
+			default:
+				s.stats.FailedCount++ // 生成失败的STRM文件
 			}
 			s.stats.Mutex.Unlock()
 
@@ -1294,7 +1898,8 @@ func (s *StrmGeneratorService) processStrmFileQueueAsync(taskInfo *task.Task, st
 			if s.stats.GeneratedFile%100 == 0 || s.stats.SkipFile%100 == 0 {
 				s.stats.Mutex.RLock()
 				// 计算需要更新到数据库的总计数
-				skipFileCount := s.stats.SkipFile + s.stats.MetadataSkipped + s.stats.SubtitleSkipped + s.stats.OtherSkipped
+				skipFileCount := s.stats.SkipFile + s.stats.MetadataSkipped + s.stats.SubtitleSkipped + s.stats.OtherSkipped + s.stats.ExcludedFile + s.stats.NotIncludedFile +
+					s.stats.DiscFragment
 
 				updateData := map[string]interface{}{
 					"generated_file": s.stats.GeneratedFile,
@@ -1313,8 +1918,8 @@ func (s *StrmGeneratorService) processStrmFileQueueAsync(taskInfo *task.Task, st
 	go func() {
 		scanDone := false
 
-		// 持续监听直到扫描结束且队列为空
-		for !scanDone || s.queue.hasStrmFiles() {
+		// 持续监听直到扫描结束且队列为空，或任务被取消
+		for (!scanDone || s.queue.hasStrmFiles()) && ctx.Err() == nil {
 			// 先检查是否有文件可处理
 			if s.queue.hasStrmFiles() {
 				// 获取并删除队列中的一批文件
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/MccRay-s/alist2strm/model/filehistory"
+	"github.com/MccRay-s/alist2strm/model/task"
+	"github.com/MccRay-s/alist2strm/repository"
+	"go.uber.org/zap"
+)
+
+// mirrorTracker 记录本次扫描到的源文件所对应的目标文件路径，镜像模式据此识别孤立文件
+type mirrorTracker struct {
+	mu       sync.Mutex
+	expected map[string]struct{}
+	keptDirs []string // 整体保留的目标目录（例如扫描失败的目录）
+
+	keptSources []string // 整体保留的目标目录对应的源目录，媒体文件改名后目标文件不在对应的目标目录中，按文件历史保留
+}
+
+// MirrorResult 镜像清理结果
+type MirrorResult struct {
+	OrphanFiles  int // 发现的孤立文件数
+	DeletedFiles int // 实际删除的文件数
+	FailedFiles  int // 删除失败的文件数
+}
+
+// newMirrorTracker 创建镜像跟踪器
+func newMirrorTracker() *mirrorTracker {
+	return &mirrorTracker{
+		expected: make(map[string]struct{}),
+	}
+}
+
+// keep 标记目标文件在本次扫描中仍有对应的源文件
+func (m *mirrorTracker) keep(targetPath string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expected[filepath.Clean(targetPath)] = struct{}{}
+}
+
+// keepTree 标记整个目标目录保留，其下的文件以及文件历史中源文件位于源目录下的目标文件不会被视为孤立文件
+func (m *mirrorTracker) keepTree(sourceDir, targetDir string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keptDirs = append(m.keptDirs, filepath.Clean(targetDir))
+	m.keptSources = append(m.keptSources, filepath.Clean(sourceDir))
+}
+
+// keepHistories 保留源文件位于整体保留的源目录中的文件历史所对应的目标文件
+func (m *mirrorTracker) keepHistories(histories []*filehistory.FileHistory) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, history := range histories {
+		for _, dir := range m.keptSources {
+			if isWithinDir(dir, filepath.Clean(history.SourcePath)) {
+				m.expected[filepath.Clean(history.TargetFilePath)] = struct{}{}
+				break
+			}
+		}
+	}
+}
+
+// isExpected 检查目标文件是否仍有对应的源文件
+func (m *mirrorTracker) isExpected(targetPath string) bool {
+	targetPath = filepath.Clean(targetPath)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.expected[targetPath]; ok {
+		return true
+	}
+	for _, dir := range m.keptDirs {
+		if isWithinDir(dir, targetPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanupOrphans 镜像模式下清理目标目录中源端已不存在的文件
+// 磁盘上只处理 .strm 文件，元数据和字幕只处理文件历史中由本任务生成的记录，避免误删 Emby 写入的刮削数据
+func (s *generatorRun) cleanupOrphans(taskInfo *task.Task, targetRoot string, totalFiles int) (*MirrorResult, error) {
+	result := &MirrorResult{}
+	root := filepath.Clean(targetRoot)
+
+	// 源端一个文件都没扫描到时通常是存储离线或挂载异常，此时清理会清空整个媒体库
+	if totalFiles == 0 {
+		s.logger.Warn("本次扫描未发现任何文件，跳过镜像清理", zap.String("targetPath", root))
+		return result, nil
+	}
+
+	var orphanList []string
+	orphanSet := make(map[string]struct{})
+	addOrphan := func(path string) {
+		if _, exists := orphanSet[path]; exists {
+			return
+		}
+		orphanSet[path] = struct{}{}
+		orphanList = append(orphanList, path)
+	}
+
+	histories, err := repository.FileHistory.ListByTaskID(taskInfo.ID)
+	if err != nil {
+		return result, err
+	}
+	s.mirror.keepHistories(histories)
+
+	// 1. 扫描磁盘上的 STRM 文件
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if strings.EqualFold(filepath.Ext(path), ".strm") && !s.mirror.isExpected(path) {
+			addOrphan(filepath.Clean(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// 2. 对比本任务的文件历史记录
+	var staleIDs []uint
+	for _, history := range histories {
+		path := filepath.Clean(history.TargetFilePath)
+		if !isWithinDir(root, path) || s.mirror.isExpected(path) {
+			continue
+		}
+		staleIDs = append(staleIDs, history.ID)
+		if s.fileExistsLocally(path) {
+			addOrphan(path)
+		}
+	}
+
+	result.OrphanFiles = len(orphanList)
+
+	if taskInfo.MirrorDryRun {
+		for _, path := range orphanList {
+			s.logger.Info("镜像演练：发现孤立文件", zap.String("path", path))
+		}
+		s.logger.Info("镜像演练完成，未删除任何文件",
+			zap.String("taskName", taskInfo.Name),
+			zap.Int("孤立文件数", result.OrphanFiles),
+			zap.Int("过期历史记录数", len(staleIDs)))
+		return result, nil
+	}
+
+	for _, path := range orphanList {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			s.logger.Error("删除孤立文件失败", zap.String("path", path), zap.Error(err))
+			result.FailedFiles++
+			continue
+		}
+		result.DeletedFiles++
+		s.logger.Info("已删除孤立文件", zap.String("path", path))
+		removeEmptyParents(filepath.Dir(path), root)
+	}
+
+	if err := repository.FileHistory.DeleteByIDs(staleIDs); err != nil {
+		s.logger.Error("删除过期文件历史记录失败", zap.Error(err))
+	}
+
+	s.logger.Info("镜像清理完成",
+		zap.String("taskName", taskInfo.Name),
+		zap.Int("孤立文件数", result.OrphanFiles),
+		zap.Int("已删除", result.DeletedFiles),
+		zap.Int("删除失败", result.FailedFiles),
+		zap.Int("过期历史记录数", len(staleIDs)))
+
+	return result, nil
+}
+
+// isWithinDir 检查路径是否位于指定目录之下（不含目录本身）
+func isWithinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// removeEmptyParents 自下而上删除空目录，直到根目录为止（根目录本身保留）
+func removeEmptyParents(dir, root string) {
+	for isWithinDir(root, dir) {
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) > 0 {
+			return
+		}
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
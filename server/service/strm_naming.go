@@ -0,0 +1,442 @@
+package service
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MccRay-s/alist2strm/model/task"
+	"github.com/MccRay-s/alist2strm/repository"
+	"go.uber.org/zap"
+)
+
+// episodePattern 文件名中的集数标记，season 表示第一个分组为季号、第二个分组为集号
+type episodePattern struct {
+	re     *regexp.Regexp
+	season bool
+}
+
+// episodePatterns 按可靠程度排列的集数标记：S01E03、1x03、第03集、E03/EP03、"Show - 03"、[03]、只有集号的文件名
+var episodePatterns = []episodePattern{
+	{regexp.MustCompile(`(?i)(?:^|[^a-z0-9])s(\d{1,2})[\s._-]*e(\d{1,4})`), true},
+	{regexp.MustCompile(`(?i)(?:^|[\s._\[-])(\d{1,2})x(\d{2,3})(?:$|[\s._\]-])`), true},
+	{regexp.MustCompile(`第\s*(\d{1,4})\s*[集话話]`), false},
+	{regexp.MustCompile(`(?i)(?:^|[\s._\[-])ep?(\d{1,4})(?:v\d)?(?:$|[\s._\]-])`), false},
+	{regexp.MustCompile(`\s-\s(\d{1,3})(?:v\d)?(?:$|[\s._\[(-])`), false},
+	{regexp.MustCompile(`\[(\d{1,3})(?:v\d)?\]`), false},
+	{regexp.MustCompile(`^\s*(\d{1,3})(?:v\d)?\s*$`), false},
+}
+
+var (
+	// mediaYearPattern 标题后的年份，分隔符已统一为空格；年份之后的字符单独检查，使相邻的两个年份都能匹配
+	mediaYearPattern = regexp.MustCompile(`(?:^|[\s(\[])((?:19|20)\d{2})`)
+	// mediaQualityPattern 分辨率、来源、编码等发布信息，标题在第一个出现的位置截断
+	mediaQualityPattern = regexp.MustCompile(`(?i)\b(?:2160p|1080p|1080i|720p|576p|480p|4k|uhd|blu-?ray|bdrip|brrip|bdremux|remux|web-?dl|webrip|hdtv|hdrip|dvdrip|x ?26[45]|h ?26[45]|hevc|avc|hdr10|hdr|10bit|aac|ac3|dts|atmos|truehd|proper|repack)\b`)
+	// mediaBracketPattern 字幕组、发布组等方括号内容
+	mediaBracketPattern = regexp.MustCompile(`\[[^\]]*\]|【[^】]*】`)
+	// seasonDirPattern 季目录：Season 1、S01、第一季、Specials
+	seasonDirPattern   = regexp.MustCompile(`(?i)^(?:season|s)[\s._-]*(\d{1,3})$`)
+	seasonDirCNPattern = regexp.MustCompile(`^第\s*([0-9一二三四五六七八九十]+)\s*季$`)
+	// showSeasonPattern 剧集目录名中的季标记，例如 Show.S02.1080p、Show 第二季
+	showSeasonPattern = regexp.MustCompile(`(?i)(?:^|[\s._-])(?:s|season[\s._-]*)(\d{1,2})(?:$|[\s._-])|第\s*([0-9一二三四五六七八九十]+)\s*季`)
+)
+
+// mediaInfo 从文件名和目录名解析出的媒体信息
+type mediaInfo struct {
+	Title   string
+	Year    int // 未识别到年份时为 0
+	Season  int // 剧集的季号，电影为 0
+	Episode int // 剧集的集号，电影为 0
+}
+
+// parseMediaInfo 按媒体类型解析媒体信息，name 为不含扩展名的文件名，dirs 为相对任务源路径的上级目录（由外到内）
+// 剧集需要识别到集数，电影需要识别到标题，否则返回 false
+func parseMediaInfo(mediaType, name string, dirs []string) (mediaInfo, bool) {
+	if mediaType == task.MediaTypeTV {
+		return parseEpisodeInfo(name, dirs)
+	}
+	return parseMovieInfo(name, dirs)
+}
+
+// parseMovieInfo 解析电影的标题和年份，文件名中没有年份时使用所在目录（例如 "Movie (2019)"）的标题和年份
+func parseMovieInfo(name string, dirs []string) (mediaInfo, bool) {
+	title, year := parseTitleYear(name)
+	if year == 0 && len(dirs) > 0 {
+		if dirTitle, dirYear := parseTitleYear(dirs[len(dirs)-1]); dirTitle != "" && dirYear > 0 {
+			title, year = dirTitle, dirYear
+		}
+	}
+	if title == "" {
+		return mediaInfo{}, false
+	}
+	return mediaInfo{Title: title, Year: year}, true
+}
+
+// parseEpisodeInfo 解析剧集的剧名、季和集
+// 剧名优先取剧集目录（跳过季目录），文件直接位于任务源路径下时取文件名中集数标记之前的部分；
+// 季号依次取文件名、季目录、剧集目录名中的季标记，都没有时为第 1 季
+func parseEpisodeInfo(name string, dirs []string) (mediaInfo, bool) {
+	prefix, season, episode, ok := parseEpisode(name)
+	if !ok {
+		return mediaInfo{}, false
+	}
+	info := mediaInfo{Season: season, Episode: episode}
+
+	dirSeason := -1
+	if n := len(dirs); n > 0 {
+		if s, ok := parseSeasonDir(dirs[n-1]); ok {
+			dirSeason = s
+			dirs = dirs[:n-1]
+		}
+	}
+	if n := len(dirs); n > 0 {
+		title, year, s := parseShowDir(dirs[n-1])
+		info.Title, info.Year = title, year
+		if dirSeason < 0 {
+			dirSeason = s
+		}
+	}
+	if info.Title == "" {
+		info.Title, info.Year = parseTitleYear(prefix)
+	}
+	if info.Title == "" {
+		return mediaInfo{}, false
+	}
+
+	if info.Season < 0 {
+		info.Season = 1
+		if dirSeason >= 0 {
+			info.Season = dirSeason
+		}
+	}
+	return info, true
+}
+
+// parseEpisode 识别文件名中的集数标记，返回标记之前的部分、季号（未标记时为 -1）和集号
+func parseEpisode(name string) (string, int, int, bool) {
+	for _, p := range episodePatterns {
+		m := p.re.FindStringSubmatchIndex(name)
+		if m == nil {
+			continue
+		}
+		first, _ := strconv.Atoi(name[m[2]:m[3]])
+		if !p.season {
+			return name[:m[0]], -1, first, true
+		}
+		second, _ := strconv.Atoi(name[m[4]:m[5]])
+		return name[:m[0]], first, second, true
+	}
+	return "", -1, 0, false
+}
+
+// parseTitleYear 清理名称中的发布组、分隔符和发布信息，拆分出标题和年份
+// 取最后一个前面仍有标题的年份，避免 "Blade Runner 2049 (2017)" 和 "1917 (2019)" 被截断
+func parseTitleYear(s string) (string, int) {
+	stripped := strings.TrimSpace(mediaBracketPattern.ReplaceAllString(s, " "))
+	if stripped == "" {
+		// 全部由方括号组成时保留括号中的内容
+		stripped = strings.NewReplacer("[", " ", "]", " ", "【", " ", "】", " ").Replace(s)
+	}
+	s = normalizeMediaSeparators(stripped)
+
+	matches := mediaYearPattern.FindAllStringSubmatchIndex(s, -1)
+	for i := len(matches) - 1; i >= 0; i-- {
+		m := matches[i]
+		if m[3] < len(s) && !strings.ContainsRune(" )]", rune(s[m[3]])) {
+			continue
+		}
+		if title := cleanMediaTitle(s[:m[2]]); title != "" {
+			year, _ := strconv.Atoi(s[m[2]:m[3]])
+			return title, year
+		}
+	}
+	return cleanMediaTitle(s), 0
+}
+
+// parseSeasonDir 判断目录是否为季目录并返回季号，Specials/SP/特别篇 视为第 0 季
+func parseSeasonDir(name string) (int, bool) {
+	name = strings.TrimSpace(name)
+	switch strings.ToLower(name) {
+	case "specials", "special", "sp", "特别篇", "特別篇":
+		return 0, true
+	}
+	if m := seasonDirPattern.FindStringSubmatch(name); m != nil {
+		season, _ := strconv.Atoi(m[1])
+		return season, true
+	}
+	if m := seasonDirCNPattern.FindStringSubmatch(name); m != nil {
+		if season, ok := parseChineseNumber(m[1]); ok {
+			return season, true
+		}
+	}
+	return 0, false
+}
+
+// parseShowDir 解析剧集目录名，返回剧名、年份和目录名中的季号（没有时为 -1）
+func parseShowDir(name string) (string, int, int) {
+	season := -1
+	if m := showSeasonPattern.FindStringSubmatchIndex(name); m != nil {
+		if m[2] >= 0 {
+			season, _ = strconv.Atoi(name[m[2]:m[3]])
+		} else if n, ok := parseChineseNumber(name[m[4]:m[5]]); ok {
+			season = n
+		}
+		if head := name[:m[0]]; strings.TrimSpace(head) != "" {
+			name = head
+		}
+	}
+	title, year := parseTitleYear(name)
+	return title, year, season
+}
+
+// parseChineseNumber 解析阿拉伯数字或一到九十九的中文数字
+func parseChineseNumber(s string) (int, bool) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, true
+	}
+	digits := map[rune]int{'一': 1, '二': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9}
+	runes := []rune(s)
+	switch {
+	case len(runes) == 1 && runes[0] == '十':
+		return 10, true
+	case len(runes) == 1:
+		n, ok := digits[runes[0]]
+		return n, ok
+	case len(runes) == 2 && runes[0] == '十':
+		n, ok := digits[runes[1]]
+		return 10 + n, ok
+	case len(runes) == 2 && runes[1] == '十':
+		n, ok := digits[runes[0]]
+		return n * 10, ok
+	case len(runes) == 3 && runes[1] == '十':
+		tens, ok1 := digits[runes[0]]
+		ones, ok2 := digits[runes[2]]
+		return tens*10 + ones, ok1 && ok2
+	}
+	return 0, false
+}
+
+// normalizeMediaSeparators 将点和下划线分隔的发布名称转换为空格分隔
+func normalizeMediaSeparators(s string) string {
+	return strings.Join(strings.Fields(strings.NewReplacer(".", " ", "_", " ").Replace(s)), " ")
+}
+
+// cleanMediaTitle 在发布信息处截断标题并去掉首尾的分隔符和括号
+func cleanMediaTitle(s string) string {
+	if loc := mediaQualityPattern.FindStringIndex(s); loc != nil {
+		s = s[:loc[0]]
+	}
+	return strings.Trim(strings.Join(strings.Fields(s), " "), " -([{【")
+}
+
+// folderName 标题和年份组成的目录名，例如 "Movie (2019)"
+func (m *mediaInfo) folderName() string {
+	name := m.Title
+	if m.Year > 0 {
+		name += fmt.Sprintf(" (%d)", m.Year)
+	}
+	return sanitizeMediaName(name)
+}
+
+// seasonDirName 季目录名，例如 "Season 01"
+func (m *mediaInfo) seasonDirName() string {
+	return fmt.Sprintf("Season %02d", m.Season)
+}
+
+// relativePath 改名后相对任务目标路径的文件路径（含扩展名），suffix 为多分段的 " - cd1" 等后缀
+// 电影：Movie (2019)/Movie (2019).mkv；剧集：Show (2019)/Season 01/Show - S01E03.mkv
+func (m *mediaInfo) relativePath(mediaType, suffix, ext string) string {
+	if mediaType == task.MediaTypeTV {
+		name := fmt.Sprintf("%s - S%02dE%02d", sanitizeMediaName(m.Title), m.Season, m.Episode)
+		return filepath.Join(m.folderName(), m.seasonDirName(), name+suffix+ext)
+	}
+	return filepath.Join(m.folderName(), m.folderName()+suffix+ext)
+}
+
+// sanitizeMediaName 去掉文件名中不允许的字符
+func sanitizeMediaName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>:"/\|?*`, r) || r < 0x20 {
+			return -1
+		}
+		return r
+	}, name)
+	return strings.TrimRight(strings.TrimSpace(name), ".")
+}
+
+// mediaRenamer 单次执行的媒体文件改名器，记录已分配的 STRM 路径，避免不同的源文件改成同一个名字
+// 文件历史中已生成的 STRM 路径保留给原来的源文件，冲突时的归属不受各目录的扫描先后影响
+type mediaRenamer struct {
+	mediaType  string
+	sourceRoot string
+	targetRoot string
+	reserved   map[string]string // 文件历史中的 STRM 路径 -> 源文件路径
+
+	mu       sync.Mutex
+	assigned map[string]string // 改名后的 STRM 路径 -> 源文件路径
+}
+
+// newMediaRenamer 创建媒体文件改名器，任务未开启改名时返回 nil
+func newMediaRenamer(taskInfo *task.Task) (*mediaRenamer, error) {
+	if !taskInfo.RenameMedia {
+		return nil, nil
+	}
+	histories, err := repository.FileHistory.ListStrm(&taskInfo.ID, false)
+	if err != nil {
+		return nil, fmt.Errorf("加载文件历史失败: %w", err)
+	}
+	// 同一 STRM 路径有多条记录时以最近更新的为准
+	reserved := make(map[string]string, len(histories))
+	updated := make(map[string]time.Time, len(histories))
+	for _, history := range histories {
+		if history.TargetFilePath == "" {
+			continue
+		}
+		if at, ok := updated[history.TargetFilePath]; ok && at.After(history.UpdatedAt) {
+			continue
+		}
+		reserved[history.TargetFilePath] = history.SourcePath
+		updated[history.TargetFilePath] = history.UpdatedAt
+	}
+	return &mediaRenamer{
+		mediaType:  taskInfo.MediaType,
+		sourceRoot: filepath.Clean(taskInfo.SourcePath),
+		targetRoot: filepath.Clean(taskInfo.TargetPath),
+		reserved:   reserved,
+		assigned:   make(map[string]string),
+	}, nil
+}
+
+// sourceDirs 返回目录相对任务源路径的各级目录名（由外到内），源路径本身返回空
+func sourceDirs(sourceRoot, dir string) []string {
+	rel, err := filepath.Rel(filepath.Clean(sourceRoot), filepath.Clean(dir))
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return nil
+	}
+	return strings.Split(rel, string(filepath.Separator))
+}
+
+// parseEntryMedia 解析媒体文件条目，多分段文件去掉分段标记后解析，返回需要保留的分段后缀
+// 合并为播放列表的第一段以标题命名，不保留后缀
+func parseEntryMedia(entry *FileEntry, mediaType, sourceRoot string) (mediaInfo, string, bool) {
+	name, suffix := entry.NameWithoutExt, ""
+	if part, ok := parseStackPart(name); ok {
+		name = part.Title
+		if len(entry.StackParts) == 0 {
+			suffix = fmt.Sprintf(" - %s%d", part.Kind, part.Number)
+		}
+	}
+	info, ok := parseMediaInfo(mediaType, name, sourceDirs(sourceRoot, filepath.Dir(entry.checkpointPath())))
+	return info, suffix, ok
+}
+
+// renameMedia 按解析出的媒体信息改写媒体文件的目标路径，无法解析或与已改名的文件冲突时保留原路径
+// 改名后的 STRM 已由文件历史中的其他源文件生成且仍然存在时同样视为冲突，保证多次执行的结果一致
+func (s *generatorRun) renameMedia(entry *FileEntry, strmConfig *StrmConfig) {
+	r := s.renamer
+	if r == nil {
+		return
+	}
+	info, suffix, ok := parseEntryMedia(entry, r.mediaType, r.sourceRoot)
+	if !ok {
+		s.logger.Debug("无法从文件名识别媒体信息，保留原文件名", zap.String("path", entry.SourcePath))
+		return
+	}
+
+	targetPath := filepath.Join(r.targetRoot, info.relativePath(r.mediaType, suffix, filepath.Ext(entry.File.Name)))
+	strmFilePath := buildStrmFilePath(strmConfig, targetPath)
+	r.mu.Lock()
+	other, exists := r.assigned[strmFilePath]
+	if !exists {
+		if owner, ok := r.reserved[strmFilePath]; ok && owner != entry.SourcePath && s.fileExistsLocally(strmFilePath) {
+			other, exists = owner, true
+		}
+	}
+	if !exists {
+		r.assigned[strmFilePath] = entry.SourcePath
+	}
+	r.mu.Unlock()
+	if exists && other != entry.SourcePath {
+		s.logger.Warn("改名后与其他文件冲突，保留原文件名",
+			zap.String("path", entry.SourcePath),
+			zap.String("conflict", other),
+			zap.String("strmFile", strmFilePath))
+		s.stats.Mutex.Lock()
+		s.stats.RenameConflict++
+		s.stats.Mutex.Unlock()
+		return
+	}
+
+	entry.TargetPath = targetPath
+	entry.Media = &info
+	s.stats.Mutex.Lock()
+	s.stats.RenamedFile++
+	s.stats.Mutex.Unlock()
+}
+
+// renameMetadata 媒体文件改名后，同目录的刮削数据随之改名：以媒体文件名开头的文件（Movie.nfo、Movie-poster.jpg）
+// 改为对应 STRM 的文件名；其他文件（poster.jpg、tvshow.nfo 等）放到本目录的媒体文件改名后所在的目录，
+// 剧集目录和季目录中没有媒体文件时放到对应的剧集目录或季目录
+func (s *generatorRun) renameMetadata(entries []FileEntry, sourcePath string, medias []FileEntry, strmConfig *StrmConfig) {
+	r := s.renamer
+	if r == nil || len(entries) == 0 {
+		return
+	}
+
+	dirTarget := ""
+	for i := range medias {
+		dir := filepath.Dir(medias[i].TargetPath)
+		if medias[i].Media == nil || (dirTarget != "" && dir != dirTarget) {
+			dirTarget = ""
+			break
+		}
+		dirTarget = dir
+	}
+	if len(medias) == 0 && r.mediaType == task.MediaTypeTV {
+		dirTarget = r.episodeDirTarget(sourcePath)
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		if media := mediaNamePrefix(entry.NameWithoutExt, medias); media != nil {
+			if media.Media == nil {
+				continue
+			}
+			strmFilePath := buildStrmFilePath(strmConfig, media.TargetPath)
+			name := strings.TrimSuffix(filepath.Base(strmFilePath), ".strm") + entry.File.Name[len(media.NameWithoutExt):]
+			entry.TargetPath = filepath.Join(filepath.Dir(strmFilePath), name)
+			continue
+		}
+		if dirTarget != "" {
+			entry.TargetPath = filepath.Join(dirTarget, entry.File.Name)
+		}
+	}
+}
+
+// episodeDirTarget 剧集目录或季目录改名后的目标目录，无法识别剧名时返回空
+func (r *mediaRenamer) episodeDirTarget(sourcePath string) string {
+	dirs := sourceDirs(r.sourceRoot, sourcePath)
+	season := -1
+	if n := len(dirs); n > 0 {
+		if s, ok := parseSeasonDir(dirs[n-1]); ok {
+			season = s
+			dirs = dirs[:n-1]
+		}
+	}
+	if len(dirs) == 0 {
+		return ""
+	}
+	title, year, _ := parseShowDir(dirs[len(dirs)-1])
+	if title == "" {
+		return ""
+	}
+	info := mediaInfo{Title: title, Year: year, Season: season}
+	if season < 0 {
+		return filepath.Join(r.targetRoot, info.folderName())
+	}
+	return filepath.Join(r.targetRoot, info.folderName(), info.seasonDirName())
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/MccRay-s/alist2strm/model/task"
+)
+
+func TestParseEpisode(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		season  int
+		episode int
+		ok      bool
+	}{
+		{"Show.S01E03.1080p.WEB-DL", "Show", 1, 3, true},
+		{"Show S02 E10", "Show", 2, 10, true},
+		{"Show 1x03", "Show", 1, 3, true},
+		{"Show [1x03]", "Show ", 1, 3, true},
+		{"[Group] Show [03]", "[Group] Show ", -1, 3, true},
+		{"[Group] Show [03v2]", "[Group] Show ", -1, 3, true},
+		{"Show 第12集", "Show ", -1, 12, true},
+		{"Show EP05", "Show", -1, 5, true},
+		{"Show - 07 [1080p]", "Show", -1, 7, true},
+		{"03", "", -1, 3, true},
+		{"Inception", "", -1, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix, season, episode, ok := parseEpisode(tt.name)
+			if prefix != tt.prefix || season != tt.season || episode != tt.episode || ok != tt.ok {
+				t.Errorf("parseEpisode(%q) = (%q, %d, %d, %v), want (%q, %d, %d, %v)",
+					tt.name, prefix, season, episode, ok, tt.prefix, tt.season, tt.episode, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseTitleYear(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		year  int
+	}{
+		{"Blade Runner 2049 (2017)", "Blade Runner 2049", 2017},
+		{"1917 (2019)", "1917", 2019},
+		{"2012.2009.1080p.BluRay", "2012", 2009},
+		{"The.Matrix.1999.1080p.BluRay.x264", "The Matrix", 1999},
+		{"[Group] Movie Name [1080p]", "Movie Name", 0},
+		{"[Movie Name]", "Movie Name", 0},
+		{"Inception", "Inception", 0},
+		{"Movie_Name_2020_WEB-DL", "Movie Name", 2020},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, year := parseTitleYear(tt.name)
+			if title != tt.title || year != tt.year {
+				t.Errorf("parseTitleYear(%q) = (%q, %d), want (%q, %d)", tt.name, title, year, tt.title, tt.year)
+			}
+		})
+	}
+}
+
+func TestParseChineseNumber(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+		ok   bool
+	}{
+		{"1", 1, true},
+		{"12", 12, true},
+		{"一", 1, true},
+		{"九", 9, true},
+		{"十", 10, true},
+		{"十二", 12, true},
+		{"二十", 20, true},
+		{"二十三", 23, true},
+		{"九十九", 99, true},
+		{"", 0, false},
+		{"百", 0, false},
+		{"十十", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, ok := parseChineseNumber(tt.s)
+			if ok != tt.ok || (ok && got != tt.want) {
+				t.Errorf("parseChineseNumber(%q) = (%d, %v), want (%d, %v)", tt.s, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseSeasonDir(t *testing.T) {
+	tests := []struct {
+		name   string
+		season int
+		ok     bool
+	}{
+		{"Season 1", 1, true},
+		{"season.03", 3, true},
+		{"S02", 2, true},
+		{"第二季", 2, true},
+		{"第十二季", 12, true},
+		{"Specials", 0, true},
+		{"特别篇", 0, true},
+		{"Extras", 0, false},
+		{"Show Season 2", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			season, ok := parseSeasonDir(tt.name)
+			if season != tt.season || ok != tt.ok {
+				t.Errorf("parseSeasonDir(%q) = (%d, %v), want (%d, %v)", tt.name, season, ok, tt.season, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseShowDir(t *testing.T) {
+	tests := []struct {
+		name   string
+		title  string
+		year   int
+		season int
+	}{
+		{"Show Name (2020)", "Show Name", 2020, -1},
+		{"Show.Name.S02.1080p.WEB-DL", "Show Name", 0, 2},
+		{"Show Name Season 3", "Show Name", 0, 3},
+		{"权力的游戏 第十二季", "权力的游戏", 0, 12},
+		{"[Group] Show Name 第2季", "Show Name", 0, 2},
+		{"Show Name", "Show Name", 0, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, year, season := parseShowDir(tt.name)
+			if title != tt.title || year != tt.year || season != tt.season {
+				t.Errorf("parseShowDir(%q) = (%q, %d, %d), want (%q, %d, %d)",
+					tt.name, title, year, season, tt.title, tt.year, tt.season)
+			}
+		})
+	}
+}
+
+func TestParseMediaInfo(t *testing.T) {
+	tests := []struct {
+		desc      string
+		mediaType string
+		name      string
+		dirs      []string
+		want      mediaInfo
+		ok        bool
+	}{
+		{"电影文件名带年份", task.MediaTypeMovie, "Blade.Runner.2049.2017.2160p.UHD", nil, mediaInfo{Title: "Blade Runner 2049", Year: 2017}, true},
+		{"电影年份取自目录", task.MediaTypeMovie, "movie", []string{"Movie Name (2019)"}, mediaInfo{Title: "Movie Name", Year: 2019}, true},
+		{"剧集季号取自文件名", task.MediaTypeTV, "Show.S02E05", []string{"Show Name (2020)", "Season 1"}, mediaInfo{Title: "Show Name", Year: 2020, Season: 2, Episode: 5}, true},
+		{"剧集季号取自季目录", task.MediaTypeTV, "[Group] Show [03]", []string{"Show Name", "第十二季"}, mediaInfo{Title: "Show Name", Season: 12, Episode: 3}, true},
+		{"剧集季号取自剧集目录", task.MediaTypeTV, "EP07", []string{"Show Name 第二季"}, mediaInfo{Title: "Show Name", Season: 2, Episode: 7}, true},
+		{"剧集默认第一季", task.MediaTypeTV, "Show Name - 07", nil, mediaInfo{Title: "Show Name", Season: 1, Episode: 7}, true},
+		{"剧集无法识别集数", task.MediaTypeTV, "Show Name", []string{"Show Name"}, mediaInfo{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, ok := parseMediaInfo(tt.mediaType, tt.name, tt.dirs)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseMediaInfo(%q, %q, %q) = (%+v, %v), want (%+v, %v)",
+					tt.mediaType, tt.name, tt.dirs, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestMediaInfoRelativePath(t *testing.T) {
+	tests := []struct {
+		desc      string
+		info      mediaInfo
+		mediaType string
+		suffix    string
+		want      string
+	}{
+		{"电影", mediaInfo{Title: "Movie", Year: 2019}, task.MediaTypeMovie, "", filepath.Join("Movie (2019)", "Movie (2019).mkv")},
+		{"电影多分段", mediaInfo{Title: "Movie"}, task.MediaTypeMovie, " - cd1", filepath.Join("Movie", "Movie - cd1.mkv")},
+		{"剧集", mediaInfo{Title: "Show: Name", Year: 2020, Season: 1, Episode: 3}, task.MediaTypeTV, "", filepath.Join("Show Name (2020)", "Season 01", "Show Name - S01E03.mkv")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := tt.info.relativePath(tt.mediaType, tt.suffix, ".mkv"); got != tt.want {
+				t.Errorf("relativePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/MccRay-s/alist2strm/model/task"
+	"go.uber.org/zap"
+)
+
+// NFO 文件中的日期格式，与 Emby/Kodi 的 dateadded 一致
+const nfoDateLayout = "2006-01-02 15:04:05"
+
+// nfoFileInfo 从 AList 获取的源文件信息
+type nfoFileInfo struct {
+	Container string `xml:"container,omitempty"` // 容器格式，即扩展名
+	Size      int64  `xml:"size,omitempty"`      // 文件大小（字节）
+}
+
+// nfoMovie 电影 NFO
+type nfoMovie struct {
+	XMLName   xml.Name     `xml:"movie"`
+	Title     string       `xml:"title"`
+	Year      int          `xml:"year,omitempty"`
+	DateAdded string       `xml:"dateadded,omitempty"`
+	FileInfo  *nfoFileInfo `xml:"fileinfo,omitempty"`
+}
+
+// nfoEpisode 剧集单集 NFO
+type nfoEpisode struct {
+	XMLName   xml.Name     `xml:"episodedetails"`
+	Title     string       `xml:"title"`
+	ShowTitle string       `xml:"showtitle"`
+	Season    int          `xml:"season"`
+	Episode   int          `xml:"episode"`
+	DateAdded string       `xml:"dateadded,omitempty"`
+	FileInfo  *nfoFileInfo `xml:"fileinfo,omitempty"`
+}
+
+// nfoTVShow 剧集 NFO
+type nfoTVShow struct {
+	XMLName xml.Name `xml:"tvshow"`
+	Title   string   `xml:"title"`
+	Year    int      `xml:"year,omitempty"`
+}
+
+// pendingNFO 等待写入的 NFO 文件
+type pendingNFO struct {
+	SourcePath string    // 对应的媒体文件，剧集 NFO 为剧集目录
+	TargetPath string    // NFO 文件路径
+	File       AListFile // 记录文件历史使用的文件信息：NFO 文件名，以及媒体文件的大小和修改时间
+	Content    interface{}
+}
+
+// nfoWriter 本地 NFO 生成器：扫描时收集源端没有 NFO 的媒体文件，下载完刮削数据后统一写入，
+// 已存在的本地 NFO（包括刚从源端下载的和 Emby 写入的）不会被覆盖
+type nfoWriter struct {
+	mediaType  string
+	sourceRoot string
+
+	mu      sync.Mutex
+	pending []pendingNFO
+	shows   map[string]bool // 已收集的剧集 NFO 路径
+}
+
+// newNFOWriter 创建 NFO 生成器，任务未开启时返回 nil
+func newNFOWriter(taskInfo *task.Task) *nfoWriter {
+	if !taskInfo.GenerateNFO {
+		return nil
+	}
+	return &nfoWriter{
+		mediaType:  taskInfo.MediaType,
+		sourceRoot: filepath.Clean(taskInfo.SourcePath),
+		shows:      make(map[string]bool),
+	}
+}
+
+// collectNFOs 为当前目录中源端没有 NFO 的媒体文件收集需要生成的 NFO，NFO 与 STRM 同名放在同一目录；
+// 剧集另在剧集目录生成 tvshow.nfo。files 为当前目录的 AList 文件列表，用于判断源端是否已有 NFO
+func (s *generatorRun) collectNFOs(sourcePath string, files []AListFile, medias []FileEntry, strmConfig *StrmConfig) {
+	w := s.nfos
+	if w == nil || len(medias) == 0 {
+		return
+	}
+
+	sourceNFOs := make(map[string]bool)
+	for i := range files {
+		if !files[i].IsDir && strings.EqualFold(filepath.Ext(files[i].Name), ".nfo") {
+			sourceNFOs[strings.ToLower(strings.TrimSuffix(files[i].Name, filepath.Ext(files[i].Name)))] = true
+		}
+	}
+
+	for i := range medias {
+		entry := &medias[i]
+		if sourceNFOs[strings.ToLower(entry.NameWithoutExt)] || (w.mediaType != task.MediaTypeTV && len(medias) == 1 && sourceNFOs["movie"]) {
+			continue
+		}
+
+		info, _, ok := parseEntryMedia(entry, w.mediaType, w.sourceRoot)
+		if entry.Media != nil {
+			info, ok = *entry.Media, true
+		}
+		if !ok {
+			s.logger.Debug("无法从文件名识别媒体信息，不生成 NFO", zap.String("path", entry.SourcePath))
+			continue
+		}
+
+		strmFilePath := buildStrmFilePath(strmConfig, entry.TargetPath)
+		nfoPath := strings.TrimSuffix(strmFilePath, ".strm") + ".nfo"
+		fileInfo := &nfoFileInfo{
+			Container: strings.TrimPrefix(strings.ToLower(filepath.Ext(entry.File.Name)), "."),
+			Size:      entry.File.Size,
+		}
+		dateAdded := ""
+		if !entry.File.Modified.IsZero() {
+			dateAdded = entry.File.Modified.Local().Format(nfoDateLayout)
+		}
+
+		var content interface{}
+		if w.mediaType == task.MediaTypeTV {
+			content = &nfoEpisode{
+				Title:     fmt.Sprintf("第 %d 集", info.Episode),
+				ShowTitle: info.Title,
+				Season:    info.Season,
+				Episode:   info.Episode,
+				DateAdded: dateAdded,
+				FileInfo:  fileInfo,
+			}
+		} else {
+			content = &nfoMovie{
+				Title:     info.Title,
+				Year:      info.Year,
+				DateAdded: dateAdded,
+				FileInfo:  fileInfo,
+			}
+		}
+		w.add(pendingNFO{
+			SourcePath: entry.SourcePath,
+			TargetPath: nfoPath,
+			File:       AListFile{Name: filepath.Base(nfoPath), Size: entry.File.Size, Modified: entry.File.Modified},
+			Content:    content,
+		})
+		s.mirror.keep(nfoPath)
+
+		if w.mediaType != task.MediaTypeTV {
+			continue
+		}
+		// 剧集目录：改名后为季目录的上级目录，否则按源目录是否为季目录判断
+		showSource, showTarget := sourcePath, filepath.Dir(strmFilePath)
+		if _, isSeason := parseSeasonDir(filepath.Base(sourcePath)); entry.Media != nil || isSeason {
+			showSource, showTarget = filepath.Dir(sourcePath), filepath.Dir(showTarget)
+		}
+		// 未改名且位于任务源路径下的文件无法确定所属剧集
+		if (showSource == sourcePath && sourceNFOs["tvshow"]) || (entry.Media == nil && len(sourceDirs(w.sourceRoot, showSource)) == 0) {
+			continue
+		}
+		showPath := filepath.Join(showTarget, "tvshow.nfo")
+		if w.addShow(showPath) {
+			w.add(pendingNFO{
+				SourcePath: showSource,
+				TargetPath: showPath,
+				File:       AListFile{Name: "tvshow.nfo"},
+				Content:    &nfoTVShow{Title: info.Title, Year: info.Year},
+			})
+		}
+		s.mirror.keep(showPath)
+	}
+}
+
+// add 加入等待写入的 NFO
+func (w *nfoWriter) add(nfo pendingNFO) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending = append(w.pending, nfo)
+}
+
+// addShow 记录剧集 NFO，同一个剧集目录只生成一次，首次记录时返回 true
+func (w *nfoWriter) addShow(path string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.shows[path] {
+		return false
+	}
+	w.shows[path] = true
+	return true
+}
+
+// writeNFOs 写入收集到的 NFO 文件，本地已存在的跳过，写入成功的按刮削数据记录文件历史
+func (s *generatorRun) writeNFOs(ctx context.Context, taskInfo *task.Task, taskLogID uint) {
+	w := s.nfos
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	pending := w.pending
+	w.mu.Unlock()
+
+	for _, nfo := range pending {
+		if ctx.Err() != nil {
+			return
+		}
+		if s.fileExistsLocally(nfo.TargetPath) {
+			continue
+		}
+		if err := writeNFOFile(nfo.TargetPath, nfo.Content); err != nil {
+			s.logger.Error("生成 NFO 文件失败", zap.String("path", nfo.TargetPath), zap.Error(err))
+			s.stats.Mutex.Lock()
+			s.stats.FailedCount++
+			s.stats.Mutex.Unlock()
+			continue
+		}
+
+		s.logger.Info("已生成 NFO 文件", zap.String("path", nfo.TargetPath))
+		s.recordFileHistory(taskInfo.ID, taskLogID, &nfo.File, nfo.SourcePath, nfo.TargetPath, FileTypeMetadata, true, nil)
+		s.stats.Mutex.Lock()
+		s.stats.NfoGenerated++
+		s.stats.Mutex.Unlock()
+	}
+}
+
+// writeNFOFile 将 NFO 内容编码为 XML 写入文件
+func writeNFOFile(path string, content interface{}) error {
+	data, err := xml.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data = append([]byte(xml.Header), data...)
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/MccRay-s/alist2strm/model/task"
+	taskResponse "github.com/MccRay-s/alist2strm/model/task/response"
+	"go.uber.org/zap"
+)
+
+// folderPasswords 按目录查找 AList 目录密码
+// AList 的目录密码对子目录同样生效，因此使用路径最长（最近）的上级目录配置的密码
+type folderPasswords struct {
+	passwords map[string]string // AList 完整目录路径 -> 密码
+}
+
+// newFolderPasswords 创建目录密码查找器，任务未配置密码时返回 nil
+func newFolderPasswords(taskInfo *task.Task) *folderPasswords {
+	if len(taskInfo.FolderPasswords) == 0 {
+		return nil
+	}
+	r := &folderPasswords{passwords: make(map[string]string, len(taskInfo.FolderPasswords))}
+	for _, item := range taskInfo.FolderPasswords {
+		r.passwords[path.Join("/", taskInfo.SourcePath, item.Path)] = item.Password
+	}
+	return r
+}
+
+// lookup 获取目录需要发送的密码，没有配置时返回空字符串
+func (r *folderPasswords) lookup(dirPath string) string {
+	if r == nil {
+		return ""
+	}
+	dir := path.Clean("/" + strings.ReplaceAll(dirPath, "\\", "/"))
+	for {
+		if password, ok := r.passwords[dir]; ok {
+			return password
+		}
+		if dir == "/" {
+			return ""
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+// normalizeFolderPasswords 校验目录密码并将路径统一为相对任务源路径的形式
+// 路径可以是相对路径，也可以是位于源路径下的 AList 完整路径；
+// 密码为空时沿用 existing 中同一目录的密码（响应中不返回密码，编辑时未修改的目录密码为空）
+func normalizeFolderPasswords(taskInfo *task.Task, items, existing []task.FolderPassword) ([]task.FolderPassword, error) {
+	existingByPath := make(map[string]string, len(existing))
+	for _, item := range existing {
+		existingByPath[item.Path] = item.Password
+	}
+
+	result := make([]task.FolderPassword, 0, len(items))
+	seen := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		rel, err := resolveSubPath(taskInfo, item.Path)
+		if err != nil {
+			return nil, fmt.Errorf("目录密码路径错误: %w", err)
+		}
+		if _, ok := seen[rel]; ok {
+			return nil, fmt.Errorf("目录 %s 重复设置了密码", path.Join("/", taskInfo.SourcePath, rel))
+		}
+		password := item.Password
+		if password == "" {
+			password = existingByPath[rel]
+		}
+		if password == "" {
+			return nil, fmt.Errorf("目录 %s 的密码不能为空", path.Join("/", taskInfo.SourcePath, rel))
+		}
+		seen[rel] = struct{}{}
+		result = append(result, task.FolderPassword{Path: rel, Password: password})
+	}
+	return result, nil
+}
+
+// toFolderPasswordInfos 将目录密码转换为不含密码的响应结构
+func toFolderPasswordInfos(items []task.FolderPassword) []taskResponse.FolderPasswordInfo {
+	infos := make([]taskResponse.FolderPasswordInfo, 0, len(items))
+	for _, item := range items {
+		infos = append(infos, taskResponse.FolderPasswordInfo{Path: item.Path, HasPassword: item.Password != ""})
+	}
+	return infos
+}
+
+// fileSign 获取构建文件 URL 使用的签名
+// 受密码保护目录中的文件必须携带签名才能访问，列表未返回签名时携带目录密码调用 /api/fs/get 获取并写回 file，
+// 仍然没有签名时返回错误，避免写入无法访问的链接
+func (s *generatorRun) fileSign(ctx context.Context, file *AListFile, sourcePath string) (string, error) {
+	password := s.passwords.lookup(path.Dir(sourcePath))
+	if file.Sign != "" || password == "" {
+		return file.Sign, nil
+	}
+
+	s.logger.Debug("受密码保护目录中的文件缺少签名，通过 fs/get 获取", zap.String("path", sourcePath))
+	info, err := s.alist.GetFile(ctx, sourcePath, password)
+	if err != nil {
+		return "", fmt.Errorf("获取受密码保护文件的签名失败: %w", err)
+	}
+	if info.Sign == "" {
+		return "", errors.New("AList 未返回受密码保护文件的签名，请检查目录密码是否正确")
+	}
+	file.Sign = info.Sign
+	return file.Sign, nil
+}
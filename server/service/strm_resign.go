@@ -0,0 +1,356 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MccRay-s/alist2strm/model/filehistory"
+	"github.com/MccRay-s/alist2strm/model/task"
+	taskResponse "github.com/MccRay-s/alist2strm/model/task/response"
+	"github.com/MccRay-s/alist2strm/repository"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+// resignAhead 签名在此时间内过期的 STRM 文件提前重新签名，避免在两次定时重新签名之间失效
+const resignAhead = 24 * time.Hour
+
+// rewritingTasks 正在重新签名或修复 STRM 文件的任务，同一任务同时只执行一次，且不与生成同时执行
+var rewritingTasks sync.Map
+
+// acquireRewrite 占用任务以重新签名或修复 STRM 文件，任务正在执行或已被占用时返回错误，占用成功后需调用 release
+// 先占用任务再检查运行状态，与生成时先标记运行状态再检查占用的顺序相反，保证两者不会同时执行
+func acquireRewrite(taskID uint, action string) (*task.Task, func(), error) {
+	if _, loaded := rewritingTasks.LoadOrStore(taskID, struct{}{}); loaded {
+		return nil, nil, errors.New("任务正在重新签名或修复 STRM 文件")
+	}
+	release := func() { rewritingTasks.Delete(taskID) }
+
+	taskInfo, err := repository.Task.GetByID(taskID)
+	if err != nil {
+		release()
+		return nil, nil, fmt.Errorf("获取任务信息失败: %w", err)
+	}
+	if taskInfo == nil {
+		release()
+		return nil, nil, errors.New("任务不存在")
+	}
+	if taskInfo.Running {
+		release()
+		return nil, nil, fmt.Errorf("任务正在执行中，请稍后再%s", action)
+	}
+	return taskInfo, release, nil
+}
+
+// isRewriting 检查任务是否正在重新签名或修复 STRM 文件
+func isRewriting(taskID uint) bool {
+	_, ok := rewritingTasks.Load(taskID)
+	return ok
+}
+
+// strmRewriter 按任务当前的配置重新写入已生成的 STRM 文件，用于重新签名和修复失效链接
+type strmRewriter struct {
+	run        *generatorRun
+	taskInfo   *task.Task
+	strmConfig *StrmConfig
+}
+
+// ValidateResignCron 校验重新签名的 Cron 表达式，为空表示不执行
+func ValidateResignCron(expr string) error {
+	if strings.TrimSpace(expr) == "" {
+		return nil
+	}
+	if _, err := cron.ParseStandard(expr); err != nil {
+		return fmt.Errorf("重新签名 Cron 表达式格式错误: %w", err)
+	}
+	return nil
+}
+
+// ResignStrmFiles 重新签名任务已生成的 STRM 文件
+// 根据文件历史中记录的签名选出签名缺失或即将过期的 STRM 文件，逐个调用 /api/fs/get 获取 AList 当前的签名和直链，
+// 按任务当前的配置重新构建内容，与本地 STRM 文件内容不一致时重新写入，并更新文件历史中的签名
+func (s *StrmGeneratorService) ResignStrmFiles(ctx context.Context, taskID uint) (*taskResponse.TaskResignResp, error) {
+	if !s.IsInitialized() {
+		return nil, fmt.Errorf("STRM 生成服务未正确初始化")
+	}
+
+	taskInfo, release, err := acquireRewrite(taskID, "重新签名")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	rewriter, err := s.newStrmRewriter(taskInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	histories, err := repository.FileHistory.ListByTaskID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件历史失败: %w", err)
+	}
+
+	start := time.Now()
+	result := &taskResponse.TaskResignResp{TaskID: taskInfo.ID, TaskName: taskInfo.Name}
+	s.logger.Info("开始重新签名 STRM 文件", zap.Uint("taskID", taskID), zap.String("taskName", taskInfo.Name))
+
+	for _, history := range histories {
+		if !history.IsStrm {
+			continue
+		}
+		if !needsResign(history, start) {
+			result.NotDue++
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result.Checked++
+
+		rewritten, err := rewriter.rewrite(ctx, history, false)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			result.Missing++
+		case err != nil:
+			result.Failed++
+			s.logger.Warn("重新签名 STRM 文件失败",
+				zap.String("sourcePath", history.SourcePath),
+				zap.String("strmFile", history.TargetFilePath),
+				zap.Error(err))
+		case rewritten:
+			result.Rewritten++
+		default:
+			result.Unchanged++
+		}
+	}
+
+	result.Duration = time.Since(start).Round(time.Millisecond).String()
+	s.logger.Info("重新签名 STRM 文件完成",
+		zap.Uint("taskID", taskID),
+		zap.Int("检查数", result.Checked),
+		zap.Int("未到期数", result.NotDue),
+		zap.Int("重新写入数", result.Rewritten),
+		zap.Int("未变化数", result.Unchanged),
+		zap.Int("本地缺失数", result.Missing),
+		zap.Int("失败数", result.Failed),
+		zap.String("耗时", result.Duration))
+	return result, nil
+}
+
+// needsResign 判断 STRM 文件是否需要重新签名：任一链接的签名缺失、无法解析或将在 resignAhead 内过期
+// 多分段播放列表的每个分段都单独判断，旧记录没有分段签名时同样需要重新签名
+func needsResign(history *filehistory.FileHistory, now time.Time) bool {
+	if len(history.StackSigns) != len(history.StackSources) {
+		return true
+	}
+	for _, sign := range append([]string{history.Sign}, history.StackSigns...) {
+		expiry, ok := signExpiry(sign)
+		if !ok || (!expiry.IsZero() && expiry.Before(now.Add(resignAhead))) {
+			return true
+		}
+	}
+	return false
+}
+
+// signExpiry 解析 AList 签名中的过期时间，签名格式为 "<HMAC>:<过期时间戳>"
+// 时间戳为 0 表示永不过期，返回零值；签名为空或格式不正确时返回 false
+func signExpiry(sign string) (time.Time, bool) {
+	i := strings.LastIndex(sign, ":")
+	if i <= 0 {
+		return time.Time{}, false
+	}
+	expire, err := strconv.ParseInt(sign[i+1:], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if expire == 0 {
+		return time.Time{}, true
+	}
+	return time.Unix(expire, 0), true
+}
+
+// newStrmRewriter 加载任务使用的 AList 客户端、目录密码、STRM 配置和内容模板
+func (s *StrmGeneratorService) newStrmRewriter(taskInfo *task.Task) (*strmRewriter, error) {
+	run := &generatorRun{StrmGeneratorService: s}
+	var err error
+	if run.alist, err = s.alistService.GetClient(taskInfo.AListProfileID); err != nil {
+		return nil, fmt.Errorf("获取 AList 配置失败: %w", err)
+	}
+	run.passwords = newFolderPasswords(taskInfo)
+	strmConfig, err := loadEffectiveStrmConfig(taskInfo)
+	if err != nil {
+		return nil, fmt.Errorf("加载 STRM 配置失败: %w", err)
+	}
+	if run.strmTemplate, err = parseStrmTemplate(taskInfo.StrmTemplate); err != nil {
+		return nil, fmt.Errorf("STRM 内容模板格式错误: %w", err)
+	}
+	return &strmRewriter{run: run, taskInfo: taskInfo, strmConfig: strmConfig}, nil
+}
+
+// rewrite 重新构建单个 STRM 文件的内容，内容有变化时写入并返回 true
+// force 为 false 时本地文件不存在返回 os.ErrNotExist；为 true 时无论内容是否变化都重新写入，文件不存在时重新创建
+func (w *strmRewriter) rewrite(ctx context.Context, history *filehistory.FileHistory, force bool) (bool, error) {
+	current, err := os.ReadFile(history.TargetFilePath)
+	if err != nil && !(force && errors.Is(err, os.ErrNotExist)) {
+		return false, err
+	}
+
+	info, err := w.run.alist.GetFile(ctx, history.SourcePath, w.run.passwords.lookup(path.Dir(history.SourcePath)))
+	if err != nil {
+		return false, fmt.Errorf("获取文件信息失败: %w", err)
+	}
+
+	// 无论任务的链接来源是什么，都以 fs/get 返回的签名为准
+	file := info.AListFile
+	content, err := w.run.buildStrmContent(&file, info.RawURL, w.strmConfig, w.taskInfo, history.SourcePath)
+	if err != nil {
+		return false, err
+	}
+
+	// 多分段播放列表按分段顺序重新构建每一行的链接
+	partSigns := make([]string, 0, len(history.StackSources))
+	for _, partPath := range history.StackSources {
+		partInfo, err := w.run.alist.GetFile(ctx, partPath, w.run.passwords.lookup(path.Dir(partPath)))
+		if err != nil {
+			return false, fmt.Errorf("获取分段文件信息失败 [%s]: %w", partPath, err)
+		}
+		partFile := partInfo.AListFile
+		partContent, err := w.run.buildStrmContent(&partFile, partInfo.RawURL, w.strmConfig, w.taskInfo, partPath)
+		if err != nil {
+			return false, err
+		}
+		content += "\n" + partContent
+		partSigns = append(partSigns, partFile.Sign)
+	}
+
+	now := time.Now()
+	// 按列名更新时 GORM 不会调用字段的 JSON 序列化器，需要自行编码
+	partSignsJSON, _ := json.Marshal(partSigns)
+	updateData := map[string]interface{}{"sign": file.Sign, "signed_at": &now, "stack_signs": string(partSignsJSON)}
+	rewritten := force || !bytes.Equal(bytes.TrimSpace(current), []byte(strings.TrimSpace(content)))
+	if rewritten {
+		if err := os.MkdirAll(filepath.Dir(history.TargetFilePath), 0755); err != nil {
+			return false, fmt.Errorf("创建目标目录失败: %w", err)
+		}
+		if err := os.WriteFile(history.TargetFilePath, []byte(content), 0644); err != nil {
+			return false, fmt.Errorf("写入 STRM 文件失败: %w", err)
+		}
+		w.run.logger.Info("STRM 文件已重新写入",
+			zap.String("sourcePath", history.SourcePath),
+			zap.String("strmFile", history.TargetFilePath))
+	} else if history.Sign == file.Sign && slices.Equal(history.StackSigns, partSigns) {
+		return false, nil
+	}
+
+	if err := repository.FileHistory.UpdateByID(history.ID, updateData); err != nil {
+		w.run.logger.Warn("更新文件历史签名失败", zap.Uint("id", history.ID), zap.Error(err))
+	}
+	return rewritten, nil
+}
+
+// RegenerateStrmFiles 按任务当前的配置强制重新生成指定的 STRM 文件，本地文件不存在时重新创建
+// 返回每条记录的处理结果，成功为 nil；任务不可用时返回错误
+func (s *StrmGeneratorService) RegenerateStrmFiles(ctx context.Context, taskID uint, histories []*filehistory.FileHistory) (map[uint]error, error) {
+	taskInfo, release, err := acquireRewrite(taskID, "重新生成")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	rewriter, err := s.newStrmRewriter(taskInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[uint]error, len(histories))
+	for _, history := range histories {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		_, results[history.ID] = rewriter.rewrite(ctx, history, true)
+	}
+	return results, nil
+}
+
+// DeleteStrmFiles 删除指定的 STRM 文件、文件历史中与其同名的字幕和刮削数据，以及对应的文件历史
+// 同时清除 STRM 源目录的快照，下次运行重新处理这些目录；返回每条记录的处理结果，成功为 nil
+func (s *StrmGeneratorService) DeleteStrmFiles(taskID uint, histories []*filehistory.FileHistory) (map[uint]error, error) {
+	_, release, err := acquireRewrite(taskID, "删除")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	taskHistories, err := repository.FileHistory.ListByTaskID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件历史失败: %w", err)
+	}
+	sidecars := strmSidecars(taskHistories)
+
+	results := make(map[uint]error, len(histories))
+	var deletedIDs []uint
+	var sourceDirs []string
+	for _, history := range histories {
+		if err := os.Remove(history.TargetFilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			results[history.ID] = err
+			continue
+		}
+		results[history.ID] = nil
+		deletedIDs = append(deletedIDs, history.ID)
+		sourceDirs = append(sourceDirs, path.Dir(history.SourcePath))
+
+		for _, sidecar := range sidecars[history.TargetFilePath] {
+			if err := os.Remove(sidecar.TargetFilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				s.logger.Warn("删除 STRM 关联文件失败", zap.String("path", sidecar.TargetFilePath), zap.Error(err))
+				continue
+			}
+			deletedIDs = append(deletedIDs, sidecar.ID)
+		}
+	}
+
+	if err := repository.FileHistory.DeleteByIDs(deletedIDs); err != nil {
+		return nil, err
+	}
+	if err := repository.DirSnapshot.DeleteBySourcePaths(taskID, sourceDirs); err != nil {
+		s.logger.Warn("清除目录快照失败", zap.Uint("taskID", taskID), zap.Error(err))
+	}
+	return results, nil
+}
+
+// strmSidecars 按 STRM 文件路径归类同一目录中属于它的字幕和刮削数据文件历史
+// 与下载时的匹配规则一致：文件名以 STRM 文件名为前缀且之后是分隔符，多个 STRM 匹配时取文件名最长的
+func strmSidecars(histories []*filehistory.FileHistory) map[string][]*filehistory.FileHistory {
+	medias := make(map[string][]FileEntry)
+	for _, history := range histories {
+		if history.IsStrm {
+			dir, name := filepath.Split(history.TargetFilePath)
+			medias[dir] = append(medias[dir], FileEntry{
+				TargetPath:     history.TargetFilePath,
+				NameWithoutExt: strings.TrimSuffix(name, filepath.Ext(name)),
+			})
+		}
+	}
+
+	sidecars := make(map[string][]*filehistory.FileHistory)
+	for _, history := range histories {
+		if history.IsStrm {
+			continue
+		}
+		dir, name := filepath.Split(history.TargetFilePath)
+		if media := mediaNamePrefix(strings.TrimSuffix(name, filepath.Ext(name)), medias[dir]); media != nil {
+			sidecars[media.TargetPath] = append(sidecars[media.TargetPath], history)
+		}
+	}
+	return sidecars
+}
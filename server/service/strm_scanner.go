@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/MccRay-s/alist2strm/model/task"
+	"github.com/MccRay-s/alist2strm/model/tasklog"
+	"go.uber.org/zap"
+)
+
+const (
+	scanDirRetryCount = 2               // 容错模式下单个目录扫描失败后的重试次数
+	scanDirRetryDelay = 3 * time.Second // 容错模式下目录重试的基础间隔，按重试次数递增
+)
+
+// dirScanJob 待扫描的目录
+type dirScanJob struct {
+	SourcePath string
+	TargetPath string
+	DirInfo    *AListFile // 父目录列表中该目录的信息，根目录为 nil
+	Provider   string     // 父目录所在存储的驱动名称，子目录通常位于同一存储，用于控制刷新频率
+}
+
+// dirScanPool 目录扫描工作池
+// 固定数量的协程从共享栈中领取目录，扫描得到的子目录再压回栈中，由空闲协程继续处理；
+// 使用栈而不是队列可以让扫描大体保持深度优先，并发数为 1 时与原先的递归顺序一致
+type dirScanPool struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	jobs     []dirScanJob
+	active   int                      // 正在扫描的目录数
+	err      error                    // 第一个扫描错误，出现后不再领取新目录
+	failures []tasklog.TaskLogFailure // 容错模式下记录的失败目录
+}
+
+// scanDirectories 使用有限并发的工作池扫描任务源目录
+// 请求间隔由 AList 客户端全局控制，并发只用于重叠请求的网络等待时间
+// 容错模式下子目录扫描失败会在重试后记录下来并继续扫描其他目录，返回失败目录列表；根目录失败仍然直接返回错误
+// root 为扫描起点，通常是任务源路径，指定子路径执行时为对应的子目录；ctx 取消后不再领取新目录，返回 ctx 的错误
+func (s *generatorRun) scanDirectories(ctx context.Context, taskInfo *task.Task, strmConfig *StrmConfig, taskLogID uint, root dirScanJob, concurrency int) ([]tasklog.TaskLogFailure, error) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	pool := &dirScanPool{
+		jobs: []dirScanJob{root},
+	}
+	pool.cond = sync.NewCond(&pool.mu)
+
+	s.logger.Info("开始扫描目录",
+		zap.String("sourcePath", root.SourcePath),
+		zap.Int("并发数", concurrency))
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				job, ok := pool.take()
+				if !ok {
+					return
+				}
+				if ctx.Err() != nil {
+					pool.done(nil, ctx.Err())
+					continue
+				}
+				if !taskInfo.TolerantMode || job.DirInfo == nil {
+					subDirs, err := s.scanDirectory(ctx, taskInfo, strmConfig, taskLogID, job)
+					pool.done(subDirs, err)
+					continue
+				}
+
+				subDirs, retries, err := s.scanDirectoryWithRetry(ctx, taskInfo, strmConfig, taskLogID, job)
+				if err != nil && ctx.Err() != nil {
+					// 取消导致的失败不记录为失败目录
+					pool.done(nil, ctx.Err())
+					continue
+				}
+				if err != nil {
+					s.logger.Warn("目录扫描失败，已记录并继续扫描其他目录",
+						zap.String("sourcePath", job.SourcePath),
+						zap.Int("重试次数", retries),
+						zap.Error(err))
+
+					// 失败目录下已有的目标文件需要保留，且上级目录不能记为未变化
+					s.mirror.keepTree(job.SourcePath, job.TargetPath)
+					s.snapshots.invalidate(job.SourcePath)
+					s.checkpoints.failDir(job.SourcePath)
+					pool.fail(tasklog.TaskLogFailure{
+						TaskLogID:  taskLogID,
+						TaskID:     taskInfo.ID,
+						Path:       job.SourcePath,
+						Error:      err.Error(),
+						RetryCount: retries,
+					})
+					continue
+				}
+				pool.done(subDirs, nil)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return pool.failures, pool.err
+}
+
+// scanDirectoryWithRetry 扫描目录，失败时按递增间隔重试，返回子目录、实际重试次数和最后一次错误
+// scanDirectory 在获取列表和创建目标目录成功之前不会产生任何副作用，因此可以安全重试
+func (s *generatorRun) scanDirectoryWithRetry(ctx context.Context, taskInfo *task.Task, strmConfig *StrmConfig, taskLogID uint, job dirScanJob) ([]dirScanJob, int, error) {
+	var lastErr error
+	for attempt := 0; attempt <= scanDirRetryCount; attempt++ {
+		if attempt > 0 {
+			if err := sleepContext(ctx, time.Duration(attempt)*scanDirRetryDelay); err != nil {
+				return nil, attempt - 1, err
+			}
+		}
+		subDirs, err := s.scanDirectory(ctx, taskInfo, strmConfig, taskLogID, job)
+		if err == nil {
+			return subDirs, attempt, nil
+		}
+		lastErr = err
+	}
+	return nil, scanDirRetryCount, lastErr
+}
+
+// failedDirPaths 提取失败目录路径用于通知展示，数量过多时截断
+func failedDirPaths(failures []tasklog.TaskLogFailure) []string {
+	const maxNotifyFailedDirs = 20
+	paths := make([]string, 0, len(failures))
+	for i, failure := range failures {
+		if i >= maxNotifyFailedDirs {
+			break
+		}
+		paths = append(paths, failure.Path)
+	}
+	return paths
+}
+
+// take 领取一个待扫描目录，栈为空且没有正在扫描的目录，或已出现错误时返回 false
+func (p *dirScanPool) take() (dirScanJob, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for len(p.jobs) == 0 && p.active > 0 && p.err == nil {
+		p.cond.Wait()
+	}
+	if p.err != nil || len(p.jobs) == 0 {
+		return dirScanJob{}, false
+	}
+
+	job := p.jobs[len(p.jobs)-1]
+	p.jobs = p.jobs[:len(p.jobs)-1]
+	p.active++
+	return job, true
+}
+
+// fail 记录一个扫描失败的目录，不影响其他目录继续扫描
+func (p *dirScanPool) fail(failure tasklog.TaskLogFailure) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.active--
+	p.failures = append(p.failures, failure)
+	p.cond.Broadcast()
+}
+
+// done 完成一个目录的扫描，将子目录压回栈中并唤醒等待的协程
+func (p *dirScanPool) done(subDirs []dirScanJob, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.active--
+	if err != nil && p.err == nil {
+		p.err = err
+	}
+	// 逆序入栈，保证先扫描列表中靠前的子目录
+	for i := len(subDirs) - 1; i >= 0; i-- {
+		p.jobs = append(p.jobs, subDirs[i])
+	}
+	p.cond.Broadcast()
+}
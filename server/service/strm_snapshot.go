@@ -0,0 +1,174 @@
+package service
+
+import (
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/MccRay-s/alist2strm/model/dirsnapshot"
+	"github.com/MccRay-s/alist2strm/model/filehistory"
+	"github.com/MccRay-s/alist2strm/repository"
+)
+
+// snapshotTracker 增量扫描状态：上次成功运行的目录快照、STRM 文件历史，以及本次运行采集到的新快照
+type snapshotTracker struct {
+	enabled   bool
+	mu        sync.Mutex
+	previous  map[string]dirsnapshot.DirSnapshot
+	current   map[string]dirsnapshot.DirSnapshot
+	histories map[string]*filehistory.FileHistory // 以源文件完整路径为键的 STRM 文件历史
+}
+
+// newSnapshotTracker 创建增量扫描跟踪器，未启用时不加载任何数据
+func newSnapshotTracker(taskID uint, enabled bool) (*snapshotTracker, error) {
+	t := &snapshotTracker{
+		enabled:   enabled,
+		previous:  make(map[string]dirsnapshot.DirSnapshot),
+		current:   make(map[string]dirsnapshot.DirSnapshot),
+		histories: make(map[string]*filehistory.FileHistory),
+	}
+	if !enabled {
+		return t, nil
+	}
+
+	snapshots, err := repository.DirSnapshot.ListByTaskID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		t.previous[snapshot.SourcePath] = snapshot
+	}
+
+	histories, err := repository.FileHistory.ListByTaskID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	for _, history := range histories {
+		if !history.IsStrm {
+			continue
+		}
+		// 同一路径可能因文件大小变化留下多条记录，保留最近更新的一条
+		if existing, ok := t.histories[history.SourcePath]; ok && existing.UpdatedAt.After(history.UpdatedAt) {
+			continue
+		}
+		t.histories[history.SourcePath] = history
+	}
+
+	return t, nil
+}
+
+// isListingUnchanged 根据修改时间、子项数量和直接子文件大小之和判断目录自身的列表是否未变化
+// 只说明目录的直接子项未变化，子目录需要单独判断
+func (t *snapshotTracker) isListingUnchanged(sourcePath string, dir *AListFile, files []AListFile) bool {
+	if !t.enabled || dir == nil || dir.Modified.IsZero() {
+		return false
+	}
+	t.mu.Lock()
+	previous, ok := t.previous[sourcePath]
+	t.mu.Unlock()
+	if !ok || previous.Modified.Unix() != dir.Modified.Unix() {
+		return false
+	}
+	childCount, sizeSum := summarizeListing(files)
+	return previous.ChildCount == childCount && previous.SizeSum == sizeSum
+}
+
+// record 记录本次扫描到的目录状态，运行成功结束后统一写入数据库
+func (t *snapshotTracker) record(taskID uint, sourcePath string, dir *AListFile, files []AListFile) {
+	if !t.enabled {
+		return
+	}
+	childCount, sizeSum := summarizeListing(files)
+	snapshot := dirsnapshot.DirSnapshot{
+		TaskID:     taskID,
+		SourcePath: sourcePath,
+		ChildCount: childCount,
+		SizeSum:    sizeSum,
+	}
+	if dir != nil {
+		snapshot.Modified = dir.Modified
+	}
+
+	t.mu.Lock()
+	t.current[sourcePath] = snapshot
+	t.mu.Unlock()
+}
+
+// invalidate 丢弃目录及其所有上级目录本次采集的快照
+// 子目录扫描失败时上级目录同样不记为未变化，下次运行重新处理失败目录所在的整条路径
+func (t *snapshotTracker) invalidate(sourcePath string) {
+	if !t.enabled {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for dir := sourcePath; ; {
+		delete(t.current, dir)
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return
+		}
+		dir = parent
+	}
+}
+
+// isFileUnchanged 根据文件历史中的大小和修改时间判断媒体文件是否未变化
+func (t *snapshotTracker) isFileUnchanged(sourcePath string, file *AListFile) bool {
+	if !t.enabled {
+		return false
+	}
+	t.mu.Lock()
+	history, ok := t.histories[sourcePath]
+	t.mu.Unlock()
+	if !ok || history.ModifiedAt == nil {
+		return false
+	}
+	return history.FileSize == file.Size && history.ModifiedAt.Unix() == file.Modified.Unix()
+}
+
+// largestHistoryIn 查找源文件直接位于指定目录中的 STRM 文件历史，有多条时返回文件最大的一条
+func (t *snapshotTracker) largestHistoryIn(dirs ...string) *filehistory.FileHistory {
+	if !t.enabled {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var largest *filehistory.FileHistory
+	for sourcePath, history := range t.histories {
+		if !slices.Contains(dirs, filepath.Dir(sourcePath)) {
+			continue
+		}
+		if largest == nil || history.FileSize > largest.FileSize {
+			largest = history
+		}
+	}
+	return largest
+}
+
+// flush 将本次运行采集到的目录快照写入数据库
+func (t *snapshotTracker) flush() error {
+	if !t.enabled {
+		return nil
+	}
+	t.mu.Lock()
+	snapshots := make([]dirsnapshot.DirSnapshot, 0, len(t.current))
+	now := time.Now()
+	for _, snapshot := range t.current {
+		snapshot.UpdatedAt = now
+		snapshots = append(snapshots, snapshot)
+	}
+	t.mu.Unlock()
+	return repository.DirSnapshot.Upsert(snapshots)
+}
+
+// summarizeListing 统计目录列表的子项数量和直接子文件大小之和
+func summarizeListing(files []AListFile) (int, int64) {
+	var sizeSum int64
+	for _, file := range files {
+		if !file.IsDir {
+			sizeSum += file.Size
+		}
+	}
+	return len(files), sizeSum
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/MccRay-s/alist2strm/model/task"
+)
+
+// stackPartPattern 常见的多分段命名：Movie.cd1、Movie - part2、Movie_disc1、Movie.pt3、Movie [CD2] 等
+// 分段标记前必须有分隔符或括号，避免 Script1、Abcd2 这类文件名被误判
+var stackPartPattern = regexp.MustCompile(`(?i)^(.+?)([\s._-]+[\[(]?|[\[(])(cd|dvd|part|pt|disc|disk)[\s._-]*(\d{1,2})[\])]?$`)
+
+// stackPart 识别出的分段
+type stackPart struct {
+	Title  string // 去掉分段标记后的标题
+	Kind   string // 分段标记（小写），如 cd、part
+	Number int
+}
+
+// parseStackPart 从不含扩展名的文件名中识别分段标记
+// 只用空格分隔的 "Part N" 通常是系列中的不同影片（如 "Deathly Hallows Part 1"），标题中没有分辨率等发布信息时不视为分段
+func parseStackPart(name string) (stackPart, bool) {
+	m := stackPartPattern.FindStringSubmatch(name)
+	if m == nil {
+		return stackPart{}, false
+	}
+	number, err := strconv.Atoi(m[4])
+	if err != nil || number == 0 {
+		return stackPart{}, false
+	}
+	title := strings.TrimRight(m[1], " ._-([")
+	if title == "" {
+		return stackPart{}, false
+	}
+	kind := strings.ToLower(m[3])
+	if (kind == "part" || kind == "pt") && strings.TrimSpace(m[2]) == "" && !mediaQualityPattern.MatchString(title) {
+		return stackPart{}, false
+	}
+	return stackPart{Title: title, Kind: kind, Number: number}, true
+}
+
+// stackMediaEntries 按任务的多分段处理方式处理同一目录中的媒体文件
+// 同一源目录中标题、分段标记和扩展名相同、分段号从 1 开始连续的两个及以上文件视为一组；
+// rename 模式将每个分段的 STRM 改名为 "<标题> - cd1"，playlist 模式只保留第一段，其余分段合并到第一段的 STRM 中
+// 返回需要生成 STRM 的条目，以及合并到播放列表而不再单独生成的分段数
+func stackMediaEntries(entries []FileEntry, mode string) ([]FileEntry, int) {
+	if mode != task.StackModeRename && mode != task.StackModePlaylist {
+		return entries, 0
+	}
+
+	type member struct {
+		index int
+		part  stackPart
+	}
+	groups := make(map[string][]member)
+	var keys []string
+	for i, entry := range entries {
+		part, ok := parseStackPart(entry.NameWithoutExt)
+		if !ok {
+			continue
+		}
+		key := strings.Join([]string{
+			filepath.Dir(entry.SourcePath),
+			strings.ToLower(part.Title),
+			part.Kind,
+			strings.ToLower(filepath.Ext(entry.File.Name)),
+		}, "|")
+		if _, exists := groups[key]; !exists {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], member{index: i, part: part})
+	}
+
+	merged := make(map[int]bool)
+	for _, key := range keys {
+		members := groups[key]
+		if len(members) < 2 {
+			continue
+		}
+		sort.Slice(members, func(i, j int) bool { return members[i].part.Number < members[j].part.Number })
+		contiguous := true
+		for i, m := range members {
+			if m.part.Number != i+1 {
+				contiguous = false
+				break
+			}
+		}
+		if !contiguous {
+			continue
+		}
+
+		first := &entries[members[0].index]
+		ext := filepath.Ext(first.File.Name)
+		if mode == task.StackModeRename {
+			for _, m := range members {
+				entry := &entries[m.index]
+				name := fmt.Sprintf("%s - %s%d%s", m.part.Title, m.part.Kind, m.part.Number, ext)
+				entry.TargetPath = filepath.Join(filepath.Dir(entry.TargetPath), name)
+			}
+			continue
+		}
+
+		first.TargetPath = filepath.Join(filepath.Dir(first.TargetPath), members[0].part.Title+ext)
+		for _, m := range members[1:] {
+			first.StackParts = append(first.StackParts, entries[m.index])
+			merged[m.index] = true
+		}
+	}
+
+	if len(merged) == 0 {
+		return entries, 0
+	}
+	result := make([]FileEntry, 0, len(entries)-len(merged))
+	for i, entry := range entries {
+		if !merged[i] {
+			result = append(result, entry)
+		}
+	}
+	return result, len(merged)
+}
+
+// stackSources 合并到播放列表中的其他分段的源路径，记录在文件历史中供重新签名使用
+func (e *FileEntry) stackSources() []string {
+	if len(e.StackParts) == 0 {
+		return nil
+	}
+	sources := make([]string, 0, len(e.StackParts))
+	for _, part := range e.StackParts {
+		sources = append(sources, part.SourcePath)
+	}
+	return sources
+}
+
+// stackSigns 返回合并为播放列表的其他分段写入的签名，与 stackSources 一一对应
+func (e *FileEntry) stackSigns() []string {
+	if len(e.StackParts) == 0 {
+		return nil
+	}
+	signs := make([]string, 0, len(e.StackParts))
+	for _, part := range e.StackParts {
+		signs = append(signs, part.File.Sign)
+	}
+	return signs
+}
+
+// isEntryUnchanged 增量扫描判断媒体文件是否未变化，合并为播放列表时所有分段都未变化才算未变化
+// 合并的分段没有单独的文件历史，所在目录有变化时播放列表总是重新生成
+func (s *generatorRun) isEntryUnchanged(entry FileEntry) bool {
+	if !s.snapshots.isFileUnchanged(entry.SourcePath, entry.File) {
+		return false
+	}
+	for _, part := range entry.StackParts {
+		if !s.snapshots.isFileUnchanged(part.SourcePath, part.File) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MccRay-s/alist2strm/model/task"
+)
+
+func TestParseStackPart(t *testing.T) {
+	tests := []struct {
+		name string
+		want stackPart
+		ok   bool
+	}{
+		{"Movie.cd1", stackPart{Title: "Movie", Kind: "cd", Number: 1}, true},
+		{"Movie - part2", stackPart{Title: "Movie", Kind: "part", Number: 2}, true},
+		{"Movie_disc1", stackPart{Title: "Movie", Kind: "disc", Number: 1}, true},
+		{"Movie.pt3", stackPart{Title: "Movie", Kind: "pt", Number: 3}, true},
+		{"Movie [CD2]", stackPart{Title: "Movie", Kind: "cd", Number: 2}, true},
+		{"Movie(DVD1)", stackPart{Title: "Movie", Kind: "dvd", Number: 1}, true},
+		{"Movie.1080p.BluRay Part 1", stackPart{Title: "Movie.1080p.BluRay", Kind: "part", Number: 1}, true},
+		{"Harry Potter and the Deathly Hallows Part 1", stackPart{}, false},
+		{"Script1", stackPart{}, false},
+		{"Abcd2", stackPart{}, false},
+		{"Movie.cd0", stackPart{}, false},
+		{"cd1", stackPart{}, false},
+		{"Movie", stackPart{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseStackPart(tt.name)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseStackPart(%q) = (%+v, %v), want (%+v, %v)", tt.name, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// newStackTestEntry 构造源路径和目标路径位于 /src、/dst 对应目录下的媒体文件条目
+func newStackTestEntry(dir, name string) FileEntry {
+	return FileEntry{
+		File:           &AListFile{Name: name},
+		FileType:       FileTypeMedia,
+		SourcePath:     filepath.Join("/src", dir, name),
+		TargetPath:     filepath.Join("/dst", dir, name),
+		NameWithoutExt: strings.TrimSuffix(name, filepath.Ext(name)),
+	}
+}
+
+func TestStackMediaEntries(t *testing.T) {
+	tests := []struct {
+		desc    string
+		mode    string
+		entries []FileEntry
+		targets []string // 每个返回条目的目标文件名
+		parts   []int    // 每个返回条目合并的其他分段数
+		merged  int
+	}{
+		{
+			desc:    "未开启多分段处理",
+			mode:    "",
+			entries: []FileEntry{newStackTestEntry("m", "Movie.cd1.mkv"), newStackTestEntry("m", "Movie.cd2.mkv")},
+			targets: []string{"Movie.cd1.mkv", "Movie.cd2.mkv"},
+			parts:   []int{0, 0},
+		},
+		{
+			desc:    "改名模式",
+			mode:    task.StackModeRename,
+			entries: []FileEntry{newStackTestEntry("m", "Movie.cd2.mkv"), newStackTestEntry("m", "Movie.cd1.mkv"), newStackTestEntry("m", "Other.mkv")},
+			targets: []string{"Movie - cd2.mkv", "Movie - cd1.mkv", "Other.mkv"},
+			parts:   []int{0, 0, 0},
+		},
+		{
+			desc:    "播放列表模式",
+			mode:    task.StackModePlaylist,
+			entries: []FileEntry{newStackTestEntry("m", "Movie.cd2.mkv"), newStackTestEntry("m", "Movie.cd1.mkv"), newStackTestEntry("m", "Movie.cd3.mkv")},
+			targets: []string{"Movie.mkv"},
+			parts:   []int{2},
+			merged:  2,
+		},
+		{
+			desc:    "分段号不连续",
+			mode:    task.StackModePlaylist,
+			entries: []FileEntry{newStackTestEntry("m", "Movie.cd1.mkv"), newStackTestEntry("m", "Movie.cd3.mkv")},
+			targets: []string{"Movie.cd1.mkv", "Movie.cd3.mkv"},
+			parts:   []int{0, 0},
+		},
+		{
+			desc:    "不同目录的同名分段",
+			mode:    task.StackModePlaylist,
+			entries: []FileEntry{newStackTestEntry("a", "Movie.cd1.mkv"), newStackTestEntry("b", "Movie.cd2.mkv")},
+			targets: []string{"Movie.cd1.mkv", "Movie.cd2.mkv"},
+			parts:   []int{0, 0},
+		},
+		{
+			desc:    "扩展名不同",
+			mode:    task.StackModePlaylist,
+			entries: []FileEntry{newStackTestEntry("m", "Movie.cd1.mkv"), newStackTestEntry("m", "Movie.cd2.mp4")},
+			targets: []string{"Movie.cd1.mkv", "Movie.cd2.mp4"},
+			parts:   []int{0, 0},
+		},
+		{
+			desc:    "只有一个分段",
+			mode:    task.StackModeRename,
+			entries: []FileEntry{newStackTestEntry("m", "Movie.cd1.mkv")},
+			targets: []string{"Movie.cd1.mkv"},
+			parts:   []int{0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, merged := stackMediaEntries(tt.entries, tt.mode)
+			if merged != tt.merged {
+				t.Errorf("merged = %d, want %d", merged, tt.merged)
+			}
+			if len(got) != len(tt.targets) {
+				t.Fatalf("got %d entries, want %d", len(got), len(tt.targets))
+			}
+			for i, entry := range got {
+				if name := filepath.Base(entry.TargetPath); name != tt.targets[i] {
+					t.Errorf("entry %d target = %q, want %q", i, name, tt.targets[i])
+				}
+				if len(entry.StackParts) != tt.parts[i] {
+					t.Errorf("entry %d stack parts = %d, want %d", i, len(entry.StackParts), tt.parts[i])
+				}
+			}
+		})
+	}
+}
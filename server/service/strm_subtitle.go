@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"go.uber.org/zap"
+)
+
+// maxReportedUnmatchedSubtitles 通知中展示的未匹配字幕文件数上限
+const maxReportedUnmatchedSubtitles = 20
+
+// subtitleDirNames 常见的字幕子目录名（小写），其中的字幕与上级目录的媒体文件匹配
+var subtitleDirNames = map[string]bool{
+	"subs":      true,
+	"sub":       true,
+	"subtitles": true,
+	"subtitle":  true,
+	"字幕":        true,
+}
+
+// subtitleTagAliases 字幕文件名中可识别的语言和标记（小写），值为写入目标文件名时使用的规范写法
+// 语言名称统一为 Emby 可识别的语言代码，forced/sdh 等标记原样保留
+var subtitleTagAliases = map[string]string{
+	"chs": "chs", "cht": "cht", "chi": "chi", "zho": "zho", "zh": "zh", "sc": "sc", "tc": "tc",
+	"gb": "gb", "big5": "big5", "zh-cn": "zh-cn", "zh-tw": "zh-tw", "zh-hk": "zh-hk", "zh-hans": "zh-hans", "zh-hant": "zh-hant",
+	"eng": "eng", "en": "en", "jpn": "jpn", "ja": "ja", "jp": "jp", "kor": "kor", "ko": "ko",
+	"fre": "fre", "fra": "fra", "fr": "fr", "ger": "ger", "deu": "deu", "de": "de", "spa": "spa", "es": "es",
+	"rus": "rus", "ru": "ru", "por": "por", "pt": "pt", "ita": "ita", "it": "it", "tha": "tha", "vie": "vie",
+	"english": "eng", "chinese": "chi", "simplified": "chs", "traditional": "cht", "japanese": "jpn", "korean": "kor",
+	"french": "fre", "german": "ger", "spanish": "spa", "russian": "rus", "portuguese": "por", "italian": "ita",
+	"简体": "chs", "简中": "chs", "繁体": "cht", "繁體": "cht", "繁中": "cht", "中文": "chi", "英文": "eng", "日文": "jpn", "韩文": "kor",
+	"forced": "forced", "sdh": "sdh", "cc": "cc", "hi": "hi", "default": "default",
+}
+
+// subtitleCandidate 待匹配的字幕文件
+type subtitleCandidate struct {
+	FileEntry
+	InSubDir  bool   // 位于 Subs/Subtitles 等字幕子目录中
+	MediaHint string // 字幕子目录下按分集划分的目录名，通常与媒体文件同名
+}
+
+// isSubtitleDir 判断目录是否为字幕子目录
+func isSubtitleDir(name string) bool {
+	return subtitleDirNames[strings.ToLower(name)]
+}
+
+// splitSubtitleTags 将字幕文件名中的标记部分拆分为规范化的标记，忽略序号（如 Subs/2_English.srt 中的 2）
+// 第二个返回值表示是否所有标记都可识别
+func splitSubtitleTags(s string) ([]string, bool) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '.' || r == '_' || r == '[' || r == ']' || r == '(' || r == ')' || unicode.IsSpace(r)
+	})
+
+	tags := make([]string, 0, len(fields))
+	known := true
+	for _, field := range fields {
+		field = strings.ToLower(strings.Trim(field, "-"))
+		if field == "" || strings.IndexFunc(field, func(r rune) bool { return !unicode.IsDigit(r) }) < 0 {
+			continue
+		}
+		// chs&eng、简体&英文 等双语标记逐个规范化
+		parts := strings.Split(field, "&")
+		for i, part := range parts {
+			if alias, ok := subtitleTagAliases[part]; ok {
+				parts[i] = alias
+			} else {
+				known = false
+			}
+		}
+		tags = append(tags, strings.Join(parts, "&"))
+	}
+	return tags, known && len(tags) > 0
+}
+
+// matchSubtitleMedia 为字幕文件查找对应的媒体文件，返回媒体文件和规范化后的标记，未匹配时返回 nil
+// 依次尝试：字幕子目录下的分集目录名与媒体文件同名；字幕文件名以媒体文件名为前缀（取最长的匹配，
+// 避免 Movie 与 Movie 2 混淆）；目录中只有一个媒体文件，且字幕位于字幕子目录或文件名只包含语言标记
+func matchSubtitleMedia(c subtitleCandidate, medias []FileEntry) (*FileEntry, []string) {
+	base := c.NameWithoutExt
+
+	if c.MediaHint != "" {
+		for i := range medias {
+			if strings.EqualFold(medias[i].NameWithoutExt, c.MediaHint) {
+				tags, _ := splitSubtitleTags(base)
+				return &medias[i], tags
+			}
+		}
+	}
+
+	if best := mediaNamePrefix(base, medias); best != nil {
+		tags, _ := splitSubtitleTags(base[len(best.NameWithoutExt):])
+		return best, tags
+	}
+
+	if len(medias) == 1 {
+		tags, tagOnly := splitSubtitleTags(base)
+		if c.InSubDir || tagOnly {
+			return &medias[0], tags
+		}
+	}
+	return nil, nil
+}
+
+// mediaNamePrefix 查找以其文件名（不含扩展名）为前缀的媒体文件，前缀之后必须是分隔符，多个匹配时取最长的
+func mediaNamePrefix(base string, medias []FileEntry) *FileEntry {
+	var best *FileEntry
+	for i := range medias {
+		name := medias[i].NameWithoutExt
+		if len(name) > len(base) || !strings.EqualFold(base[:len(name)], name) {
+			continue
+		}
+		// 前缀之后必须是分隔符，Movie.srt 不能匹配 Movies.mkv
+		if rest := base[len(name):]; rest != "" && !strings.ContainsRune(".-_ [(", rune(rest[0])) {
+			continue
+		}
+		if best == nil || len(name) > len(best.NameWithoutExt) {
+			best = &medias[i]
+		}
+	}
+	return best
+}
+
+// subtitleTargetPath 计算字幕的目标路径：<STRM 文件名去掉 .strm>.<标记>.<扩展名>，与 STRM 文件放在同一目录，便于 Emby 识别
+func subtitleTargetPath(media *FileEntry, tags []string, subtitleName string, strmConfig *StrmConfig) string {
+	strmFilePath := buildStrmFilePath(strmConfig, media.TargetPath)
+	name := strings.TrimSuffix(filepath.Base(strmFilePath), ".strm")
+	if len(tags) > 0 {
+		name += "." + strings.Join(tags, ".")
+	}
+	return filepath.Join(filepath.Dir(strmFilePath), name+filepath.Ext(subtitleName))
+}
+
+// subtitleDirFile 字幕子目录中的文件
+type subtitleDirFile struct {
+	File       AListFile
+	SourcePath string
+	MediaHint  string // 所在的分集目录名，直接位于字幕子目录中时为空
+}
+
+// listSubtitleDir 列出字幕子目录中的文件，按分集划分的下一级目录（Subs/<分集名>/*.srt）也一并列出
+func (s *generatorRun) listSubtitleDir(ctx context.Context, dirPath string, dirInfo *AListFile, provider, mediaHint string) ([]subtitleDirFile, error) {
+	files, _, err := s.alist.ListFiles(ctx, dirPath, ListOptions{
+		Password: s.passwords.lookup(dirPath),
+		Refresh:  s.refresh.shouldRefresh(dirInfo),
+		Provider: provider,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取字幕目录文件列表失败 [%s]: %w", dirPath, err)
+	}
+
+	var result []subtitleDirFile
+	for i := range files {
+		file := files[i]
+		sourcePath := filepath.Join(dirPath, file.Name)
+		if !file.IsDir {
+			result = append(result, subtitleDirFile{File: file, SourcePath: sourcePath, MediaHint: mediaHint})
+			continue
+		}
+		// 只展开一层
+		if mediaHint != "" {
+			continue
+		}
+		nested, err := s.listSubtitleDir(ctx, sourcePath, &file, provider, file.Name)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, nested...)
+	}
+	return result, nil
+}
+
+// matchSubtitles 将字幕文件与当前目录的媒体文件匹配，并改名为对应 STRM 文件的字幕名
+// 未匹配或目标文件名冲突的字幕不会下载，计入未匹配字幕并在任务日志和通知中报告
+func (s *generatorRun) matchSubtitles(candidates []subtitleCandidate, medias []FileEntry, strmConfig *StrmConfig) []FileEntry {
+	var matched []FileEntry
+	targets := make(map[string]string, len(candidates))
+	for _, c := range candidates {
+		reason := "没有对应的媒体文件"
+		if media, tags := matchSubtitleMedia(c, medias); media != nil {
+			targetPath := subtitleTargetPath(media, tags, c.File.Name, strmConfig)
+			other, exists := targets[targetPath]
+			if !exists {
+				targets[targetPath] = c.SourcePath
+				entry := c.FileEntry
+				entry.TargetPath = targetPath
+				matched = append(matched, entry)
+				continue
+			}
+			reason = "与 " + other + " 的目标文件名冲突"
+		}
+
+		s.logger.Warn("字幕文件未匹配到媒体文件",
+			zap.String("path", c.SourcePath),
+			zap.String("reason", reason))
+		s.stats.Mutex.Lock()
+		s.stats.SubtitleUnmatched++
+		if len(s.stats.UnmatchedSubtitles) < maxReportedUnmatchedSubtitles {
+			s.stats.UnmatchedSubtitles = append(s.stats.UnmatchedSubtitles, c.SourcePath)
+		}
+		s.stats.Mutex.Unlock()
+	}
+	return matched
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newSubtitleTestEntry 构造只包含文件名的条目
+func newSubtitleTestEntry(name string) FileEntry {
+	return FileEntry{
+		File:           &AListFile{Name: name},
+		SourcePath:     filepath.Join("/src", name),
+		TargetPath:     filepath.Join("/dst", name),
+		NameWithoutExt: strings.TrimSuffix(name, filepath.Ext(name)),
+	}
+}
+
+func TestSplitSubtitleTags(t *testing.T) {
+	tests := []struct {
+		s     string
+		tags  []string
+		known bool
+	}{
+		{".chs", []string{"chs"}, true},
+		{".zh-CN.forced", []string{"zh-cn", "forced"}, true},
+		{"2_English", []string{"eng"}, true},
+		{"[简体&英文]", []string{"chs&eng"}, true},
+		{"Chinese (Traditional)", []string{"chi", "cht"}, true},
+		{".default.foo", []string{"default", "foo"}, false},
+		{".2", []string{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			tags, known := splitSubtitleTags(tt.s)
+			if !slices.Equal(tags, tt.tags) || known != tt.known {
+				t.Errorf("splitSubtitleTags(%q) = (%q, %v), want (%q, %v)", tt.s, tags, known, tt.tags, tt.known)
+			}
+		})
+	}
+}
+
+func TestMediaNamePrefix(t *testing.T) {
+	medias := []FileEntry{newSubtitleTestEntry("Movie.mkv"), newSubtitleTestEntry("Movie 2.mkv"), newSubtitleTestEntry("Show.S01E01.mkv")}
+	tests := []struct {
+		base string
+		want string // 匹配到的媒体文件名，未匹配时为空
+	}{
+		{"Movie.chs", "Movie.mkv"},
+		{"Movie", "Movie.mkv"},
+		{"movie.eng", "Movie.mkv"},
+		{"Movie 2.chs", "Movie 2.mkv"},
+		{"Movies.chs", ""},
+		{"Show.S01E01 [chs]", "Show.S01E01.mkv"},
+		{"Show.S01E02.chs", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.base, func(t *testing.T) {
+			got := ""
+			if media := mediaNamePrefix(tt.base, medias); media != nil {
+				got = media.File.Name
+			}
+			if got != tt.want {
+				t.Errorf("mediaNamePrefix(%q) = %q, want %q", tt.base, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchSubtitleMedia(t *testing.T) {
+	medias := []FileEntry{newSubtitleTestEntry("Movie.mkv"), newSubtitleTestEntry("Movie 2.mkv")}
+	single := medias[:1]
+	tests := []struct {
+		desc      string
+		candidate subtitleCandidate
+		medias    []FileEntry
+		want      string
+		tags      []string
+	}{
+		{"文件名前缀", subtitleCandidate{FileEntry: newSubtitleTestEntry("Movie 2.chs.srt")}, medias, "Movie 2.mkv", []string{"chs"}},
+		{"分集目录名", subtitleCandidate{FileEntry: newSubtitleTestEntry("2_English.srt"), InSubDir: true, MediaHint: "Movie 2"}, medias, "Movie 2.mkv", []string{"eng"}},
+		{"唯一媒体文件的语言标记", subtitleCandidate{FileEntry: newSubtitleTestEntry("chs.srt")}, single, "Movie.mkv", []string{"chs"}},
+		{"唯一媒体文件的字幕子目录", subtitleCandidate{FileEntry: newSubtitleTestEntry("Other.srt"), InSubDir: true}, single, "Movie.mkv", []string{"other"}},
+		{"唯一媒体文件的无关字幕", subtitleCandidate{FileEntry: newSubtitleTestEntry("Other.srt")}, single, "", nil},
+		{"多个媒体文件无法确定", subtitleCandidate{FileEntry: newSubtitleTestEntry("chs.srt")}, medias, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			media, tags := matchSubtitleMedia(tt.candidate, tt.medias)
+			got := ""
+			if media != nil {
+				got = media.File.Name
+			}
+			if got != tt.want || !slices.Equal(tags, tt.tags) {
+				t.Errorf("matchSubtitleMedia(%q) = (%q, %q), want (%q, %q)", tt.candidate.File.Name, got, tags, tt.want, tt.tags)
+			}
+		})
+	}
+}
+
+func TestSubtitleTargetPath(t *testing.T) {
+	media := newSubtitleTestEntry("Movie.mkv")
+	tests := []struct {
+		desc          string
+		tags          []string
+		replaceSuffix bool
+		want          string
+	}{
+		{"替换后缀", []string{"chs", "forced"}, true, filepath.Join("/dst", "Movie.chs.forced.srt")},
+		{"保留后缀", []string{"eng"}, false, filepath.Join("/dst", "Movie.mkv.eng.srt")},
+		{"没有标记", nil, true, filepath.Join("/dst", "Movie.srt")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got := subtitleTargetPath(&media, tt.tags, "Movie.chs.srt", &StrmConfig{ReplaceSuffix: tt.replaceSuffix})
+			if got != tt.want {
+				t.Errorf("subtitleTargetPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// StrmTemplateData STRM 内容模板可用字段
+// 示例：
+//
+//	{{.URL}}                                   默认的 AList /d/ 直链
+//	{{.Domain}}/p{{.EncodedPath}}?sign={{.Sign}}  AList /p/ 代理链接
+//	/mnt/alist{{.Path}}                        rclone 等本地挂载路径
+//	{{.Path}}                                  原始 AList 路径（emby2alist 等重定向代理）
+type StrmTemplateData struct {
+	Path        string // 源文件完整路径（未编码），例如 /movies/电影.mkv
+	EncodedPath string // 逐段 URL 编码后的源文件完整路径
+	Dir         string // 源文件所在目录（未编码）
+	Name        string // 文件名
+	Sign        string // AList 签名
+	Size        int64  // 文件大小（字节）
+	Sha1        string // SHA1 哈希（存储不提供时为空）
+	Domain      string // AList 访问域名（优先 Domain，否则为 Host），不含末尾斜杠
+	URL         string // 默认生成的 /d/ 直链
+}
+
+// strmTemplateFuncs 模板中可用的辅助函数
+var strmTemplateFuncs = template.FuncMap{
+	"pathEscape":  escapePathSegments,
+	"queryEscape": url.QueryEscape,
+	"trimPrefix":  strings.TrimPrefix,
+	"trimSuffix":  strings.TrimSuffix,
+	"replace":     strings.ReplaceAll,
+}
+
+// parseStrmTemplate 解析 STRM 内容模板，模板为空时返回 nil 表示使用默认直链
+func parseStrmTemplate(text string) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	return template.New("strm").Funcs(strmTemplateFuncs).Parse(text)
+}
+
+// ValidateStrmTemplate 校验 STRM 内容模板，使用示例数据试渲染以提前发现字段名错误
+func ValidateStrmTemplate(text string) error {
+	tmpl, err := parseStrmTemplate(text)
+	if err != nil {
+		return fmt.Errorf("STRM 内容模板格式错误: %w", err)
+	}
+	if tmpl == nil {
+		return nil
+	}
+	sample := &StrmTemplateData{
+		Path:        "/movies/sample.mkv",
+		EncodedPath: "/movies/sample.mkv",
+		Dir:         "/movies",
+		Name:        "sample.mkv",
+		Domain:      "http://localhost:5244",
+		URL:         "http://localhost:5244/d/movies/sample.mkv",
+	}
+	if _, err := renderStrmTemplate(tmpl, sample); err != nil {
+		return fmt.Errorf("STRM 内容模板渲染失败: %w", err)
+	}
+	return nil
+}
+
+// renderStrmTemplate 渲染 STRM 内容，去除首尾空白后不能为空
+func renderStrmTemplate(tmpl *template.Template, data *StrmTemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	content := strings.TrimSpace(buf.String())
+	if content == "" {
+		return "", fmt.Errorf("模板渲染结果为空")
+	}
+	return content, nil
+}
+
+// newStrmTemplateData 根据 AList 文件信息构建模板数据
+func newStrmTemplateData(file *AListFile, sourcePath, domain, fileURL string) *StrmTemplateData {
+	fullPath := path.Join("/", sourcePath)
+	return &StrmTemplateData{
+		Path:        fullPath,
+		EncodedPath: escapePathSegments(fullPath),
+		Dir:         path.Dir(fullPath),
+		Name:        file.Name,
+		Sign:        file.Sign,
+		Size:        file.Size,
+		Sha1:        file.HashInfo.Sha1,
+		Domain:      domain,
+		URL:         fileURL,
+	}
+}
+
+// escapePathSegments 对路径逐段进行 URL 编码，保留分隔符
+func escapePathSegments(p string) string {
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
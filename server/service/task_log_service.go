@@ -71,7 +71,7 @@ func (s *TaskLogService) UpdateTaskLog(req *taskLogRequest.TaskLogUpdateReq) err
 	if req.Status != "" {
 		updates["status"] = req.Status
 		// 如果状态变为完成或失败，设置结束时间
-		if req.Status == "completed" || req.Status == "failed" || req.Status == "cancelled" {
+		if req.Status == "completed" || req.Status == "failed" || req.Status == "cancelled" || req.Status == "partial" || req.Status == "interrupted" {
 			now := time.Now()
 			updates["end_time"] = now
 			// 如果没有传入duration，计算持续时间
@@ -139,8 +139,14 @@ func (s *TaskLogService) GetTaskLogInfo(req *taskLogRequest.TaskLogInfoReq) (*ta
 		return nil, fmt.Errorf("任务日志不存在")
 	}
 
+	failures, err := repository.TaskLogFailure.ListByTaskLogID(taskLog.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询任务失败记录失败: %v", err)
+	}
+
 	return &taskLogResponse.TaskLogInfoResp{
-		TaskLog: *taskLog,
+		TaskLog:  *taskLog,
+		Failures: failures,
 	}, nil
 }
 
@@ -1,20 +1,70 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	taskResponse "github.com/MccRay-s/alist2strm/model/task/response"
 	"github.com/MccRay-s/alist2strm/repository"
 	"github.com/MccRay-s/alist2strm/utils"
 )
 
+// 任务入队来源
+const (
+	QueueSourceCron    = "cron"    // 定时任务触发
+	QueueSourceManual  = "manual"  // 手动执行
+	QueueSourceWebhook = "webhook" // Webhook 触发
+)
+
+// 任务优先级，数值越大越先执行
+const (
+	QueuePriorityCron   = 0  // 定时任务默认优先级
+	QueuePriorityManual = 10 // 手动执行默认优先级
+)
+
+// QueueItem 队列中的任务项
+type QueueItem struct {
+	TaskID     uint
+	Priority   int       // 优先级，数值越大越靠前
+	Source     string    // 入队来源：cron、manual、webhook
+	SubPath    string    // 扫描范围（相对任务源路径），为空表示完整执行
+	EnqueuedAt time.Time // 入队时间
+
+	host      string             // 任务所用 AList 主机标识，入队时确定
+	hostSlots int                // 该主机允许同时执行的任务数
+	done      chan<- queueResult // 同步执行时接收执行结果，异步执行为 nil
+}
+
+// queueResult 同步执行的任务的执行结果
+type queueResult struct {
+	resp *taskResponse.TaskExecuteResp
+	err  error
+}
+
+// RunningItem 正在执行的任务
+type RunningItem struct {
+	QueueItem
+	Host      string    // AList 主机标识
+	StartedAt time.Time // 开始执行时间
+}
+
 // TaskQueue 任务队列
+// 新任务按优先级插入（同优先级按入队时间排列），之后可手动上移、下移调整顺序；
+// 执行器按队列顺序取出任务并行执行，同时执行的任务数受全局任务槽位和单个 AList 主机的槽位限制，
+// 超出主机限制的任务会让位给队列中后面使用其他主机的任务
 type TaskQueue struct {
-	queue    []uint        // 任务ID队列
-	running  bool          // 执行器是否正在运行
-	mutex    sync.Mutex    // 互斥锁
-	cond     *sync.Cond    // 条件变量，用于任务通知
-	shutdown chan struct{} // 关闭信号
+	queue       []QueueItem           // 等待执行的任务，按执行顺序排列
+	resumes     map[uint]uint         // 需要断点续传的任务，任务ID -> 继续执行的任务日志ID
+	running     map[uint]*RunningItem // 正在执行的任务
+	hostRunning map[string]int        // 各 AList 主机上正在执行的任务数
+	mutex       sync.Mutex            // 互斥锁
+	cond        *sync.Cond            // 条件变量，用于任务通知
+	shutdown    chan struct{}         // 关闭信号
 }
 
 // 全局队列单例
@@ -28,10 +78,12 @@ func GetTaskQueue() *TaskQueue {
 	taskQueueOnce.Do(func() {
 		// 创建一个互斥锁
 		taskQueue = &TaskQueue{
-			queue:    make([]uint, 0),
-			running:  false,
-			mutex:    sync.Mutex{},
-			shutdown: make(chan struct{}),
+			queue:       make([]QueueItem, 0),
+			resumes:     make(map[uint]uint),
+			running:     make(map[uint]*RunningItem),
+			hostRunning: make(map[string]int),
+			mutex:       sync.Mutex{},
+			shutdown:    make(chan struct{}),
 		}
 		// 使用已创建的互斥锁初始化条件变量
 		taskQueue.cond = sync.NewCond(&taskQueue.mutex)
@@ -43,11 +95,6 @@ func GetTaskQueue() *TaskQueue {
 func StartTaskQueue() {
 	tq := GetTaskQueue()
 
-	// 确保执行器状态正确
-	tq.mutex.Lock()
-	tq.running = false
-	tq.mutex.Unlock()
-
 	// 启动任务执行器
 	go tq.executor()
 
@@ -62,67 +109,141 @@ func StartTaskQueue() {
 	}()
 }
 
-// AddTask 添加任务到队列
-func (tq *TaskQueue) AddTask(taskID uint) {
-	utils.Info("正在尝试添加任务到队列", "task_id", taskID)
+// AddTask 添加完整执行的任务到队列
+func (tq *TaskQueue) AddTask(taskID uint, source string, priority int) {
+	tq.Enqueue(QueueItem{TaskID: taskID, Source: source, Priority: priority})
+}
+
+// Enqueue 添加任务到队列，按优先级插入到同优先级任务之后
+// 任务已在队列中时不重复添加：扫描范围合并为两者的共同上级目录，若新的优先级更高则提升优先级并重新排位
+func (tq *TaskQueue) Enqueue(newItem QueueItem) {
+	taskID, priority := newItem.TaskID, newItem.Priority
+	utils.Info("正在尝试添加任务到队列", "task_id", taskID, "source", newItem.Source, "priority", priority, "sub_path", newItem.SubPath)
+
+	// 在加锁前确定任务所用主机，避免调度时在锁内查询数据库
+	newItem.host, newItem.hostSlots = taskHost(GetAListService(), taskID)
 
 	tq.mutex.Lock()
 	defer tq.mutex.Unlock()
 
 	// 检查任务是否已在队列中
-	for _, id := range tq.queue {
-		if id == taskID {
-			utils.Info("任务已在队列中，跳过添加", "task_id", taskID)
+	for i, item := range tq.queue {
+		if item.TaskID == taskID {
+			subPath := mergeSubPaths(item.SubPath, newItem.SubPath)
+			if priority <= item.Priority {
+				tq.queue[i].SubPath = subPath
+				utils.Info("任务已在队列中，跳过添加", "task_id", taskID, "sub_path", subPath)
+				return
+			}
+			tq.queue = append(tq.queue[:i], tq.queue[i+1:]...)
+			item.Priority = priority
+			item.SubPath = subPath
+			tq.insert(item)
+			utils.Info("任务已在队列中，提升优先级", "task_id", taskID, "priority", priority, "sub_path", subPath)
+			tq.cond.Signal()
 			return
 		}
 	}
 
 	// 检查执行器状态
-	executorRunning := tq.running
+	runningCount := len(tq.running)
 	queueLen := len(tq.queue)
 
 	// 添加到队列
-	tq.queue = append(tq.queue, taskID)
-	utils.Info("任务已添加到队列", "task_id", taskID, "queue_length", len(tq.queue), "executor_running", executorRunning)
+	newItem.EnqueuedAt = time.Now()
+	position := tq.insert(newItem)
+	utils.Info("任务已添加到队列", "task_id", taskID, "position", position+1, "queue_length", len(tq.queue), "running_count", runningCount)
 
 	// 通知执行器有新任务
 	utils.Info("发送信号通知执行器处理新任务", "task_id", taskID, "queue_before", queueLen, "queue_after", len(tq.queue))
 	tq.cond.Signal()
 }
 
+// EnqueueAndWait 添加同步执行的任务到队列并等待执行结束
+// 任务已在队列中或正在执行时返回错误；任务在执行前被移出队列时返回 context.Canceled
+func (tq *TaskQueue) EnqueueAndWait(item QueueItem) (*taskResponse.TaskExecuteResp, error) {
+	done := make(chan queueResult, 1)
+	item.host, item.hostSlots = taskHost(GetAListService(), item.TaskID)
+
+	tq.mutex.Lock()
+	if _, running := tq.running[item.TaskID]; running {
+		tq.mutex.Unlock()
+		return nil, errors.New("任务正在运行中")
+	}
+	for _, queued := range tq.queue {
+		if queued.TaskID == item.TaskID {
+			tq.mutex.Unlock()
+			return nil, errors.New("任务已在执行队列中")
+		}
+	}
+	item.done = done
+	item.EnqueuedAt = time.Now()
+	position := tq.insert(item)
+	utils.Info("同步执行任务已添加到队列", "task_id", item.TaskID, "position", position+1, "queue_length", len(tq.queue))
+	tq.cond.Signal()
+	tq.mutex.Unlock()
+
+	result := <-done
+	return result.resp, result.err
+}
+
+// AddResumeTask 添加断点续传任务到队列
+func (tq *TaskQueue) AddResumeTask(taskID, fromLogID uint, priority int) {
+	tq.mutex.Lock()
+	tq.resumes[taskID] = fromLogID
+	tq.mutex.Unlock()
+
+	tq.AddTask(taskID, QueueSourceManual, priority)
+}
+
+// mergeSubPaths 合并同一任务的两个扫描范围，返回能覆盖两者的最深共同目录，为空表示完整执行
+func mergeSubPaths(a, b string) string {
+	if a == "" || b == "" {
+		return ""
+	}
+	partsA, partsB := strings.Split(a, "/"), strings.Split(b, "/")
+	common := make([]string, 0, len(partsA))
+	for i := 0; i < len(partsA) && i < len(partsB) && partsA[i] == partsB[i]; i++ {
+		common = append(common, partsA[i])
+	}
+	return strings.Join(common, "/")
+}
+
+// insert 将任务插入到第一个优先级更低的任务之前，返回插入位置，调用方需持有锁
+func (tq *TaskQueue) insert(item QueueItem) int {
+	position := len(tq.queue)
+	for i, queued := range tq.queue {
+		if queued.Priority < item.Priority {
+			position = i
+			break
+		}
+	}
+	tq.queue = append(tq.queue, QueueItem{})
+	copy(tq.queue[position+1:], tq.queue[position:])
+	tq.queue[position] = item
+	return position
+}
+
 // executor 任务执行器
 func (tq *TaskQueue) executor() {
 	utils.Info("任务执行器已启动，等待任务...")
 
 	for {
-		var taskID uint
-		var hasTask bool
-
 		// 获取任务
 		tq.mutex.Lock()
-		queueLen := len(tq.queue)
-
-		utils.Info("执行器检查队列", "queue_length", queueLen, "running_state", tq.running)
-
-		// 等待队列中有任务
-		if queueLen == 0 {
-			utils.Info("队列为空，执行器进入等待状态")
-
-			// 使用条件变量等待任务或关闭信号
-			waitStart := time.Now()
-
-			// 创建一个通道，用于监听关闭信号
-			shutdownCh := tq.shutdown
+		item, host, ok := tq.nextRunnable()
+		for !ok {
+			utils.Info("没有可执行的任务，执行器进入等待状态", "queue_length", len(tq.queue), "running_count", len(tq.running))
 
+			// 使用条件变量等待新任务、任务结束或关闭信号
 			// 在条件变量等待期间已经持有锁，Wait会释放锁并在返回前重新获取锁
+			waitStart := time.Now()
 			tq.cond.Wait()
-
-			waitDuration := time.Since(waitStart)
-			utils.Info("执行器收到信号，退出等待状态", "wait_duration", waitDuration.String(), "queue_length", len(tq.queue))
+			utils.Info("执行器收到信号，退出等待状态", "wait_duration", time.Since(waitStart).String(), "queue_length", len(tq.queue))
 
 			// 检查是否收到关闭信号 (需要在重新获取锁之后检查，避免竞争)
 			select {
-			case <-shutdownCh:
+			case <-tq.shutdown:
 				// 收到关闭信号，释放锁并退出
 				utils.Info("执行器收到关闭信号，退出执行")
 				tq.mutex.Unlock()
@@ -130,70 +251,119 @@ func (tq *TaskQueue) executor() {
 			default:
 				// 没有关闭信号，继续处理
 			}
+			item, host, ok = tq.nextRunnable()
+		}
+
+		taskID := item.TaskID
+		opts := GenerateOptions{ResumeFromLogID: tq.resumes[taskID], SubPath: item.SubPath, Source: item.Source}
+		delete(tq.resumes, taskID)
+		tq.running[taskID] = &RunningItem{QueueItem: item, Host: host, StartedAt: time.Now()}
+		tq.hostRunning[host]++
+		utils.Info("任务占用执行槽位", "task_id", taskID, "host", host, "running_count", len(tq.running), "host_running_count", tq.hostRunning[host])
+		tq.mutex.Unlock()
+
+		// 在单独的goroutine中执行任务，执行器继续调度其他任务
+		go tq.runTask(item, host, opts)
+	}
+}
+
+// nextRunnable 按队列顺序查找可以立即执行的任务并移出队列，调用方需持有锁
+// 全局槽位已满时返回 false；任务所在主机槽位已满或同一任务仍在执行时跳过该任务
+func (tq *TaskQueue) nextRunnable() (QueueItem, string, bool) {
+	taskSlots := defaultTaskConcurrency
+	if alistService := GetAListService(); alistService != nil {
+		taskSlots = alistService.GetTaskConcurrency()
+	}
+	if len(tq.running) >= taskSlots {
+		return QueueItem{}, "", false
+	}
+
+	for i, item := range tq.queue {
+		if _, running := tq.running[item.TaskID]; running {
+			continue
 		}
+		if tq.hostRunning[item.host] >= item.hostSlots {
+			continue
+		}
+		tq.queue = append(tq.queue[:i], tq.queue[i+1:]...)
+		return item, item.host, true
+	}
+	return QueueItem{}, "", false
+}
 
-		// 取出队列头部的任务
-		if len(tq.queue) > 0 {
-			taskID = tq.queue[0]
-			tq.queue = tq.queue[1:]
-			tq.running = true
-			hasTask = true
+// taskHost 获取任务所用 AList 配置的主机标识和该主机允许同时执行的任务数，在任务入队时调用
+// 配置不可用时返回空主机和默认值，任务执行时再报告具体错误
+func taskHost(alistService *AListService, taskID uint) (string, int) {
+	if alistService == nil {
+		return "", defaultHostConcurrency
+	}
+	var profileID uint
+	if t, err := repository.Task.GetByID(taskID); err == nil && t != nil {
+		profileID = t.AListProfileID
+	}
+	client, err := alistService.GetClient(profileID)
+	if err != nil {
+		return "", defaultHostConcurrency
+	}
+	return client.HostKey(), client.HostConcurrency()
+}
+
+// runTask 执行任务并在结束后释放槽位，同步执行的任务将结果发送给等待方
+func (tq *TaskQueue) runTask(item QueueItem, host string, opts GenerateOptions) {
+	id := item.TaskID
+	defer func() {
+		tq.mutex.Lock()
+		delete(tq.running, id)
+		tq.hostRunning[host]--
+		if tq.hostRunning[host] <= 0 {
+			delete(tq.hostRunning, host)
 		}
+		// 槽位释放，唤醒执行器调度等待中的任务
+		tq.cond.Signal()
 		tq.mutex.Unlock()
+	}()
 
-		// 如果有任务，则执行
-		if hasTask {
-			// 创建一个单独的goroutine来执行任务，避免阻塞队列处理
-			go func(id uint) {
-				// 执行任务（不在锁内执行，避免阻塞其他操作）
-				utils.Info("开始执行任务", "task_id", id)
-
-				// 记录开始时间
-				startTime := time.Now()
-
-				// 执行任务
-				_, err := Task.ExecuteStrmGeneration(id)
-
-				// 计算持续时间（秒）
-				endTime := time.Now()
-				durationSeconds := int64(endTime.Sub(startTime).Seconds())
-
-				// 获取最新的任务日志，更新持续时间
-				taskLogs, total, logErr := repository.TaskLog.GetLatestByTaskID(id, 1)
-				if logErr == nil && total > 0 && len(taskLogs) > 0 {
-					latestLog := taskLogs[0]
-
-					// 更新持续时间
-					updateData := map[string]interface{}{
-						"duration": durationSeconds,
-					}
-
-					if updateErr := repository.TaskLog.UpdatePartial(latestLog.ID, updateData); updateErr != nil {
-						utils.Error("更新任务日志持续时间失败", "task_id", id, "log_id", latestLog.ID, "error", updateErr.Error())
-					} else {
-						utils.Info("更新任务日志持续时间", "task_id", id, "duration", durationSeconds)
-					}
-				}
-
-				// 任务已经在ExecuteStrmGeneration方法中设置和重置了运行状态
-
-				// 记录执行结果
-				if err != nil {
-					utils.Error("任务执行失败", "task_id", id, "error", err.Error(), "duration", durationSeconds)
-				} else {
-					utils.Info("任务执行成功", "task_id", id, "duration", durationSeconds)
-				}
-
-				// 更新状态
-				tq.mutex.Lock()
-				tq.running = false
-				tq.mutex.Unlock()
-			}(taskID)
+	// 执行任务（不在锁内执行，避免阻塞其他操作）
+	utils.Info("开始执行任务", "task_id", id)
 
-			// 短暂休息，避免CPU占用过高和立即处理下一个任务
-			time.Sleep(100 * time.Millisecond)
+	// 记录开始时间
+	startTime := time.Now()
+
+	// 执行任务
+	resp, err := Task.runStrmGeneration(id, opts)
+	if item.done != nil {
+		item.done <- queueResult{resp: resp, err: err}
+	}
+
+	// 计算持续时间（秒）
+	endTime := time.Now()
+	durationSeconds := int64(endTime.Sub(startTime).Seconds())
+
+	// 获取最新的任务日志，更新持续时间
+	taskLogs, total, logErr := repository.TaskLog.GetLatestByTaskID(id, 1)
+	if logErr == nil && total > 0 && len(taskLogs) > 0 {
+		latestLog := taskLogs[0]
+
+		// 更新持续时间
+		updateData := map[string]interface{}{
+			"duration": durationSeconds,
+		}
+
+		if updateErr := repository.TaskLog.UpdatePartial(latestLog.ID, updateData); updateErr != nil {
+			utils.Error("更新任务日志持续时间失败", "task_id", id, "log_id", latestLog.ID, "error", updateErr.Error())
+		} else {
+			utils.Info("更新任务日志持续时间", "task_id", id, "duration", durationSeconds)
 		}
 	}
+
+	// 任务已经在runStrmGeneration方法中设置和重置了运行状态
+
+	// 记录执行结果
+	if err != nil {
+		utils.Error("任务执行失败", "task_id", id, "error", err.Error(), "duration", durationSeconds)
+	} else {
+		utils.Info("任务执行成功", "task_id", id, "duration", durationSeconds)
+	}
 }
 
 // IsTaskInQueue 检查任务是否在队列中
@@ -201,19 +371,26 @@ func (tq *TaskQueue) IsTaskInQueue(taskID uint) bool {
 	tq.mutex.Lock()
 	defer tq.mutex.Unlock()
 
-	for _, id := range tq.queue {
-		if id == taskID {
+	for _, item := range tq.queue {
+		if item.TaskID == taskID {
 			return true
 		}
 	}
 	return false
 }
 
-// IsExecutorRunning 检查执行器是否正在运行
+// IsExecutorRunning 检查执行器是否有正在执行的任务
 func (tq *TaskQueue) IsExecutorRunning() bool {
 	tq.mutex.Lock()
 	defer tq.mutex.Unlock()
-	return tq.running
+	return len(tq.running) > 0
+}
+
+// GetRunningCount 获取正在执行的任务数
+func (tq *TaskQueue) GetRunningCount() int {
+	tq.mutex.Lock()
+	defer tq.mutex.Unlock()
+	return len(tq.running)
 }
 
 // GetQueueLength 获取队列长度
@@ -228,18 +405,87 @@ func (tq *TaskQueue) RemoveTaskFromQueue(taskID uint) bool {
 	tq.mutex.Lock()
 	defer tq.mutex.Unlock()
 
-	for i, id := range tq.queue {
-		if id == taskID {
-			// 移除任务
+	for i, item := range tq.queue {
+		if item.TaskID == taskID {
+			// 移除任务，同步执行的等待方按取消处理
 			tq.queue = append(tq.queue[:i], tq.queue[i+1:]...)
+			delete(tq.resumes, taskID)
+			if item.done != nil {
+				item.done <- queueResult{err: fmt.Errorf("任务已从队列中移除: %w", context.Canceled)}
+			}
 			return true
 		}
 	}
 	return false
 }
 
+// MoveTask 将任务在队列中上移（offset 为负）或下移（offset 为正），到达队首或队尾时停止
+// 移动后任务的优先级调整为与相邻任务一致，避免之后插入的任务打乱手动调整的顺序
+func (tq *TaskQueue) MoveTask(taskID uint, offset int) bool {
+	tq.mutex.Lock()
+	defer tq.mutex.Unlock()
+
+	from := -1
+	for i, item := range tq.queue {
+		if item.TaskID == taskID {
+			from = i
+			break
+		}
+	}
+	if from < 0 {
+		return false
+	}
+
+	to := from + offset
+	if to < 0 {
+		to = 0
+	}
+	if to > len(tq.queue)-1 {
+		to = len(tq.queue) - 1
+	}
+	if to == from {
+		return true
+	}
+
+	item := tq.queue[from]
+	item.Priority = tq.queue[to].Priority
+	if to < from {
+		copy(tq.queue[to+1:from+1], tq.queue[to:from])
+	} else {
+		copy(tq.queue[from:to], tq.queue[from+1:to+1])
+	}
+	tq.queue[to] = item
+
+	// 顺序调整后可能有任务可以立即执行（例如主机槽位限制不同）
+	tq.cond.Signal()
+	return true
+}
+
+// Snapshot 获取正在执行（按开始时间排列）和等待执行（按执行顺序排列）的任务
+func (tq *TaskQueue) Snapshot() ([]RunningItem, []QueueItem) {
+	tq.mutex.Lock()
+	defer tq.mutex.Unlock()
+
+	running := make([]RunningItem, 0, len(tq.running))
+	for _, item := range tq.running {
+		running = append(running, *item)
+	}
+	sort.Slice(running, func(i, j int) bool {
+		return running[i].StartedAt.Before(running[j].StartedAt)
+	})
+
+	queued := make([]QueueItem, len(tq.queue))
+	copy(queued, tq.queue)
+	return running, queued
+}
+
 // Shutdown 关闭任务队列
 func (tq *TaskQueue) Shutdown() {
 	close(tq.shutdown)
+
+	// 唤醒等待中的执行器，使其检查关闭信号
+	tq.mutex.Lock()
+	tq.cond.Broadcast()
+	tq.mutex.Unlock()
 	utils.Info("任务队列关闭")
 }
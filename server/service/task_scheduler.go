@@ -1,9 +1,11 @@
 package service
 
 import (
+	"context"
 	"sync"
 	"time"
 
+	fileHistoryRequest "github.com/MccRay-s/alist2strm/model/filehistory/request"
 	"github.com/MccRay-s/alist2strm/model/task"
 	"github.com/MccRay-s/alist2strm/repository"
 	"github.com/MccRay-s/alist2strm/utils"
@@ -14,6 +16,8 @@ import (
 type TaskScheduler struct {
 	cron      *cron.Cron
 	entryIDs  map[uint]cron.EntryID
+	resignIDs map[uint]cron.EntryID // 任务ID -> 定时重新签名的调度项
+	checkIDs  map[uint]cron.EntryID // 任务ID -> 定时链接检查的调度项
 	taskMutex sync.RWMutex
 }
 
@@ -30,6 +34,8 @@ func GetTaskScheduler() *TaskScheduler {
 			// 使用标准的 5 字段 Cron 格式 (分、时、日、月、周)，而不是 6 字段格式 (秒、分、时、日、月、周)
 			cron:      cron.New(),
 			entryIDs:  make(map[uint]cron.EntryID),
+			resignIDs: make(map[uint]cron.EntryID),
+			checkIDs:  make(map[uint]cron.EntryID),
 			taskMutex: sync.RWMutex{},
 		}
 	})
@@ -95,6 +101,20 @@ func (s *TaskScheduler) LoadAllEnabledTasks() error {
 		}
 	}
 
+	// 加载定时重新签名，与任务本身的 Cron 无关
+	enabledTasks, err := repository.Task.ListEnabled()
+	if err != nil {
+		return err
+	}
+	for _, t := range enabledTasks {
+		if err := s.AddResignJob(&t); err != nil {
+			utils.Error("添加重新签名任务到调度器失败", "task_id", t.ID, "error", err.Error())
+		}
+		if err := s.AddLinkCheckJob(&t); err != nil {
+			utils.Error("添加链接检查任务到调度器失败", "task_id", t.ID, "error", err.Error())
+		}
+	}
+
 	return nil
 }
 
@@ -147,7 +167,7 @@ func (s *TaskScheduler) AddTask(t *task.Task) error {
 
 			// 将任务添加到队列
 			utils.Info("定时任务触发，添加到执行队列", "task_id", t.ID, "name", currentTask.Name, "queue_length", taskQueue.GetQueueLength())
-			taskQueue.AddTask(t.ID)
+			taskQueue.AddTask(t.ID, QueueSourceCron, QueuePriorityCron)
 
 			// 再次检查队列状态
 			utils.Info("添加到队列后的状态", "task_id", t.ID, "in_queue", taskQueue.IsTaskInQueue(t.ID), "executor_running", taskQueue.IsExecutorRunning(), "queue_length", taskQueue.GetQueueLength())
@@ -171,11 +191,103 @@ func (s *TaskScheduler) AddTask(t *task.Task) error {
 	return nil
 }
 
+// AddResignJob 添加任务的定时重新签名，任务未启用或未设置 ResignCron 时只移除已有的调度
+// 触发时任务正在运行或已在队列中则跳过本次，避免与生成同时写入 STRM 文件
+func (s *TaskScheduler) AddResignJob(t *task.Task) error {
+	s.taskMutex.Lock()
+	defer s.taskMutex.Unlock()
+
+	s.removeResignJobNoLock(t.ID)
+	if !t.Enabled || t.ResignCron == "" {
+		return nil
+	}
+
+	taskID := t.ID
+	entryID, err := s.cron.AddFunc(t.ResignCron, func() {
+		go func() {
+			currentTask, err := repository.Task.GetByID(taskID)
+			if err != nil || currentTask == nil || !currentTask.Enabled {
+				utils.Info("任务不存在或已禁用，跳过重新签名", "task_id", taskID)
+				return
+			}
+			if currentTask.Running || GetTaskQueue().IsTaskInQueue(taskID) {
+				utils.Info("任务正在运行或排队中，跳过本次重新签名", "task_id", taskID, "name", currentTask.Name)
+				return
+			}
+
+			utils.Info("定时重新签名触发", "task_id", taskID, "name", currentTask.Name)
+			if _, err := GetStrmGeneratorService().ResignStrmFiles(context.Background(), taskID); err != nil {
+				utils.Error("定时重新签名失败", "task_id", taskID, "error", err.Error())
+			}
+		}()
+	})
+	if err != nil {
+		utils.Error("添加重新签名任务到调度器失败", "task_id", t.ID, "cron", t.ResignCron, "error", err.Error())
+		return err
+	}
+
+	s.resignIDs[t.ID] = entryID
+	utils.Info("重新签名任务已添加到调度器", "task_id", t.ID, "cron", t.ResignCron, "next_run", s.cron.Entry(entryID).Next.Format("2006-01-02 15:04:05"))
+	return nil
+}
+
+// removeResignJobNoLock 移除任务的定时重新签名(无锁版本)
+func (s *TaskScheduler) removeResignJobNoLock(taskID uint) {
+	if entryID, exists := s.resignIDs[taskID]; exists {
+		s.cron.Remove(entryID)
+		delete(s.resignIDs, taskID)
+	}
+}
+
+// AddLinkCheckJob 添加任务的定时 STRM 链接检查，任务未启用或未设置 LinkCheckCron 时只移除已有的调度
+// 同一时间只运行一次链接检查，触发时已有检查在进行则跳过本次
+func (s *TaskScheduler) AddLinkCheckJob(t *task.Task) error {
+	s.taskMutex.Lock()
+	defer s.taskMutex.Unlock()
+
+	s.removeLinkCheckJobNoLock(t.ID)
+	if !t.Enabled || t.LinkCheckCron == "" {
+		return nil
+	}
+
+	taskID := t.ID
+	entryID, err := s.cron.AddFunc(t.LinkCheckCron, func() {
+		currentTask, err := repository.Task.GetByID(taskID)
+		if err != nil || currentTask == nil || !currentTask.Enabled {
+			utils.Info("任务不存在或已禁用，跳过链接检查", "task_id", taskID)
+			return
+		}
+
+		utils.Info("定时链接检查触发", "task_id", taskID, "name", currentTask.Name)
+		if _, err := LinkCheck.Start(&fileHistoryRequest.LinkCheckReq{TaskID: &taskID}); err != nil {
+			utils.Warn("定时链接检查未执行", "task_id", taskID, "error", err.Error())
+		}
+	})
+	if err != nil {
+		utils.Error("添加链接检查任务到调度器失败", "task_id", t.ID, "cron", t.LinkCheckCron, "error", err.Error())
+		return err
+	}
+
+	s.checkIDs[t.ID] = entryID
+	utils.Info("链接检查任务已添加到调度器", "task_id", t.ID, "cron", t.LinkCheckCron, "next_run", s.cron.Entry(entryID).Next.Format("2006-01-02 15:04:05"))
+	return nil
+}
+
+// removeLinkCheckJobNoLock 移除任务的定时链接检查(无锁版本)
+func (s *TaskScheduler) removeLinkCheckJobNoLock(taskID uint) {
+	if entryID, exists := s.checkIDs[taskID]; exists {
+		s.cron.Remove(entryID)
+		delete(s.checkIDs, taskID)
+	}
+}
+
 // RemoveTask 从调度器移除任务
 func (s *TaskScheduler) RemoveTask(taskID uint) {
 	s.taskMutex.Lock()
 	defer s.taskMutex.Unlock()
 	s.RemoveTaskNoLock(taskID)
+	s.removeResignJobNoLock(taskID)
+	s.removeLinkCheckJobNoLock(taskID)
 }
 
 // RemoveTaskNoLock 从调度器移除任务(无锁版本)
@@ -198,8 +310,21 @@ func (s *TaskScheduler) UpdateTask(t *task.Task) error {
 	s.taskMutex.Lock()
 	// 先移除任务
 	s.RemoveTaskNoLock(t.ID)
+	s.removeResignJobNoLock(t.ID)
+	s.removeLinkCheckJobNoLock(t.ID)
 	s.taskMutex.Unlock()
 
+	if t.Enabled && t.ResignCron != "" {
+		if err := s.AddResignJob(&taskCopy); err != nil {
+			utils.Error("添加重新签名任务到调度器失败", "task_id", taskCopy.ID, "error", err.Error())
+		}
+	}
+	if t.Enabled && t.LinkCheckCron != "" {
+		if err := s.AddLinkCheckJob(&taskCopy); err != nil {
+			utils.Error("添加链接检查任务到调度器失败", "task_id", taskCopy.ID, "error", err.Error())
+		}
+	}
+
 	// 如果需要调度，则添加任务（AddTask会自行获取锁）
 	if needsScheduling {
 		// 使用go routine异步添加任务，避免长时间阻塞HTTP请求
@@ -1,21 +1,34 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"path"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/MccRay-s/alist2strm/model/task"
 	taskRequest "github.com/MccRay-s/alist2strm/model/task/request"
 	taskResponse "github.com/MccRay-s/alist2strm/model/task/response"
+	"github.com/MccRay-s/alist2strm/model/tasklog"
 	"github.com/MccRay-s/alist2strm/repository"
 	"github.com/MccRay-s/alist2strm/utils"
 )
 
-type TaskService struct{}
+type TaskService struct {
+	cancelMu sync.Mutex
+	cancels  map[uint]*taskCancel // 运行中任务的取消函数，任务ID -> 本次执行注册的取消函数
+}
+
+// taskCancel 一次任务执行注册的取消函数，以指针区分同一任务的不同次执行
+type taskCancel struct {
+	cancel context.CancelFunc
+}
 
 // 包级别的全局实例
-var Task = &TaskService{}
+var Task = &TaskService{cancels: make(map[uint]*taskCancel)}
 
 // GetTaskStats 获取任务统计数据
 func (s *TaskService) GetTaskStats(timeRange string) (*taskResponse.TaskStatsResp, error) {
@@ -60,6 +73,31 @@ func (s *TaskService) GetTaskStats(timeRange string) (*taskResponse.TaskStatsRes
 
 // Create 创建任务
 func (s *TaskService) Create(req *taskRequest.TaskCreateReq) error {
+	// 校验 STRM 内容模板
+	if err := ValidateStrmTemplate(req.StrmTemplate); err != nil {
+		return err
+	}
+	// 校验包含/排除规则
+	if err := ValidateFileFilterRules(req.IncludeRules, req.ExcludeRules); err != nil {
+		return err
+	}
+	// 校验 STRM 配置覆盖
+	if err := ValidateStrmOverride(&req.StrmOverride); err != nil {
+		return err
+	}
+	// 校验 AList 配置档案
+	if err := checkAListProfile(req.AListProfileID); err != nil {
+		return err
+	}
+	// 校验重新签名的 Cron 表达式
+	if err := ValidateResignCron(req.ResignCron); err != nil {
+		return err
+	}
+	// 校验链接检查的 Cron 表达式
+	if err := ValidateLinkCheckCron(req.LinkCheckCron); err != nil {
+		return err
+	}
+
 	// 创建任务
 	newTask := &task.Task{
 		Name:               req.Name,
@@ -75,7 +113,32 @@ func (s *TaskService) Create(req *taskRequest.TaskCreateReq) error {
 		DownloadSubtitle:   req.DownloadSubtitle,
 		MetadataExtensions: req.MetadataExtensions,
 		SubtitleExtensions: req.SubtitleExtensions,
+		MirrorMode:         req.MirrorMode,
+		MirrorDryRun:       req.MirrorDryRun,
+		SkipUnchanged:      req.SkipUnchanged,
+		TolerantMode:       req.TolerantMode,
+		StrmTemplate:       req.StrmTemplate,
+		IncludeRules:       req.IncludeRules,
+		ExcludeRules:       req.ExcludeRules,
+		StrmOverride:       req.StrmOverride,
+		AListProfileID:     req.AListProfileID,
+		RefreshMode:        req.RefreshMode,
+		RefreshRecentHours: req.RefreshRecentHours,
+		RefreshManualOnly:  req.RefreshManualOnly,
+		URLMode:            req.URLMode,
+		ResignCron:         req.ResignCron,
+		LinkCheckCron:      req.LinkCheckCron,
+		StackMode:          req.StackMode,
+		RenameMedia:        req.RenameMedia,
+		GenerateNFO:        req.GenerateNFO,
+	}
+
+	// 校验目录密码
+	folderPasswords, err := normalizeFolderPasswords(newTask, req.FolderPasswords, nil)
+	if err != nil {
+		return err
 	}
+	newTask.FolderPasswords = folderPasswords
 
 	// 设置默认值
 	if newTask.MetadataExtensions == "" {
@@ -84,8 +147,20 @@ func (s *TaskService) Create(req *taskRequest.TaskCreateReq) error {
 	if newTask.SubtitleExtensions == "" {
 		newTask.SubtitleExtensions = "srt,ass,ssa"
 	}
+	if newTask.RefreshMode == "" {
+		newTask.RefreshMode = task.RefreshModeOff
+	}
+	if newTask.RefreshRecentHours == 0 {
+		newTask.RefreshRecentHours = task.DefaultRefreshRecentHours
+	}
+	if newTask.URLMode == "" {
+		newTask.URLMode = task.URLModeList
+	}
+	if newTask.StackMode == "" {
+		newTask.StackMode = task.StackModeOff
+	}
 
-	err := repository.Task.Create(newTask)
+	err = repository.Task.Create(newTask)
 	if err != nil {
 		return err
 	}
@@ -99,10 +174,62 @@ func (s *TaskService) Create(req *taskRequest.TaskCreateReq) error {
 			utils.Info("任务已添加到调度器", "task_id", newTask.ID, "name", newTask.Name)
 		}
 	}
+	if newTask.Enabled && newTask.ResignCron != "" {
+		if err := GetTaskScheduler().AddResignJob(newTask); err != nil {
+			utils.Warn("添加重新签名任务到调度器失败", "task_id", newTask.ID, "error", err.Error())
+		}
+	}
+	if newTask.Enabled && newTask.LinkCheckCron != "" {
+		if err := GetTaskScheduler().AddLinkCheckJob(newTask); err != nil {
+			utils.Warn("添加链接检查任务到调度器失败", "task_id", newTask.ID, "error", err.Error())
+		}
+	}
 
 	return nil
 }
 
+// toTaskInfo 将任务模型转换为响应结构
+func toTaskInfo(t *task.Task) taskResponse.TaskInfo {
+	return taskResponse.TaskInfo{
+		ID:                 t.ID,
+		CreatedAt:          t.CreatedAt,
+		UpdatedAt:          t.UpdatedAt,
+		Name:               t.Name,
+		MediaType:          t.MediaType,
+		SourcePath:         t.SourcePath,
+		TargetPath:         t.TargetPath,
+		FileSuffix:         t.FileSuffix,
+		Overwrite:          t.Overwrite,
+		Enabled:            t.Enabled,
+		Cron:               t.Cron,
+		Running:            t.Running,
+		LastRunAt:          t.LastRunAt,
+		DownloadMetadata:   t.DownloadMetadata,
+		DownloadSubtitle:   t.DownloadSubtitle,
+		MetadataExtensions: t.MetadataExtensions,
+		SubtitleExtensions: t.SubtitleExtensions,
+		MirrorMode:         t.MirrorMode,
+		MirrorDryRun:       t.MirrorDryRun,
+		SkipUnchanged:      t.SkipUnchanged,
+		TolerantMode:       t.TolerantMode,
+		StrmTemplate:       t.StrmTemplate,
+		IncludeRules:       t.IncludeRules,
+		ExcludeRules:       t.ExcludeRules,
+		StrmOverride:       t.StrmOverride,
+		AListProfileID:     t.AListProfileID,
+		FolderPasswords:    toFolderPasswordInfos(t.FolderPasswords),
+		RefreshMode:        t.RefreshMode,
+		RefreshRecentHours: t.RefreshRecentHours,
+		RefreshManualOnly:  t.RefreshManualOnly,
+		URLMode:            t.URLMode,
+		ResignCron:         t.ResignCron,
+		LinkCheckCron:      t.LinkCheckCron,
+		StackMode:          t.StackMode,
+		RenameMedia:        t.RenameMedia,
+		GenerateNFO:        t.GenerateNFO,
+	}
+}
+
 // GetTaskInfo 获取任务信息
 func (s *TaskService) GetTaskInfo(req *taskRequest.TaskInfoReq) (*taskResponse.TaskInfo, error) {
 	task, err := repository.Task.GetByID(uint(req.ID))
@@ -113,27 +240,21 @@ func (s *TaskService) GetTaskInfo(req *taskRequest.TaskInfoReq) (*taskResponse.T
 		return nil, errors.New("任务不存在")
 	}
 
-	resp := &taskResponse.TaskInfo{
-		ID:                 task.ID,
-		CreatedAt:          task.CreatedAt,
-		UpdatedAt:          task.UpdatedAt,
-		Name:               task.Name,
-		MediaType:          task.MediaType,
-		SourcePath:         task.SourcePath,
-		TargetPath:         task.TargetPath,
-		FileSuffix:         task.FileSuffix,
-		Overwrite:          task.Overwrite,
-		Enabled:            task.Enabled,
-		Cron:               task.Cron,
-		Running:            task.Running,
-		LastRunAt:          task.LastRunAt,
-		DownloadMetadata:   task.DownloadMetadata,
-		DownloadSubtitle:   task.DownloadSubtitle,
-		MetadataExtensions: task.MetadataExtensions,
-		SubtitleExtensions: task.SubtitleExtensions,
-	}
+	resp := toTaskInfo(task)
 
-	return resp, nil
+	// 任务详情中展示应用覆盖后实际生效的 STRM 配置
+	strmConfig, err := loadEffectiveStrmConfig(task)
+	if err != nil {
+		utils.Warn("加载任务 STRM 配置失败", "task_id", task.ID, "error", err.Error())
+	} else {
+		resp.EffectiveStrm = &taskResponse.TaskStrmConfig{
+			DefaultSuffix: strmConfig.DefaultSuffix,
+			ReplaceSuffix: strmConfig.ReplaceSuffix,
+			URLEncode:     strmConfig.URLEncode,
+			MinFileSize:   strmConfig.MinFileSize,
+		}
+	}
+	return &resp, nil
 }
 
 // UpdateTask 更新任务
@@ -163,7 +284,9 @@ func (s *TaskService) UpdateTask(req *taskRequest.TaskUpdateReq) error {
 		task.MediaType = req.MediaType
 		hasUpdate = true
 	}
+	sourcePathChanged := false
 	if req.SourcePath != "" {
+		sourcePathChanged = req.SourcePath != task.SourcePath
 		task.SourcePath = req.SourcePath
 		hasUpdate = true
 	}
@@ -203,17 +326,142 @@ func (s *TaskService) UpdateTask(req *taskRequest.TaskUpdateReq) error {
 		task.SubtitleExtensions = req.SubtitleExtensions
 		hasUpdate = true
 	}
+	if req.MirrorMode != nil {
+		task.MirrorMode = *req.MirrorMode
+		hasUpdate = true
+	}
+	if req.MirrorDryRun != nil {
+		task.MirrorDryRun = *req.MirrorDryRun
+		hasUpdate = true
+	}
+	if req.SkipUnchanged != nil {
+		task.SkipUnchanged = *req.SkipUnchanged
+		hasUpdate = true
+	}
+	if req.TolerantMode != nil {
+		task.TolerantMode = *req.TolerantMode
+		hasUpdate = true
+	}
+	if req.StrmTemplate != nil {
+		if err := ValidateStrmTemplate(*req.StrmTemplate); err != nil {
+			return err
+		}
+		task.StrmTemplate = *req.StrmTemplate
+		hasUpdate = true
+	}
+	filterChanged := false
+	if req.IncludeRules != nil || req.ExcludeRules != nil {
+		includeRules, excludeRules := task.IncludeRules, task.ExcludeRules
+		if req.IncludeRules != nil {
+			includeRules = *req.IncludeRules
+		}
+		if req.ExcludeRules != nil {
+			excludeRules = *req.ExcludeRules
+		}
+		if err := ValidateFileFilterRules(includeRules, excludeRules); err != nil {
+			return err
+		}
+		filterChanged = includeRules != task.IncludeRules || excludeRules != task.ExcludeRules
+		task.IncludeRules = includeRules
+		task.ExcludeRules = excludeRules
+		hasUpdate = true
+	}
+	if req.AListProfileID != nil {
+		if err := checkAListProfile(*req.AListProfileID); err != nil {
+			return err
+		}
+		// 切换到其他 AList 实例后目录修改时间不再可比，需要和源路径变更一样丢弃快照
+		sourcePathChanged = sourcePathChanged || *req.AListProfileID != task.AListProfileID
+		task.AListProfileID = *req.AListProfileID
+		hasUpdate = true
+	}
+	existingPasswords := task.FolderPasswords
+	if req.FolderPasswords != nil {
+		task.FolderPasswords = *req.FolderPasswords
+		hasUpdate = true
+	}
+	if req.RefreshMode != "" {
+		task.RefreshMode = req.RefreshMode
+		hasUpdate = true
+	}
+	if req.RefreshRecentHours != nil {
+		task.RefreshRecentHours = *req.RefreshRecentHours
+		hasUpdate = true
+	}
+	if req.RefreshManualOnly != nil {
+		task.RefreshManualOnly = *req.RefreshManualOnly
+		hasUpdate = true
+	}
+	if req.URLMode != "" {
+		task.URLMode = req.URLMode
+		hasUpdate = true
+	}
+	if req.ResignCron != nil {
+		if err := ValidateResignCron(*req.ResignCron); err != nil {
+			return err
+		}
+		task.ResignCron = *req.ResignCron
+		hasUpdate = true
+	}
+	if req.LinkCheckCron != nil {
+		if err := ValidateLinkCheckCron(*req.LinkCheckCron); err != nil {
+			return err
+		}
+		task.LinkCheckCron = *req.LinkCheckCron
+		hasUpdate = true
+	}
+	if req.StackMode != "" {
+		task.StackMode = req.StackMode
+		hasUpdate = true
+	}
+	if req.RenameMedia != nil {
+		// 命名方式变更后 STRM 的目标路径随之变化，未变化的目录也需要重新扫描
+		filterChanged = filterChanged || task.RenameMedia != *req.RenameMedia
+		task.RenameMedia = *req.RenameMedia
+		hasUpdate = true
+	}
+	if req.GenerateNFO != nil {
+		// 开启后未变化的目录也需要重新扫描，才能为其中的媒体文件生成 NFO
+		filterChanged = filterChanged || (*req.GenerateNFO && !task.GenerateNFO)
+		task.GenerateNFO = *req.GenerateNFO
+		hasUpdate = true
+	}
+	if req.StrmOverride != nil {
+		if err := ValidateStrmOverride(req.StrmOverride); err != nil {
+			return err
+		}
+		// 后缀和最小文件大小决定哪些文件会被处理，变更后与过滤规则一样需要重新扫描
+		filterChanged = filterChanged || strmOverrideScopeChanged(&task.StrmOverride, req.StrmOverride)
+		task.StrmOverride = *req.StrmOverride
+		hasUpdate = true
+	}
 
 	// 如果没有任何更新，返回错误
 	if !hasUpdate {
 		return errors.New("请提供要更新的信息")
 	}
 
+	// 目录密码的路径相对源路径，在源路径更新后统一校验
+	if req.FolderPasswords != nil || sourcePathChanged {
+		folderPasswords, err := normalizeFolderPasswords(task, task.FolderPasswords, existingPasswords)
+		if err != nil {
+			return err
+		}
+		task.FolderPasswords = folderPasswords
+	}
+
 	err = repository.Task.Update(task)
 	if err != nil {
 		return err
 	}
 
+	// 源路径或过滤规则变更后原有目录快照失效，否则未变化的目录中新纳入的文件不会被处理
+	if sourcePathChanged || filterChanged {
+		if err := repository.DirSnapshot.DeleteByTaskID(task.ID); err != nil {
+			utils.Warn("清理任务目录快照失败", "task_id", task.ID, "error", err.Error())
+		}
+	}
+
 	// 更新任务调度
 	scheduler := GetTaskScheduler()
 	if err := scheduler.UpdateTask(task); err != nil {
@@ -249,6 +497,21 @@ func (s *TaskService) DeleteTask(id uint) error {
 		return err
 	}
 
+	// 清理目录快照
+	if err := repository.DirSnapshot.DeleteByTaskID(id); err != nil {
+		utils.Warn("清理任务目录快照失败", "task_id", id, "error", err.Error())
+	}
+
+	// 清理任务断点
+	if err := repository.TaskLogCheckpoint.DeleteByTaskID(id); err != nil {
+		utils.Warn("清理任务断点失败", "task_id", id, "error", err.Error())
+	}
+
+	// 清理目录扫描失败记录
+	if err := repository.TaskLogFailure.DeleteByTaskID(id); err != nil {
+		utils.Warn("清理任务失败记录失败", "task_id", id, "error", err.Error())
+	}
+
 	// 从调度器中移除任务
 	scheduler := GetTaskScheduler()
 	scheduler.RemoveTask(id)
@@ -267,25 +530,7 @@ func (s *TaskService) GetTaskList(req *taskRequest.TaskListReq) (*taskResponse.T
 	// 转换为响应格式
 	taskInfos := make([]taskResponse.TaskInfo, len(tasks))
 	for i, t := range tasks {
-		taskInfos[i] = taskResponse.TaskInfo{
-			ID:                 t.ID,
-			CreatedAt:          t.CreatedAt,
-			UpdatedAt:          t.UpdatedAt,
-			Name:               t.Name,
-			MediaType:          t.MediaType,
-			SourcePath:         t.SourcePath,
-			TargetPath:         t.TargetPath,
-			FileSuffix:         t.FileSuffix,
-			Overwrite:          t.Overwrite,
-			Enabled:            t.Enabled,
-			Cron:               t.Cron,
-			Running:            t.Running,
-			LastRunAt:          t.LastRunAt,
-			DownloadMetadata:   t.DownloadMetadata,
-			DownloadSubtitle:   t.DownloadSubtitle,
-			MetadataExtensions: t.MetadataExtensions,
-			SubtitleExtensions: t.SubtitleExtensions,
-		}
+		taskInfos[i] = toTaskInfo(&t)
 	}
 
 	resp := &taskResponse.TaskListResp{
@@ -308,25 +553,7 @@ func (s *TaskService) GetAllTasks(req *taskRequest.TaskAllReq) ([]taskResponse.T
 	// 转换为响应格式
 	taskInfos := make([]taskResponse.TaskInfo, len(tasks))
 	for i, t := range tasks {
-		taskInfos[i] = taskResponse.TaskInfo{
-			ID:                 t.ID,
-			CreatedAt:          t.CreatedAt,
-			UpdatedAt:          t.UpdatedAt,
-			Name:               t.Name,
-			MediaType:          t.MediaType,
-			SourcePath:         t.SourcePath,
-			TargetPath:         t.TargetPath,
-			FileSuffix:         t.FileSuffix,
-			Overwrite:          t.Overwrite,
-			Enabled:            t.Enabled,
-			Cron:               t.Cron,
-			Running:            t.Running,
-			LastRunAt:          t.LastRunAt,
-			DownloadMetadata:   t.DownloadMetadata,
-			DownloadSubtitle:   t.DownloadSubtitle,
-			MetadataExtensions: t.MetadataExtensions,
-			SubtitleExtensions: t.SubtitleExtensions,
-		}
+		taskInfos[i] = toTaskInfo(&t)
 	}
 
 	return taskInfos, nil
@@ -386,6 +613,9 @@ func (s *TaskService) ResetTaskStatus(id uint) error {
 	return repository.Task.UpdateRunningStatus(id, false)
 }
 
+// errRewriting 任务正在重新签名或修复 STRM 文件时拒绝执行生成
+var errRewriting = errors.New("任务正在重新签名或修复 STRM 文件，请稍后再执行")
+
 // checkTaskExecutable 检查任务是否可执行
 // 返回任务信息和错误（如果有）
 func (s *TaskService) checkTaskExecutable(taskID uint) (*task.Task, error) {
@@ -408,6 +638,11 @@ func (s *TaskService) checkTaskExecutable(taskID uint) (*task.Task, error) {
 		return nil, errors.New("任务正在运行中")
 	}
 
+	// 重新签名或修复 STRM 文件时不能同时生成
+	if isRewriting(taskID) {
+		return nil, errRewriting
+	}
+
 	return taskInfo, nil
 }
 
@@ -431,10 +666,16 @@ func (s *TaskService) ExecuteTask(id uint, req *taskRequest.TaskExecuteReq) (*ta
 
 	if req.Sync {
 		// 同步执行 STRM 生成
-		execResult, err := s.ExecuteStrmGeneration(id)
-		if err != nil {
+		execResult, err := s.ExecuteStrmGeneration(id, GenerateOptions{SubPath: req.SubPath, Source: QueueSourceManual})
+		if errors.Is(err, context.Canceled) {
+			resp.Status = "cancelled"
+			resp.Message = "任务已取消"
+		} else if err != nil {
 			resp.Status = "failed"
 			resp.Message = err.Error()
+		} else if execResult != nil && execResult.Status == "partial" {
+			resp.Status = "partial"
+			resp.Message = "任务执行完成，部分目录扫描失败"
 		} else {
 			resp.Status = "completed"
 			resp.Message = "任务执行完成"
@@ -450,6 +691,8 @@ func (s *TaskService) ExecuteTask(id uint, req *taskRequest.TaskExecuteReq) (*ta
 			resp.SkippedCount = execResult.SkippedCount
 			resp.MetadataCount = execResult.MetadataCount
 			resp.SubtitleCount = execResult.SubtitleCount
+			resp.OrphanCount = execResult.OrphanCount
+			resp.DeletedCount = execResult.DeletedCount
 		} else {
 			resp.EndTime = time.Now().Format("2006-01-02 15:04:05")
 			resp.Duration = time.Since(startTime).String()
@@ -458,7 +701,11 @@ func (s *TaskService) ExecuteTask(id uint, req *taskRequest.TaskExecuteReq) (*ta
 		return resp, err
 	} else {
 		// 异步执行
-		err := s.ExecuteStrmGenerationAsync(id)
+		priority := QueuePriorityManual
+		if req.Priority != nil {
+			priority = *req.Priority
+		}
+		err := s.ExecuteStrmGenerationAsync(id, priority, req.SubPath)
 		if err != nil {
 			return nil, err
 		}
@@ -468,8 +715,32 @@ func (s *TaskService) ExecuteTask(id uint, req *taskRequest.TaskExecuteReq) (*ta
 	}
 }
 
-// ExecuteStrmGeneration 执行 STRM 文件生成任务
-func (s *TaskService) ExecuteStrmGeneration(taskID uint) (*taskResponse.TaskExecuteResp, error) {
+// ExecuteStrmGeneration 同步执行 STRM 文件生成任务
+// 任务同样经过执行队列，受全局任务槽位和 AList 主机槽位限制，执行结束后返回结果；任务已在排队或运行中时不执行
+func (s *TaskService) ExecuteStrmGeneration(taskID uint, opts GenerateOptions) (*taskResponse.TaskExecuteResp, error) {
+	// 使用辅助方法检查任务是否可执行
+	taskInfo, err := s.checkTaskExecutable(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 校验扫描范围
+	opts.SubPath, err = resolveSubPath(taskInfo, opts.SubPath)
+	if err != nil {
+		return nil, err
+	}
+
+	utils.Info("准备同步执行任务", "task_id", taskID, "name", taskInfo.Name, "sub_path", opts.SubPath)
+	return GetTaskQueue().EnqueueAndWait(QueueItem{
+		TaskID:   taskID,
+		Priority: QueuePriorityManual,
+		Source:   opts.Source,
+		SubPath:  opts.SubPath,
+	})
+}
+
+// runStrmGeneration 执行 STRM 文件生成，由任务队列在取得执行槽位后调用
+func (s *TaskService) runStrmGeneration(taskID uint, opts GenerateOptions) (*taskResponse.TaskExecuteResp, error) {
 	// 使用辅助方法检查任务是否可执行
 	taskInfo, err := s.checkTaskExecutable(taskID)
 	if err != nil {
@@ -485,10 +756,21 @@ func (s *TaskService) ExecuteStrmGeneration(taskID uint) (*taskResponse.TaskExec
 	// 记录开始时间
 	startTime := time.Now()
 
-	// 更新任务运行状态
-	if err := repository.Task.UpdateRunningStatus(taskID, true); err != nil {
+	// 标记任务运行状态，任务已被其他执行占用时不执行
+	claimed, err := repository.Task.ClaimRunning(taskID)
+	if err != nil {
 		return nil, fmt.Errorf("更新任务运行状态失败: %w", err)
 	}
+	if !claimed {
+		return nil, errors.New("任务正在运行中")
+	}
+	// 标记运行状态后再次检查，避免与同时开始的重新签名或修复并发写入 STRM 文件
+	if isRewriting(taskID) {
+		if err := repository.Task.UpdateRunningStatus(taskID, false); err != nil {
+			utils.Error("更新任务运行状态失败", "task_id", taskID, "error", err.Error())
+		}
+		return nil, errRewriting
+	}
 
 	// 更新最后执行时间
 	if err := repository.Task.UpdateLastRunAt(taskID, startTime); err != nil {
@@ -502,10 +784,13 @@ func (s *TaskService) ExecuteStrmGeneration(taskID uint) (*taskResponse.TaskExec
 		IsSync:    true,
 		Status:    "running",
 		StartTime: startTime.Format("2006-01-02 15:04:05"),
+		SubPath:   opts.SubPath,
 	}
 
-	// 启动 STRM 文件生成
-	err = strmService.GenerateStrmFiles(taskID)
+	// 启动 STRM 文件生成，注册取消函数以便通过接口取消
+	ctx, release := s.registerCancel(taskID)
+	err = strmService.GenerateStrmFiles(ctx, taskID, opts)
+	release()
 
 	// 更新任务运行状态
 	if updateErr := repository.Task.UpdateRunningStatus(taskID, false); updateErr != nil {
@@ -518,39 +803,257 @@ func (s *TaskService) ExecuteStrmGeneration(taskID uint) (*taskResponse.TaskExec
 	resp.Duration = endTime.Sub(startTime).String()
 
 	// 获取最新的任务日志记录，填充详细执行结果
+	latestStatus := ""
 	taskLogs, total, logErr := repository.TaskLog.GetLatestByTaskID(taskID, 1)
 	if logErr == nil && total > 0 && len(taskLogs) > 0 {
 		latestLog := taskLogs[0]
+		latestStatus = latestLog.Status
 		resp.TotalCount = latestLog.TotalFile
 		resp.SuccessCount = latestLog.GeneratedFile
 		resp.SkippedCount = latestLog.SkipFile
 		resp.MetadataCount = latestLog.MetadataCount
 		resp.SubtitleCount = latestLog.SubtitleCount
+		resp.OrphanCount = latestLog.OrphanFile
+		resp.DeletedCount = latestLog.DeletedFile
 
 		// 计算失败文件数
 		resp.FailedCount = resp.TotalCount - resp.SuccessCount - resp.SkippedCount
 	}
 
+	if errors.Is(err, context.Canceled) {
+		resp.Status = "cancelled"
+		return resp, err
+	}
 	if err != nil {
 		resp.Status = "failed"
 		return resp, err
 	}
 
 	resp.Status = "completed"
+	if latestStatus == "partial" {
+		resp.Status = "partial"
+	}
 	return resp, nil
 }
 
-// ExecuteStrmGenerationAsync 异步执行 STRM 文件生成任务
-func (s *TaskService) ExecuteStrmGenerationAsync(taskID uint) error {
+// registerCancel 为任务创建可取消的 context，返回的 release 需在执行结束后调用
+// release 只移除本次注册的取消函数，不影响同一任务之后注册的执行
+func (s *TaskService) registerCancel(taskID uint) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	entry := &taskCancel{cancel: cancel}
+
+	s.cancelMu.Lock()
+	s.cancels[taskID] = entry
+	s.cancelMu.Unlock()
+
+	return ctx, func() {
+		s.cancelMu.Lock()
+		if s.cancels[taskID] == entry {
+			delete(s.cancels, taskID)
+		}
+		s.cancelMu.Unlock()
+		cancel()
+	}
+}
+
+// CancelTask 取消任务：运行中的任务停止扫描和处理并记录为已取消，排队中的任务直接移出队列
+func (s *TaskService) CancelTask(id uint) error {
+	task, err := repository.Task.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if task == nil {
+		return errors.New("任务不存在")
+	}
+
+	s.cancelMu.Lock()
+	entry, ok := s.cancels[id]
+	s.cancelMu.Unlock()
+	if ok {
+		utils.Info("取消运行中的任务", "task_id", id, "name", task.Name)
+		entry.cancel()
+		return nil
+	}
+
+	if GetTaskQueue().RemoveTaskFromQueue(id) {
+		utils.Info("已从队列中移除任务", "task_id", id, "name", task.Name)
+		return nil
+	}
+
+	return errors.New("任务未在运行或排队中")
+}
+
+// ExecuteStrmGenerationAsync 异步执行 STRM 文件生成任务，按指定优先级加入执行队列
+// subPath 不为空时只扫描任务源路径下的该子路径
+func (s *TaskService) ExecuteStrmGenerationAsync(taskID uint, priority int, subPath string) error {
 	// 验证任务是否可执行
 	taskInfo, err := s.checkTaskExecutable(taskID)
 	if err != nil {
 		return err
 	}
 
-	utils.Info("准备异步执行任务", "task_id", taskID, "name", taskInfo.Name)
+	// 校验扫描范围
+	subPath, err = resolveSubPath(taskInfo, subPath)
+	if err != nil {
+		return err
+	}
+
+	utils.Info("准备异步执行任务", "task_id", taskID, "name", taskInfo.Name, "priority", priority, "sub_path", subPath)
 
 	// 将任务添加到队列
-	GetTaskQueue().AddTask(taskID)
+	GetTaskQueue().Enqueue(QueueItem{
+		TaskID:   taskID,
+		Priority: priority,
+		Source:   QueueSourceManual,
+		SubPath:  subPath,
+	})
+	return nil
+}
+
+// resolveSubPath 校验并规范化执行范围，返回相对任务源路径的子路径，为空表示扫描整个源路径
+// 支持相对路径，也支持以 / 开头的 AList 完整路径，完整路径必须位于任务源路径之下
+func resolveSubPath(taskInfo *task.Task, subPath string) (string, error) {
+	subPath = strings.TrimSpace(strings.ReplaceAll(subPath, "\\", "/"))
+	if subPath == "" {
+		return "", nil
+	}
+	for _, part := range strings.Split(subPath, "/") {
+		if part == ".." {
+			return "", errors.New("子路径不能包含 ..")
+		}
+	}
+
+	if !strings.HasPrefix(subPath, "/") {
+		return strings.Trim(path.Clean("/"+subPath), "/"), nil
+	}
+
+	sourceRoot := path.Clean("/" + taskInfo.SourcePath)
+	cleaned := path.Clean(subPath)
+	if cleaned == sourceRoot {
+		return "", nil
+	}
+	rel := strings.TrimPrefix(cleaned, strings.TrimSuffix(sourceRoot, "/")+"/")
+	if rel == cleaned {
+		return "", fmt.Errorf("子路径 %s 不在任务源路径 %s 下", subPath, taskInfo.SourcePath)
+	}
+	return rel, nil
+}
+
+// ResumeTask 从最近一次中断、失败或取消的执行断点继续执行任务（异步）
+func (s *TaskService) ResumeTask(taskID uint) (*taskResponse.TaskResumeResp, error) {
+	taskInfo, err := s.checkTaskExecutable(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	taskLogs, total, err := repository.TaskLog.GetLatestByTaskID(taskID, 1)
+	if err != nil {
+		return nil, err
+	}
+	if total == 0 || len(taskLogs) == 0 {
+		return nil, errors.New("任务没有执行记录，无法继续执行")
+	}
+	latestLog := taskLogs[0]
+	if latestLog.Status != tasklog.TaskLogStatusInterrupted && latestLog.Status != tasklog.TaskLogStatusFailed &&
+		latestLog.Status != tasklog.TaskLogStatusCancelled {
+		return nil, errors.New("任务最近一次执行未中断，无需继续执行")
+	}
+
+	checkpointCount, err := repository.TaskLogCheckpoint.CountByTaskLogID(latestLog.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	utils.Info("准备从断点继续执行任务", "task_id", taskID, "name", taskInfo.Name, "task_log_id", latestLog.ID, "checkpoints", checkpointCount)
+	GetTaskQueue().AddResumeTask(taskID, latestLog.ID, QueuePriorityManual)
+
+	return &taskResponse.TaskResumeResp{
+		TaskID:          taskID,
+		TaskName:        taskInfo.Name,
+		ResumeFromLogID: latestLog.ID,
+		CheckpointCount: checkpointCount,
+	}, nil
+}
+
+// ResignTask 立即重新签名任务已生成的 STRM 文件（同步），任务正在运行时不执行
+func (s *TaskService) ResignTask(ctx context.Context, taskID uint) (*taskResponse.TaskResignResp, error) {
+	return GetStrmGeneratorService().ResignStrmFiles(ctx, taskID)
+}
+
+// GetQueue 获取任务执行队列，包括正在执行和等待执行的任务
+func (s *TaskService) GetQueue() *taskResponse.TaskQueueResp {
+	running, queued := GetTaskQueue().Snapshot()
+
+	resp := &taskResponse.TaskQueueResp{
+		Running: make([]taskResponse.TaskQueueItem, 0, len(running)),
+		Queued:  make([]taskResponse.TaskQueueItem, 0, len(queued)),
+	}
+	for _, item := range running {
+		queueItem := s.toQueueItem(item.QueueItem, 0)
+		startedAt := item.StartedAt
+		queueItem.StartedAt = &startedAt
+		resp.Running = append(resp.Running, queueItem)
+	}
+	for i, item := range queued {
+		resp.Queued = append(resp.Queued, s.toQueueItem(item, i+1))
+	}
+	return resp
+}
+
+// toQueueItem 将队列项转换为响应格式，任务已删除时名称为空
+func (s *TaskService) toQueueItem(item QueueItem, position int) taskResponse.TaskQueueItem {
+	queueItem := taskResponse.TaskQueueItem{
+		Position:   position,
+		TaskID:     item.TaskID,
+		Priority:   item.Priority,
+		Source:     item.Source,
+		SubPath:    item.SubPath,
+		EnqueuedAt: item.EnqueuedAt,
+	}
+	if t, err := repository.Task.GetByID(item.TaskID); err == nil && t != nil {
+		queueItem.TaskName = t.Name
+	}
+	return queueItem
+}
+
+// MoveQueueTask 调整任务在队列中的位置，offset 为负表示上移，为正表示下移
+func (s *TaskService) MoveQueueTask(id uint, offset int) error {
+	if !GetTaskQueue().MoveTask(id, offset) {
+		return errors.New("任务不在队列中")
+	}
 	return nil
 }
+
+// RemoveQueueTask 从队列中移除等待执行的任务
+func (s *TaskService) RemoveQueueTask(id uint) error {
+	if !GetTaskQueue().RemoveTaskFromQueue(id) {
+		return errors.New("任务不在队列中")
+	}
+	return nil
+}
+
+// RecoverInterruptedRuns 启动时处理上次服务退出时仍在运行的任务
+// 将运行中的任务日志标记为已中断，并重置任务运行状态，已记录的断点保留用于继续执行
+func (s *TaskService) RecoverInterruptedRuns() error {
+	runningLogs, err := repository.TaskLog.ListByStatus(tasklog.TaskLogStatusRunning)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, runningLog := range runningLogs {
+		updates := map[string]interface{}{
+			"status":   tasklog.TaskLogStatusInterrupted,
+			"message":  "服务重启导致任务中断，可从断点继续执行",
+			"end_time": &now,
+			"duration": int64(now.Sub(runningLog.StartTime).Seconds()),
+		}
+		if err := repository.TaskLog.UpdatePartial(runningLog.ID, updates); err != nil {
+			utils.Error("标记中断的任务日志失败", "task_log_id", runningLog.ID, "error", err.Error())
+			continue
+		}
+		utils.Warn("检测到中断的任务执行", "task_id", runningLog.TaskID, "task_log_id", runningLog.ID)
+	}
+
+	return repository.Task.ResetRunningStatus()
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/MccRay-s/alist2strm/model/task"
+	"github.com/MccRay-s/alist2strm/model/webhook"
+)
+
+// defaultWebhookPathField generic 适配器未配置字段时读取的 JSON 字段
+const defaultWebhookPathField = "path"
+
+// webhookFileExtensions 判断上报路径是否为文件时使用的常见扩展名，任务配置的扩展名同样生效
+var webhookFileExtensions = map[string]struct{}{
+	"mkv": {}, "mp4": {}, "avi": {}, "mov": {}, "rmvb": {}, "webm": {}, "flv": {}, "m3u8": {}, "ts": {}, "m2ts": {}, "iso": {}, "wmv": {},
+	"srt": {}, "ass": {}, "ssa": {}, "sub": {}, "idx": {}, "sup": {}, "vtt": {},
+	"nfo": {}, "jpg": {}, "jpeg": {}, "png": {},
+}
+
+// WebhookPayload 入站请求内容
+type WebhookPayload struct {
+	Body   []byte     // 原始请求体
+	Values url.Values // 查询参数和表单参数
+}
+
+// extractWebhookPaths 按 Webhook 的适配器从请求中提取上报的路径
+// 返回的 ignoreReason 不为空时表示事件无需处理（例如测试事件）
+func extractWebhookPaths(hook *webhook.Webhook, payload *WebhookPayload) ([]string, string, error) {
+	switch hook.Adapter {
+	case webhook.AdapterSonarr:
+		return extractArrPaths(payload, "episodeFile", "episodeFiles", "series", "path")
+	case webhook.AdapterRadarr:
+		return extractArrPaths(payload, "movieFile", "movieFiles", "movie", "folderPath")
+	case webhook.AdapterQBittorrent:
+		paths, err := extractQBittorrentPaths(payload)
+		return paths, "", err
+	case webhook.AdapterGeneric, "":
+		paths, err := extractGenericPaths(hook.PathField, payload)
+		return paths, "", err
+	default:
+		return nil, "", fmt.Errorf("不支持的适配器类型: %s", hook.Adapter)
+	}
+}
+
+// extractGenericPaths 从 JSON 请求体中按点号分隔的字段路径读取路径，字段值可以是字符串或字符串数组
+// 请求体不是 JSON 时从查询参数和表单参数中读取同名字段
+func extractGenericPaths(field string, payload *WebhookPayload) ([]string, error) {
+	if field == "" {
+		field = defaultWebhookPathField
+	}
+
+	var body interface{}
+	if len(payload.Body) > 0 && json.Unmarshal(payload.Body, &body) == nil {
+		paths := collectStrings(lookupJSONField(body, field))
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("请求体中未找到路径字段 %s", field)
+		}
+		return paths, nil
+	}
+
+	if values := payload.Values[field]; len(values) > 0 {
+		return values, nil
+	}
+	return nil, fmt.Errorf("请求中未找到路径字段 %s", field)
+}
+
+// extractArrPaths 解析 Sonarr/Radarr 的 Webhook，只处理 Download（导入完成）事件
+// 优先使用导入文件的完整路径，其次为媒体目录与相对路径拼接，最后退回媒体目录
+func extractArrPaths(payload *WebhookPayload, fileKey, filesKey, mediaKey, mediaPathKey string) ([]string, string, error) {
+	var body map[string]interface{}
+	if err := json.Unmarshal(payload.Body, &body); err != nil {
+		return nil, "", fmt.Errorf("解析请求体失败: %w", err)
+	}
+
+	eventType, _ := body["eventType"].(string)
+	switch eventType {
+	case "Test":
+		return nil, "测试事件", nil
+	case "Download":
+	default:
+		return nil, fmt.Sprintf("不处理的事件类型: %s", eventType), nil
+	}
+
+	mediaPath, _ := lookupJSONField(body, mediaKey+"."+mediaPathKey).(string)
+	filePath := func(file interface{}) string {
+		if p, _ := lookupJSONField(file, "path").(string); p != "" {
+			return p
+		}
+		if rel, _ := lookupJSONField(file, "relativePath").(string); rel != "" && mediaPath != "" {
+			return strings.TrimSuffix(mediaPath, "/") + "/" + strings.TrimPrefix(rel, "/")
+		}
+		return ""
+	}
+
+	var paths []string
+	if p := filePath(body[fileKey]); p != "" {
+		paths = append(paths, p)
+	}
+	if files, ok := body[filesKey].([]interface{}); ok {
+		for _, file := range files {
+			if p := filePath(file); p != "" {
+				paths = append(paths, p)
+			}
+		}
+	}
+	if len(paths) == 0 && mediaPath != "" {
+		paths = append(paths, mediaPath)
+	}
+	if len(paths) == 0 {
+		return nil, "", fmt.Errorf("请求体中未找到导入文件路径")
+	}
+	return paths, "", nil
+}
+
+// extractQBittorrentPaths 解析 qBittorrent “下载完成时运行外部程序” 通过 curl 上报的参数
+// 支持 content_path（%F）或 save_path（%D）+ name（%N），参数可以放在查询参数、表单或 JSON 请求体中
+func extractQBittorrentPaths(payload *WebhookPayload) ([]string, error) {
+	get := func(key string) string {
+		return strings.TrimSpace(payload.Values.Get(key))
+	}
+
+	var body map[string]interface{}
+	if len(payload.Body) > 0 && json.Unmarshal(payload.Body, &body) == nil {
+		get = func(key string) string {
+			if value, ok := body[key].(string); ok {
+				return strings.TrimSpace(value)
+			}
+			return strings.TrimSpace(payload.Values.Get(key))
+		}
+	}
+
+	if contentPath := get("content_path"); contentPath != "" {
+		return []string{contentPath}, nil
+	}
+	if contentPath := get("path"); contentPath != "" {
+		return []string{contentPath}, nil
+	}
+	savePath, name := get("save_path"), get("name")
+	if savePath == "" {
+		return nil, fmt.Errorf("请求中未找到 content_path 或 save_path 参数")
+	}
+	if name == "" {
+		return []string{savePath}, nil
+	}
+	return []string{strings.TrimSuffix(savePath, "/") + "/" + name}, nil
+}
+
+// lookupJSONField 按点号分隔的字段路径读取 JSON 值
+func lookupJSONField(value interface{}, field string) interface{} {
+	for _, key := range strings.Split(field, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// collectStrings 将字符串或字符串数组转换为非空字符串列表
+func collectStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	case []interface{}:
+		var result []string
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// mapWebhookPath 将发送方上报的路径转换为 AList 路径
+// 统一路径分隔符后，将 PathFrom 前缀替换为 PathTo；未配置或不匹配时原样返回
+func mapWebhookPath(hook *webhook.Webhook, reported string) string {
+	p := path.Clean("/" + strings.ReplaceAll(strings.TrimSpace(reported), "\\", "/"))
+	if hook.PathFrom == "" {
+		return p
+	}
+
+	from := path.Clean("/" + strings.ReplaceAll(hook.PathFrom, "\\", "/"))
+	to := path.Clean("/" + hook.PathTo)
+	if p == from {
+		return to
+	}
+	if rest := strings.TrimPrefix(p, strings.TrimSuffix(from, "/")+"/"); rest != p {
+		return path.Join(to, rest)
+	}
+	return p
+}
+
+// webhookScanDir 上报的是文件时返回其所在目录，目录原样返回
+// 只能根据扩展名判断，扩展名来自任务配置和常见的媒体、字幕、元数据格式
+func webhookScanDir(taskInfo *task.Task, alistPath string) string {
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(alistPath), "."))
+	if ext == "" {
+		return alistPath
+	}
+	if _, ok := webhookFileExtensions[ext]; ok {
+		return path.Dir(alistPath)
+	}
+	lists := []string{taskInfo.FileSuffix, taskInfo.MetadataExtensions, taskInfo.SubtitleExtensions}
+	if taskInfo.StrmOverride.DefaultSuffix != nil {
+		lists = append(lists, *taskInfo.StrmOverride.DefaultSuffix)
+	}
+	for _, list := range lists {
+		for _, suffix := range strings.Split(list, ",") {
+			if strings.EqualFold(strings.TrimPrefix(strings.TrimSpace(suffix), "."), ext) {
+				return path.Dir(alistPath)
+			}
+		}
+	}
+	return alistPath
+}
+
+// matchWebhookTask 查找源路径包含该 AList 路径的任务，多个任务匹配时选择源路径最长（最具体）的任务
+func matchWebhookTask(tasks []task.Task, alistPath string) *task.Task {
+	var matched *task.Task
+	matchedLen := -1
+	for i := range tasks {
+		sourceRoot := path.Clean("/" + tasks[i].SourcePath)
+		if alistPath != sourceRoot && !strings.HasPrefix(alistPath, strings.TrimSuffix(sourceRoot, "/")+"/") {
+			continue
+		}
+		if len(sourceRoot) > matchedLen {
+			matched = &tasks[i]
+			matchedLen = len(sourceRoot)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/MccRay-s/alist2strm/model/task"
+	"github.com/MccRay-s/alist2strm/model/webhook"
+	webhookRequest "github.com/MccRay-s/alist2strm/model/webhook/request"
+	webhookResponse "github.com/MccRay-s/alist2strm/model/webhook/response"
+	"github.com/MccRay-s/alist2strm/repository"
+	"github.com/MccRay-s/alist2strm/utils"
+)
+
+const (
+	defaultWebhookDebounce = 30               // 默认防抖时间（秒）
+	maxWebhookDebounceWait = 10 * time.Minute // 持续收到事件时最长等待时间，超过后立即加入队列
+)
+
+// pendingWebhookRun 防抖窗口内等待加入队列的执行
+type pendingWebhookRun struct {
+	subPath  string
+	priority int
+	first    time.Time // 窗口内第一个事件的时间
+	timer    *time.Timer
+}
+
+// WebhookService Webhook 服务
+type WebhookService struct {
+	mu      sync.Mutex
+	pending map[uint]*pendingWebhookRun // 任务ID -> 等待加入队列的执行
+}
+
+// 包级别的全局实例
+var Webhook = &WebhookService{pending: make(map[uint]*pendingWebhookRun)}
+
+// Create 创建 Webhook，自动生成调用凭证
+func (s *WebhookService) Create(req *webhookRequest.WebhookCreateReq) (*webhook.Webhook, error) {
+	token, err := generateWebhookToken()
+	if err != nil {
+		return nil, err
+	}
+
+	hook := &webhook.Webhook{
+		Name:            req.Name,
+		Token:           token,
+		Adapter:         req.Adapter,
+		PathField:       req.PathField,
+		PathFrom:        req.PathFrom,
+		PathTo:          req.PathTo,
+		TaskID:          req.TaskID,
+		Priority:        QueuePriorityManual,
+		DebounceSeconds: defaultWebhookDebounce,
+		Enabled:         req.Enabled,
+	}
+	if req.Priority != nil {
+		hook.Priority = *req.Priority
+	}
+	if req.DebounceSeconds != nil {
+		hook.DebounceSeconds = *req.DebounceSeconds
+	}
+	if err := s.checkTask(hook.TaskID); err != nil {
+		return nil, err
+	}
+
+	if err := repository.Webhook.Create(hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// GetInfo 获取 Webhook 信息
+func (s *WebhookService) GetInfo(id uint) (*webhook.Webhook, error) {
+	hook, err := repository.Webhook.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if hook == nil {
+		return nil, errors.New("Webhook 不存在")
+	}
+	return hook, nil
+}
+
+// List 获取所有 Webhook
+func (s *WebhookService) List() ([]webhook.Webhook, error) {
+	return repository.Webhook.List()
+}
+
+// Update 更新 Webhook
+func (s *WebhookService) Update(req *webhookRequest.WebhookUpdateReq) error {
+	hook, err := s.GetInfo(req.ID)
+	if err != nil {
+		return err
+	}
+
+	if req.Name != "" {
+		hook.Name = req.Name
+	}
+	if req.Adapter != "" {
+		hook.Adapter = req.Adapter
+	}
+	if req.PathField != nil {
+		hook.PathField = *req.PathField
+	}
+	if req.PathFrom != nil {
+		hook.PathFrom = *req.PathFrom
+	}
+	if req.PathTo != nil {
+		hook.PathTo = *req.PathTo
+	}
+	if req.TaskID != nil {
+		if err := s.checkTask(*req.TaskID); err != nil {
+			return err
+		}
+		hook.TaskID = *req.TaskID
+	}
+	if req.Priority != nil {
+		hook.Priority = *req.Priority
+	}
+	if req.DebounceSeconds != nil {
+		hook.DebounceSeconds = *req.DebounceSeconds
+	}
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+
+	return repository.Webhook.Update(hook)
+}
+
+// Delete 删除 Webhook
+func (s *WebhookService) Delete(id uint) error {
+	if _, err := s.GetInfo(id); err != nil {
+		return err
+	}
+	return repository.Webhook.Delete(id)
+}
+
+// RegenerateToken 重新生成调用凭证，旧凭证立即失效
+func (s *WebhookService) RegenerateToken(id uint) (*webhook.Webhook, error) {
+	hook, err := s.GetInfo(id)
+	if err != nil {
+		return nil, err
+	}
+	token, err := generateWebhookToken()
+	if err != nil {
+		return nil, err
+	}
+	hook.Token = token
+	if err := repository.Webhook.Update(hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// checkTask 检查限定的任务是否存在，0 表示不限定
+func (s *WebhookService) checkTask(taskID uint) error {
+	if taskID == 0 {
+		return nil
+	}
+	t, err := repository.Task.GetByID(taskID)
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return errors.New("任务不存在")
+	}
+	return nil
+}
+
+// Receive 处理入站事件：解析上报路径，映射为 AList 路径并匹配任务和扫描范围，经防抖后加入执行队列
+func (s *WebhookService) Receive(token string, payload *WebhookPayload) (*webhookResponse.WebhookReceiveResp, error) {
+	if token == "" {
+		return nil, errors.New("Webhook 不存在")
+	}
+	hook, err := repository.Webhook.GetByToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if hook == nil {
+		return nil, errors.New("Webhook 不存在")
+	}
+	if !hook.Enabled {
+		return nil, errors.New("Webhook 已禁用")
+	}
+
+	if err := repository.Webhook.UpdateLastTriggeredAt(hook.ID, time.Now()); err != nil {
+		utils.Warn("更新 Webhook 触发时间失败", "webhook_id", hook.ID, "error", err.Error())
+	}
+
+	resp := &webhookResponse.WebhookReceiveResp{
+		Triggers:  make([]webhookResponse.WebhookTrigger, 0),
+		Unmatched: make([]string, 0),
+	}
+
+	reported, ignoreReason, err := extractWebhookPaths(hook, payload)
+	if err != nil {
+		return nil, err
+	}
+	if ignoreReason != "" {
+		utils.Info("Webhook 事件已忽略", "webhook_id", hook.ID, "reason", ignoreReason)
+		resp.Ignored = true
+		resp.Message = ignoreReason
+		return resp, nil
+	}
+
+	// 候选任务：限定任务时只匹配该任务，否则匹配所有启用的任务
+	var tasks []task.Task
+	if hook.TaskID != 0 {
+		t, err := repository.Task.GetByID(hook.TaskID)
+		if err != nil {
+			return nil, err
+		}
+		if t != nil && t.Enabled {
+			tasks = append(tasks, *t)
+		}
+	} else {
+		tasks, err = repository.Task.ListEnabled()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	debounce := time.Duration(hook.DebounceSeconds) * time.Second
+	for _, p := range reported {
+		alistPath := mapWebhookPath(hook, p)
+		matched := matchWebhookTask(tasks, alistPath)
+		if matched == nil {
+			utils.Warn("Webhook 路径未匹配到任务", "webhook_id", hook.ID, "path", p, "alist_path", alistPath)
+			resp.Unmatched = append(resp.Unmatched, alistPath)
+			continue
+		}
+
+		subPath, err := resolveSubPath(matched, webhookScanDir(matched, alistPath))
+		if err != nil {
+			utils.Warn("Webhook 路径无法转换为扫描范围", "webhook_id", hook.ID, "path", alistPath, "error", err.Error())
+			resp.Unmatched = append(resp.Unmatched, alistPath)
+			continue
+		}
+
+		s.schedule(matched.ID, subPath, hook.Priority, debounce)
+		resp.Triggers = append(resp.Triggers, webhookResponse.WebhookTrigger{
+			Path:     alistPath,
+			TaskID:   matched.ID,
+			TaskName: matched.Name,
+			SubPath:  subPath,
+		})
+	}
+
+	resp.Message = "事件已接收"
+	if len(resp.Triggers) == 0 {
+		resp.Message = "没有路径匹配到任务"
+	}
+	utils.Info("Webhook 事件已处理", "webhook_id", hook.ID, "name", hook.Name, "triggers", len(resp.Triggers), "unmatched", len(resp.Unmatched))
+	return resp, nil
+}
+
+// schedule 防抖：窗口内同一任务的多次事件合并扫描范围和优先级，窗口结束后只加入一次队列
+// 持续收到事件时，最迟在第一个事件后 maxWebhookDebounceWait 加入队列
+func (s *WebhookService) schedule(taskID uint, subPath string, priority int, debounce time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.pending[taskID]
+	if !ok {
+		p = &pendingWebhookRun{subPath: subPath, priority: priority, first: time.Now()}
+		s.pending[taskID] = p
+		p.timer = time.AfterFunc(debounce, func() { s.fire(taskID) })
+		return
+	}
+
+	p.subPath = mergeSubPaths(p.subPath, subPath)
+	if priority > p.priority {
+		p.priority = priority
+	}
+	wait := debounce
+	if remain := maxWebhookDebounceWait - time.Since(p.first); remain < wait {
+		wait = remain
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	p.timer.Reset(wait)
+}
+
+// fire 防抖窗口结束，将合并后的执行加入队列
+func (s *WebhookService) fire(taskID uint) {
+	s.mu.Lock()
+	p, ok := s.pending[taskID]
+	delete(s.pending, taskID)
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	t, err := repository.Task.GetByID(taskID)
+	if err != nil || t == nil || !t.Enabled {
+		utils.Warn("Webhook 触发的任务不存在或已禁用，跳过执行", "task_id", taskID)
+		return
+	}
+
+	utils.Info("Webhook 触发任务，添加到执行队列", "task_id", taskID, "name", t.Name, "sub_path", p.subPath, "priority", p.priority)
+	GetTaskQueue().Enqueue(QueueItem{
+		TaskID:   taskID,
+		Priority: p.priority,
+		Source:   QueueSourceWebhook,
+		SubPath:  p.subPath,
+	})
+}
+
+// generateWebhookToken 生成随机调用凭证
+func generateWebhookToken() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}